@@ -0,0 +1,98 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 启动一个服务端，每个连接上的第一个请求延迟 delay 后才应答，之后的请求立即应答
+func startLateReplyServer(t *testing.T, delay time.Duration) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error:%s\n", err.Error())
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				prot := thrift.NewTBinaryProtocolTransport(thrift.NewTSocketFromConnTimeout(conn, 0))
+				for first := true; ; first = false {
+					name, _, seqId, err := prot.ReadMessageBegin()
+					if err != nil {
+						return
+					}
+					if prot.Skip(thrift.STRUCT) != nil || prot.ReadMessageEnd() != nil {
+						return
+					}
+					if first {
+						time.Sleep(delay)
+					}
+					_ = prot.WriteMessageBegin(name, thrift.REPLY, seqId)
+					_ = prot.WriteStructBegin("result")
+					_ = prot.WriteFieldStop()
+					_ = prot.WriteStructEnd()
+					_ = prot.WriteMessageEnd()
+					if prot.Flush() != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	return listener.Addr().String(), func() { _ = listener.Close() }
+}
+
+// 发出请求后不等待回复就归还连接，返回下一个借出方的调用结果
+func callAfterAbandon(t *testing.T, markAbandoned bool) error {
+	endpoint, stop := startLateReplyServer(t, 50*time.Millisecond)
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 1, 0)
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get error:%s\n", err.Error())
+	}
+	abandoned := newSeqClient(conn.ClientFactory())
+	// 与下一个借出方的 SeqId 区分开，便于识别迟到的回复
+	abandoned.SeqId = 99
+	if err := abandoned.send(); err != nil {
+		t.Fatalf("send error:%s\n", err.Error())
+	}
+	if markAbandoned {
+		_ = conn.MarkAbandoned()
+	}
+	_ = pool.Put(conn)
+
+	next, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get error:%s\n", err.Error())
+	}
+	defer pool.Put(next)
+	if markAbandoned && next == conn {
+		t.Errorf("abandoned conn should not be reused\n")
+	}
+	return newSeqClient(next.ClientFactory()).call()
+}
+
+func TestMarkAbandoned(t *testing.T) {
+	if err := callAfterAbandon(t, true); err != nil {
+		t.Errorf("next borrower should not see the late reply:%s\n", err.Error())
+	}
+}
+
+func TestReuseAfterAbandonDesyncs(t *testing.T) {
+	err := callAfterAbandon(t, false)
+	if appErr, ok := err.(thrift.TApplicationException); !ok || appErr.TypeId() != thrift.BAD_SEQUENCE_ID {
+		t.Errorf("reused conn should read the late reply and fail with BAD_SEQUENCE_ID, got %v\n", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+)
+
+// 按 key 取一个连接，同一 key 总是取到同一目标端点的连接，用于有状态或依赖缓存局部性的服务端
+// key 到端点的映射使用按权重的 rendezvous hash，SetTargets 增删目标时只有少量 key 会改变端点；
+// 未设置多个目标时同 Get。
+// 目标端点拨号失败（连接层面的错误，见 IsConnectionError）时记录日志，改按 Get 从其它端点取连接，
+// 此时同一 key 的请求不再落到同一端点。应和 Put 一对一成对调用
+func (t *ThriftPool) GetForKey(ctx context.Context, key string) (*ThriftConn, error) {
+	bound := t.loadBinding()
+	if bound.targets == nil {
+		return t.Get(ctx)
+	}
+	endpoint := bound.targets.pickKey(key)
+	poolCtx, cancel := t.withPoolContext(ctx)
+	conn, err := t.getFor(poolCtx, false, endpoint)
+	cancel()
+	if err != nil {
+		if !IsConnectionError(err) {
+			return nil, t.nameErr(err)
+		}
+		t.logf("affinity of key %q to %s broken:%s", key, endpoint, err.Error())
+		return t.Get(ctx)
+	}
+	conn.tag = ""
+	t.markInUse(conn)
+	t.startWatchdog(conn)
+	t.emit(EventBorrowed, nil)
+	return conn, nil
+}
+
+// 按权重的 rendezvous hash 选取 key 对应的端点
+func (s *targetSet) pickKey(key string) string {
+	var selected string
+	var best float64
+	var prev int32
+	for i, endpoint := range s.endpoints {
+		weight := s.cumulative[i] - prev
+		prev = s.cumulative[i]
+
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(endpoint))
+		_, _ = hash.Write([]byte{0})
+		_, _ = hash.Write([]byte(key))
+		// 把哈希值映射到 (0, 1)，得分为 -weight/ln(u)，权重越大得分越高的概率越大
+		u := (float64(mix64(hash.Sum64())>>11) + 0.5) / (1 << 53)
+		score := -float64(weight) / math.Log(u)
+		if selected == "" || score > best {
+			selected, best = endpoint, score
+		}
+	}
+	return selected
+}
+
+// murmur3 的 fmix64，FNV 的高位对输入的区分度不够，各端点只有末尾几个字符不同时得分会集中
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
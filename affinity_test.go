@@ -0,0 +1,136 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestPickKey(t *testing.T) {
+	set := newTargetSet([]Target{{Endpoint: "a", Weight: 1}, {Endpoint: "b", Weight: 3}})
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		key := strconv.Itoa(i)
+		endpoint := set.pickKey(key)
+		if set.pickKey(key) != endpoint {
+			t.Fatalf("key %s should map to the same endpoint\n", key)
+		}
+		counts[endpoint]++
+	}
+	if counts["a"] < 800 || counts["a"] > 1200 {
+		t.Errorf("keys should follow the weights, got %v\n", counts)
+	}
+
+	// 增加目标时，只有移到新目标的 key 改变端点
+	grown := newTargetSet([]Target{{Endpoint: "a", Weight: 1}, {Endpoint: "b", Weight: 3}, {Endpoint: "c", Weight: 1}})
+	for i := 0; i < 4000; i++ {
+		key := strconv.Itoa(i)
+		if endpoint := grown.pickKey(key); endpoint != "c" && endpoint != set.pickKey(key) {
+			t.Fatalf("key %s moved from %s to %s\n", key, set.pickKey(key), endpoint)
+		}
+	}
+}
+
+func TestGetForKey(t *testing.T) {
+	endpoint1, stop1 := startTestServer(t, "127.0.0.1:0")
+	defer stop1()
+	endpoint2, stop2 := startTestServer(t, "127.0.0.1:0")
+	defer stop2()
+
+	pool := NewThriftPool(endpoint1, 1000, 60000, 10, 0)
+	defer pool.Close()
+	pool.SetTargets([]Target{{Endpoint: endpoint1, Weight: 1}, {Endpoint: endpoint2, Weight: 1}})
+
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		key := strconv.Itoa(i)
+		var endpoint string
+		for j := 0; j < 3; j++ {
+			conn, err := pool.GetForKey(context.Background(), key)
+			if err != nil {
+				t.Fatalf("GetForKey error:%s\n", err.Error())
+			}
+			if j > 0 && conn.GetEndpoint() != endpoint {
+				t.Errorf("key %s moved from %s to %s\n", key, endpoint, conn.GetEndpoint())
+			}
+			endpoint = conn.GetEndpoint()
+			_ = pool.Put(conn)
+		}
+		seen[endpoint] = true
+	}
+	if !seen[endpoint1] || !seen[endpoint2] {
+		t.Errorf("keys should spread over both endpoints, got %v\n", seen)
+	}
+	if pool.GetUsed() != 0 || pool.GetEndpointUsed(endpoint1) != 0 || pool.GetEndpointUsed(endpoint2) != 0 {
+		t.Errorf("all conns should be returned, used:%d\n", pool.GetUsed())
+	}
+}
+
+func TestGetForKeyResetsTag(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0)
+	defer pool.Close()
+	pool.SetTargets([]Target{{Endpoint: endpoint, Weight: 1}})
+
+	conn, err := pool.GetTagged(context.Background(), "checkout")
+	if err != nil {
+		t.Fatalf("GetTagged error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+
+	// 复用的空闲连接不应带着上一个借用者的标签
+	keyed, err := pool.GetForKey(context.Background(), "user")
+	if err != nil {
+		t.Fatalf("GetForKey error:%s\n", err.Error())
+	}
+	if keyed != conn {
+		t.Errorf("GetForKey should reuse the idle conn\n")
+	}
+	if keyed.GetTag() != "" {
+		t.Errorf("reused conn should not keep the previous tag, got %q\n", keyed.GetTag())
+	}
+	_ = pool.Put(keyed)
+}
+
+func TestGetForKeyFallback(t *testing.T) {
+	live, stopLive := startTestServer(t, "127.0.0.1:0")
+	defer stopLive()
+	down, stopDown := startTestServer(t, "127.0.0.1:0")
+	stopDown()
+
+	logger := &testLogger{}
+	pool := NewThriftPool(live, 1000, 60000, 10, 0, WithLogger(logger))
+	defer pool.Close()
+	pool.SetTargets([]Target{{Endpoint: live, Weight: 1}, {Endpoint: down, Weight: 1}})
+	targets := pool.loadBinding().targets
+
+	var liveKey, downKey string
+	for i := 0; liveKey == "" || downKey == ""; i++ {
+		key := strconv.Itoa(i)
+		if targets.pickKey(key) == live {
+			liveKey = key
+		} else {
+			downKey = key
+		}
+	}
+	// 先放回一个存活端点的空闲连接，回退时取用它
+	conn, err := pool.GetForKey(context.Background(), liveKey)
+	if err != nil {
+		t.Fatalf("GetForKey error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+
+	conn, err = pool.GetForKey(context.Background(), downKey)
+	if err != nil {
+		t.Fatalf("GetForKey should fall back to another endpoint:%s\n", err.Error())
+	}
+	if conn.GetEndpoint() != live {
+		t.Errorf("fallback conn endpoint is %s, want %s\n", conn.GetEndpoint(), live)
+	}
+	_ = pool.Put(conn)
+	if len(logger.lines) != 1 {
+		t.Errorf("affinity break should be logged once, got %v\n", logger.lines)
+	}
+}
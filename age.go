@@ -0,0 +1,56 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// 空闲连接的年龄（自创建起的时长）分布
+type AgeHistogram struct {
+	Under1s		int32	// 小于1秒
+	Under10s	int32	// 1秒到10秒
+	Under1m		int32	// 10秒到1分钟
+	Under10m	int32	// 1分钟到10分钟
+	Older		int32	// 10分钟及以上
+}
+
+// 统计空闲连接的年龄分布，可用于评估连接是否更替过快或存活过久
+func (t *ThriftPool) GetAgeHistogram() AgeHistogram {
+	var histogram AgeHistogram
+	nowTime := t.now()
+	t.rangeIdle(func(conn *ThriftConn) {
+		age := nowTime.Sub(conn.createdTime)
+		switch {
+		case age < time.Second:
+			histogram.Under1s++
+		case age < 10*time.Second:
+			histogram.Under10s++
+		case age < time.Minute:
+			histogram.Under1m++
+		case age < 10*time.Minute:
+			histogram.Under10m++
+		default:
+			histogram.Older++
+		}
+	})
+	return histogram
+}
+
+// 遍历空闲连接的快照
+// 遍历在持有空闲队列的锁时进行，不会取出连接，并发的 Get 在此期间短暂等待锁；f 应尽快返回
+func (t *ThriftPool) rangeIdle(f func(conn *ThriftConn)) {
+	t.idleConns.each(f)
+}
+
+// 把已预占空闲名额的连接放回队列，放不回时关闭连接
+func (t *ThriftPool) restoreIdle(conn *ThriftConn) {
+	if !t.idleConns.push(conn) {
+		t.subIdle()
+		t.discard(conn)
+		if atomic.LoadInt32(&t.closed) == 0 {
+			t.countEvicted(evictOverMax)
+			t.emit(EventEvicted, nil)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+)
+
+// 设置连接池的基础 ctx，适合由长期运行的服务组件持有的连接池。
+// 后台协程（回收、心跳、预热等）及其回调收到的 ctx 派生自 base，可携带日志、租户等值；
+// base 结束（如组件关闭）时连接池自动关闭，Close 只取消派生的 ctx，不影响 base。
+// Get 等借出方法在调用方 ctx 之外同时受连接池生命周期约束，连接池关闭时正在等待的借出立即返回
+func WithBaseContext(base context.Context) Option {
+	return func(t *ThriftPool) {
+		t.BaseContext = base
+	}
+}
+
+// base 结束时关闭连接池，Close 会等待后台协程退出，因此不能作为后台协程运行
+func (t *ThriftPool) watchBaseContext() {
+	go func() {
+		select {
+		case <-t.BaseContext.Done():
+		case <-t.ctx.Done():
+		}
+		if t.BaseContext.Err() != nil {
+			t.logf("base context done:%s, closing", t.BaseContext.Err().Error())
+			t.Close()
+		}
+	}()
+}
+
+// 合并调用方的 ctx 和连接池的生命周期：连接池关闭（包括 base 结束）时取消返回的 ctx，值仍取自调用方的 ctx。
+// 未设置基础 ctx 时原样返回，不增加开销。返回的 cancel 必须调用，以结束监听协程
+func (t *ThriftPool) withPoolContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.BaseContext == nil {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-t.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
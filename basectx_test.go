@@ -0,0 +1,92 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type tenantKey struct{}
+
+func TestBaseContext(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	base, cancelBase := context.WithCancel(context.WithValue(context.Background(), tenantKey{}, "acme"))
+	defer cancelBase()
+	tenants := make(chan interface{}, 10)
+	heartbeat := func(ctx context.Context, conn *ThriftConn) error {
+		select {
+		case tenants <- ctx.Value(tenantKey{}):
+		default:
+		}
+		return nil
+	}
+	pool := NewThriftPool(endpoint, 100, 60000, 1, 0, WithBaseContext(base), WithHeartbeat(heartbeat, 10, 100))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+	select {
+	case tenant := <-tenants:
+		if tenant != "acme" {
+			t.Errorf("heartbeat ctx should carry base values, got %v\n", tenant)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("heartbeat did not run\n")
+	}
+
+	// base 结束时正在等待的借出立即返回，连接池随之关闭
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	defer pool.Put(conn)
+	waitErr := make(chan error, 1)
+	go func() {
+		_, err := pool.GetPriority(context.Background(), PriorityHigh)
+		waitErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancelBase()
+	select {
+	case err := <-waitErr:
+		if err == nil {
+			t.Errorf("waiting get should fail after base is cancelled\n")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("waiting get not released by base cancellation\n")
+	}
+	select {
+	case <-pool.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("pool should close when base is cancelled\n")
+	}
+	pool.WaitStopped()
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("get after base cancellation should fail with ErrPoolClosed, got %v\n", err)
+	}
+}
+
+func TestBaseContextClose(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	base, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 0, WithBaseContext(base))
+	pool.Close()
+	if base.Err() != nil {
+		t.Errorf("Close should not cancel the base context\n")
+	}
+	select {
+	case <-pool.Done():
+	default:
+		t.Errorf("Close should cancel the derived context\n")
+	}
+}
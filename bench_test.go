@@ -0,0 +1,77 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"testing"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/tianxingpan/thriftpool/example/echo"
+	"github.com/tianxingpan/thriftpool/testutil"
+)
+
+// 连接池耗尽时 Get 失败路径的开销
+func BenchmarkGetExhausted(b *testing.B) {
+	endpoint, stop := startTestServer(b, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 1, 0)
+	defer pool.Close()
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		b.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	defer pool.Put(conn)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pool.Get(context.Background()); err == nil {
+			b.Fatalf("Get on an exhausted pool should fail\n")
+		}
+	}
+}
+
+// 在同一条连接上反复调用时 framed transport 的开销：
+// Pooled 复用连接池随连接保存的 framed transport，Fresh 每次调用都新建一个，用于对比 allocs/op
+func BenchmarkFramedCall(b *testing.B) {
+	endpoint, stop := testutil.StartEchoServer(b)
+	defer stop()
+	req := &echo.EchoReq{Msg: "hello"}
+
+	b.Run("Pooled", func(b *testing.B) {
+		pool := NewThriftPool(endpoint, 1000, 60000, 1, 0, WithFramedTransport(0))
+		defer pool.Close()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			conn, err := pool.Get(context.Background())
+			if err != nil {
+				b.Fatalf("pool.Get error:%s\n", err.Error())
+			}
+			if _, err = echo.NewEchoClientFactory(conn.ClientFactory()).Echo(req); err != nil {
+				b.Fatalf("Echo error:%s\n", err.Error())
+			}
+			pool.Put(conn)
+		}
+	})
+
+	b.Run("Fresh", func(b *testing.B) {
+		// 连接池不套 framed，由调用方每次新建
+		pool := NewThriftPool(endpoint, 1000, 60000, 1, 0)
+		defer pool.Close()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			conn, err := pool.Get(context.Background())
+			if err != nil {
+				b.Fatalf("pool.Get error:%s\n", err.Error())
+			}
+			transport, protocolFactory := conn.ClientFactory()
+			framed := thrift.NewTFramedTransportMaxLength(transport, thrift.DEFAULT_MAX_LENGTH)
+			if _, err = echo.NewEchoClientFactory(framed, protocolFactory).Echo(req); err != nil {
+				b.Fatalf("Echo error:%s\n", err.Error())
+			}
+			pool.Put(conn)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"time"
+)
+
+// 借出的连接，实现 io.Closer，便于用 defer 归还：
+//   borrowed, err := pool.Borrow(ctx)
+//   if err != nil { ... }
+//   defer borrowed.Close()
+//   if err := call(borrowed.Conn); err != nil { borrowed.Fail(err) }
+// 同一个 Borrowed 不应被多个协程同时使用
+type Borrowed struct {
+	Conn		*ThriftConn			// 借出的连接
+	pool		*ThriftPool
+	err			error				// Fail 记录的错误，非nil时 Close 关闭连接而不放回池
+	closed		bool
+	ctx			context.Context		// 带有本次借出截止时间的子context
+	cancel		context.CancelFunc
+	deadline	time.Time			// 按 ctx 设置socket超时所依据的截止时间
+	timeoutSet	bool				// 是否按 deadline 缩短了socket超时，Close 时恢复
+}
+
+// 从连接池借出一个连接，用完后调用 Close 归还
+// ctx 带有截止时间且剩余时间短于连接的读写超时（即 DialTimeout）时，把socket的读写超时缩短为剩余时间，
+// 使单次读写不会超过 ctx 的截止时间，Close 时恢复；生效的截止时间见 Deadline，
+// 下游代码可用 Context 取得带有同一截止时间的子context，使业务超时与传输超时保持一致。
+// 注意 thrift 的socket超时作用于每次读写，一次调用包含多次读写时总耗时仍可能略超过截止时间
+func (t *ThriftPool) Borrow(ctx context.Context) (*Borrowed, error) {
+	conn, err := t.GetTagged(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	b := &Borrowed{Conn: conn, pool: t}
+	b.ctx, b.cancel = context.WithCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		b.deadline = deadline
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			remaining = time.Millisecond
+		}
+		if socket := conn.GetSocket(); socket != nil && (t.DialTimeout <= 0 || remaining < t.DialTimeout) {
+			_ = socket.SetTimeout(remaining)
+			b.timeoutSet = true
+		}
+	}
+	return b, nil
+}
+
+// 本次借出的截止时间，即 Borrow 时 ctx 的截止时间，ctx 没有截止时间时 ok 为 false
+func (b *Borrowed) Deadline() (deadline time.Time, ok bool) {
+	return b.deadline, !b.deadline.IsZero()
+}
+
+// 带有本次借出截止时间的子context，Close 后被取消，ctx 没有截止时间时同样如此
+func (b *Borrowed) Context() context.Context {
+	return b.ctx
+}
+
+// 标记连接已损坏（如调用出错后连接状态不确定），Close 时关闭连接而不放回池，err 为nil时忽略
+func (b *Borrowed) Fail(err error) {
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+// Fail 记录的第一个错误
+func (b *Borrowed) Err() error {
+	return b.err
+}
+
+// 归还连接，Fail 过的连接会被关闭；可重复调用，只有第一次生效
+func (b *Borrowed) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	b.cancel()
+	if b.err != nil {
+		_ = b.Conn.CloseTransport()
+	} else if b.timeoutSet {
+		// 恢复拨号时设置的读写超时
+		_ = b.Conn.GetSocket().SetTimeout(b.pool.DialTimeout)
+	}
+	return b.pool.Put(b.Conn)
+}
@@ -0,0 +1,112 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBorrow(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1)
+	defer pool.Close()
+
+	borrowed, err := pool.Borrow(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Borrow error:%s\n", err.Error())
+	}
+	ctx := borrowed.Context()
+	var closer io.Closer = borrowed
+	if err := closer.Close(); err != nil {
+		t.Errorf("Close error:%s\n", err)
+	}
+	_ = borrowed.Close()
+	// ctx 没有截止时间时 Close 也要取消 Context
+	if ctx.Err() != context.Canceled {
+		t.Errorf("Context without a deadline should be cancelled after Close, got %v\n", ctx.Err())
+	}
+	if pool.GetIdle() != 1 || pool.GetUsed() != 0 {
+		t.Errorf("healthy conn should be pooled once, idle:%d, used:%d\n", pool.GetIdle(), pool.GetUsed())
+	}
+
+	borrowed, err = pool.Borrow(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Borrow error:%s\n", err.Error())
+	}
+	borrowed.Fail(errors.New("broken pipe"))
+	_ = borrowed.Close()
+	if !borrowed.Conn.IsClose() || pool.GetIdle() != 0 || pool.GetOpen() != 0 {
+		t.Errorf("failed conn should be discarded, idle:%d, open:%d\n", pool.GetIdle(), pool.GetOpen())
+	}
+}
+
+func TestBorrowDeadline(t *testing.T) {
+	// 只接受连接不应答的服务端，读操作只会因超时返回
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error:%s\n", err.Error())
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	pool := NewThriftPool(listener.Addr().String(), 300, 60000, 10, 0)
+	defer pool.Close()
+
+	deadline := time.Now().Add(50 * time.Millisecond)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	borrowed, err := pool.Borrow(ctx)
+	if err != nil {
+		t.Fatalf("pool.Borrow error:%s\n", err.Error())
+	}
+	if d, ok := borrowed.Deadline(); !ok || !d.Equal(deadline) {
+		t.Errorf("Deadline should be the ctx deadline, got %v %v\n", d, ok)
+	}
+	if d, ok := borrowed.Context().Deadline(); !ok || !d.Equal(deadline) {
+		t.Errorf("Context should carry the deadline, got %v %v\n", d, ok)
+	}
+	startTime := time.Now()
+	if _, err := borrowed.Conn.GetTransport().Read(make([]byte, 1)); err == nil {
+		t.Fatalf("read from a silent server should time out\n")
+	}
+	if elapsed := time.Since(startTime); elapsed > 200*time.Millisecond {
+		t.Errorf("read should time out at the ctx deadline, took %s\n", elapsed)
+	}
+	_ = borrowed.Close()
+	if borrowed.Context().Err() == nil {
+		t.Errorf("Context should be cancelled after Close\n")
+	}
+
+	// 归还后恢复拨号时的读写超时
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	startTime = time.Now()
+	_, _ = conn.GetTransport().Read(make([]byte, 1))
+	if elapsed := time.Since(startTime); elapsed < 250*time.Millisecond {
+		t.Errorf("socket timeout should be restored after Close, read returned after %s\n", elapsed)
+	}
+	_ = pool.Put(conn)
+
+	borrowed, err = pool.Borrow(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Borrow error:%s\n", err.Error())
+	}
+	if _, ok := borrowed.Deadline(); ok {
+		t.Errorf("Borrow without a ctx deadline should have no deadline\n")
+	}
+	_ = borrowed.Close()
+}
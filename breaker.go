@@ -0,0 +1,84 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// 熔断打开时，Get 不再拨号新连接，直接返回该错误
+var ErrBreakerOpen = errors.New("thriftpool circuit breaker open")
+
+// 熔断是否打开，即连续拨号失败次数是否已达到 BreakerThreshold
+func (t *ThriftPool) IsBreakerOpen() bool {
+	threshold := atomic.LoadInt32(&t.BreakerThreshold)
+	if threshold < 1 {
+		return false
+	}
+	return atomic.LoadInt32(&t.dialFailures) >= threshold
+}
+
+// 连续拨号失败次数，拨号成功后清零
+func (t *ThriftPool) GetDialFailures() int32 {
+	return atomic.LoadInt32(&t.dialFailures)
+}
+
+// 设置熔断阈值，小于1表示不启用熔断
+func (t *ThriftPool) SetBreakerThreshold(threshold int32) {
+	if threshold < 1 {
+		atomic.StoreInt32(&t.BreakerThreshold, 0)
+	} else {
+		atomic.StoreInt32(&t.BreakerThreshold, threshold)
+	}
+}
+
+// 设置熔断冷却时长，单位毫秒
+func (t *ThriftPool) SetBreakerCooldown(cooldown int32) {
+	if cooldown < 1 {
+		t.BreakerCooldown = time.Duration(1000) * time.Millisecond
+	} else {
+		t.BreakerCooldown = time.Duration(cooldown) * time.Millisecond
+	}
+}
+
+// 探测端点是否可用：拨号一个新连接后立即关闭，不经过连接池计数。
+// 探测成功会关闭熔断，失败则计入连续拨号失败次数
+func (t *ThriftPool) Probe() error {
+	conn, err := t.dial(t.ctx)
+	if err != nil {
+		return t.nameErr(err)
+	}
+	t.discard(conn)
+	return nil
+}
+
+// 是否允许拨号新连接。
+// 熔断打开期间拒绝拨号，冷却期过后只放行一次试探，试探失败则重新进入冷却期
+func (t *ThriftPool) allowDial() bool {
+	if !t.IsBreakerOpen() {
+		return true
+	}
+	openTime := atomic.LoadInt64(&t.breakerOpenTime)
+	nowTime := t.now().UnixNano()
+	if nowTime-openTime < int64(t.BreakerCooldown) {
+		return false
+	}
+	return atomic.CompareAndSwapInt64(&t.breakerOpenTime, openTime, nowTime)
+}
+
+func (t *ThriftPool) dialFailed() {
+	failures := atomic.AddInt32(&t.dialFailures, 1)
+	if failures == atomic.LoadInt32(&t.BreakerThreshold) {
+		atomic.StoreInt64(&t.breakerOpenTime, t.now().UnixNano())
+	}
+}
+
+func (t *ThriftPool) dialSucceeded() {
+	atomic.StoreInt32(&t.dialFailures, 0)
+	t.resumeWarmUp()
+}
+
+func (t *ThriftPool) GetBreakerThreshold() int32 {
+	return atomic.LoadInt32(&t.BreakerThreshold)
+}
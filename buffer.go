@@ -0,0 +1,58 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"sync/atomic"
+)
+
+// 为每个连接套上带缓冲的transport，bufferSize 为读写缓冲各自的大小（字节），小于1时不套缓冲（默认）。
+// budget 为连接池所有连接的缓冲总预算（字节），小于1时不限制；
+// 再套一个缓冲会超出预算时，新连接直接使用不带缓冲的transport，避免大量空闲连接占用过多内存
+func WithBufferedTransport(bufferSize int32, budget int64) Option {
+	return func(t *ThriftPool) {
+		if bufferSize > 0 {
+			t.BufferSize = bufferSize
+		} else {
+			t.BufferSize = 0
+		}
+		if budget > 0 {
+			t.BufferBudget = budget
+		} else {
+			t.BufferBudget = 0
+		}
+	}
+}
+
+// 所有连接当前占用的缓冲总大小（字节）
+func (t *ThriftPool) GetBufferedBytes() int64 {
+	return atomic.LoadInt64(&t.bufferedBytes)
+}
+
+// 预算允许时为连接套上缓冲
+func (t *ThriftPool) wrapBuffered(conn *ThriftConn) {
+	if t.BufferSize < 1 {
+		return
+	}
+	// 读写各一个缓冲
+	size := int64(t.BufferSize) * 2
+	for {
+		bufferedBytes := atomic.LoadInt64(&t.bufferedBytes)
+		if t.BufferBudget > 0 && bufferedBytes+size > t.BufferBudget {
+			return
+		}
+		if atomic.CompareAndSwapInt64(&t.bufferedBytes, bufferedBytes, bufferedBytes+size) {
+			break
+		}
+	}
+	conn.transport = thrift.NewTBufferedTransport(conn.transport, int(t.BufferSize))
+	conn.bufferBytes = size
+}
+
+// 连接关闭后释放其占用的缓冲预算
+func (t *ThriftPool) releaseBuffer(conn *ThriftConn) {
+	if conn.bufferBytes > 0 {
+		atomic.AddInt64(&t.bufferedBytes, -conn.bufferBytes)
+		conn.bufferBytes = 0
+	}
+}
@@ -0,0 +1,43 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"testing"
+)
+
+func TestBufferBudget(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1, WithBufferedTransport(1024, 4096))
+	defer pool.Close()
+
+	conns := make([]*ThriftConn, 0, 3)
+	for i := 0; i < 3; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns = append(conns, conn)
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := conns[i].GetTransport().(*thrift.TBufferedTransport); !ok {
+			t.Errorf("conn %d should be buffered\n", i)
+		}
+	}
+	if conns[2].GetTransport() != conns[2].GetSocket() {
+		t.Errorf("conn over budget should use the bare socket\n")
+	}
+	if pool.GetBufferedBytes() != 4096 {
+		t.Errorf("buffered bytes is %d, want 4096\n", pool.GetBufferedBytes())
+	}
+
+	_ = conns[0].Close()
+	_ = pool.Put(conns[0])
+	if pool.GetBufferedBytes() != 2048 {
+		t.Errorf("buffered bytes is %d, want 2048 after discard\n", pool.GetBufferedBytes())
+	}
+	_ = pool.Put(conns[1])
+	_ = pool.Put(conns[2])
+}
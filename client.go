@@ -0,0 +1,23 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 返回新建 thrift 客户端所需的 transport 和 protocol 工厂，可直接传给生成代码的构造函数：
+//   client := echo.NewEchoClientFactory(conn.ClientFactory())
+// 生成的客户端内部维护 SeqId 并缓存首次使用的 protocol，不能在多个协程或多次借出之间共用：
+// 并发共用时 SeqId 与响应错位，返回 "out of sequence response"；
+// 跨借出共用时仍会读写之前连接的 protocol。因此每次借出连接都应新建客户端，SeqId 从头开始。
+// 返回的 transport 为 GuardedTransport，调用中写失败的连接不会被放回池；protocol 工厂见 SetProtocolFactory
+func (t *ThriftConn) ClientFactory() (thrift.TTransport, thrift.TProtocolFactory) {
+	return t.GuardedTransport(), t.pool.protocolFactory()
+}
+
+// 以 factory 在借出的连接上新建客户端，factory 一般为生成代码的 NewXxxClientFactory：
+//   client := thriftpool.ClientFor(conn, echo.NewEchoClientFactory)
+// 客户端只应在本次借出期间使用，Put 之后丢弃，下次借出再调用 ClientFor，SeqId 不会跨借出残留
+func ClientFor[T any](conn *ThriftConn, factory func(thrift.TTransport, thrift.TProtocolFactory) T) T {
+	return factory(conn.ClientFactory())
+}
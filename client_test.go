@@ -0,0 +1,177 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 启动一个按 binary protocol 应答的服务端，对每个请求原样返回其 SeqId
+func startSeqServer(t *testing.T) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error:%s\n", err.Error())
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				socket := thrift.NewTSocketFromConnTimeout(conn, 0)
+				prot := thrift.NewTBinaryProtocolTransport(socket)
+				for {
+					name, _, seqId, err := prot.ReadMessageBegin()
+					if err != nil {
+						return
+					}
+					if prot.Skip(thrift.STRUCT) != nil || prot.ReadMessageEnd() != nil {
+						return
+					}
+					_ = prot.WriteMessageBegin(name, thrift.REPLY, seqId)
+					_ = prot.WriteStructBegin("result")
+					_ = prot.WriteFieldStop()
+					_ = prot.WriteStructEnd()
+					_ = prot.WriteMessageEnd()
+					if prot.Flush() != nil {
+						return
+					}
+				}
+			}(conn)
+		}
+	}()
+	return listener.Addr().String(), func() { _ = listener.Close() }
+}
+
+// 模拟生成代码的客户端：send 时 SeqId 加1，recv 时校验响应的 SeqId
+type seqClient struct {
+	SeqId	int32
+	prot	thrift.TProtocol
+}
+
+func newSeqClient(trans thrift.TTransport, f thrift.TProtocolFactory) *seqClient {
+	return &seqClient{prot: f.GetProtocol(trans)}
+}
+
+func (c *seqClient) send() error {
+	c.SeqId++
+	if err := c.prot.WriteMessageBegin("ping", thrift.CALL, c.SeqId); err != nil {
+		return err
+	}
+	if err := c.prot.WriteStructBegin("args"); err != nil {
+		return err
+	}
+	if err := c.prot.WriteFieldStop(); err != nil {
+		return err
+	}
+	if err := c.prot.WriteStructEnd(); err != nil {
+		return err
+	}
+	if err := c.prot.WriteMessageEnd(); err != nil {
+		return err
+	}
+	return c.prot.Flush()
+}
+
+func (c *seqClient) recv() error {
+	_, _, seqId, err := c.prot.ReadMessageBegin()
+	if err != nil {
+		return err
+	}
+	if c.SeqId != seqId {
+		return thrift.NewTApplicationException(thrift.BAD_SEQUENCE_ID, "ping failed: out of sequence response")
+	}
+	if err := c.prot.Skip(thrift.STRUCT); err != nil {
+		return err
+	}
+	return c.prot.ReadMessageEnd()
+}
+
+func (c *seqClient) call() error {
+	if err := c.send(); err != nil {
+		return err
+	}
+	return c.recv()
+}
+
+func TestClientFactory(t *testing.T) {
+	endpoint, stop := startSeqServer(t)
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1)
+	defer pool.Close()
+
+	// 每次借出都新建客户端，SeqId 从头开始
+	for i := 0; i < 3; i++ {
+		err := pool.Do(func(conn *ThriftConn) error {
+			client := newSeqClient(conn.ClientFactory())
+			if err := client.call(); err != nil {
+				return err
+			}
+			if client.SeqId != 1 {
+				t.Errorf("fresh client should start with SeqId 1, got %d\n", client.SeqId)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("call with a fresh client failed:%s\n", err)
+		}
+	}
+
+	// 两个调用共用一个客户端且交错执行（即并发共用），第一个调用读到响应时 SeqId 已被第二个调用改写
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	shared := newSeqClient(conn.ClientFactory())
+	if err := shared.send(); err != nil {
+		t.Fatalf("send error:%s\n", err.Error())
+	}
+	if err := shared.send(); err != nil {
+		t.Fatalf("send error:%s\n", err.Error())
+	}
+	err = shared.recv()
+	if err == nil || !strings.Contains(err.Error(), "out of sequence") {
+		t.Errorf("shared client should fail with out of sequence response, got %v\n", err)
+	}
+	_ = conn.CloseTransport()
+	_ = pool.Put(conn)
+}
+
+func TestClientFor(t *testing.T) {
+	endpoint, stop := startSeqServer(t)
+	defer stop()
+	pool := NewThriftPool(endpoint, 1, 60000, 10, 1)
+	defer pool.Close()
+
+	// 池中只有一个连接，两次借出拿到同一个底层连接，客户端各自从 SeqId 1 开始单调递增
+	var first *ThriftConn
+	for i := 0; i < 2; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		if first == nil {
+			first = conn
+		} else if conn != first {
+			t.Errorf("second borrow should reuse the same connection\n")
+		}
+		client := ClientFor(conn, newSeqClient)
+		for seqId := int32(1); seqId <= 3; seqId++ {
+			if err := client.call(); err != nil {
+				t.Errorf("borrow %d call %d failed:%s\n", i, seqId, err)
+			}
+			if client.SeqId != seqId {
+				t.Errorf("borrow %d expected SeqId %d, got %d\n", i, seqId, client.SeqId)
+			}
+		}
+		if err := pool.Put(conn); err != nil {
+			t.Errorf("pool.Put error:%s\n", err.Error())
+		}
+	}
+}
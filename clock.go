@@ -0,0 +1,11 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"time"
+)
+
+// 当前时间，测试时可通过替换 nowFunc 模拟时间流逝
+func (t *ThriftPool) now() time.Time {
+	return t.nowFunc()
+}
@@ -0,0 +1,79 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 可手动推进的时钟
+type fakeClock struct {
+	nanos	int64
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{nanos: time.Now().UnixNano()}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&c.nanos))
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	atomic.AddInt64(&c.nanos, int64(d))
+}
+
+// 替换连接池取当前时间的函数，需在后台协程启动前生效，因此以 Option 传入
+func withClock(nowFunc func() time.Time) Option {
+	return func(t *ThriftPool) {
+		t.nowFunc = nowFunc
+	}
+}
+
+func TestIdleTimeoutWithFakeClock(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	clock := newFakeClock()
+	pool := NewThriftPool(endpoint, 1000, 10000, 10, 1, withClock(clock.Now))
+	defer pool.Close()
+
+	conns := make([]*ThriftConn, 0, 3)
+	for i := 0; i < 3; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns = append(conns, conn)
+	}
+	// 借出超过闲置超时后归还，超出 InitSize 的连接在 Put 时直接关闭
+	clock.Advance(11 * time.Second)
+	for _, conn := range conns {
+		_ = pool.Put(conn)
+	}
+	if pool.GetIdle() != 1 || pool.GetOpen() != 1 {
+		t.Errorf("conns idle past the timeout should be closed on Put, idle:%d, open:%d\n",
+			pool.GetIdle(), pool.GetOpen())
+	}
+
+	// 放回的空闲连接在闲置超时前不会被回收，超时后回收到 InitSize
+	for i := 0; i < 2; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns[i] = conn
+	}
+	_ = pool.Put(conns[0])
+	_ = pool.Put(conns[1])
+	pool.releaseIdle()
+	if pool.GetIdle() != 2 {
+		t.Errorf("fresh idle conns should be kept, idle:%d\n", pool.GetIdle())
+	}
+	clock.Advance(11 * time.Second)
+	pool.releaseIdle()
+	if pool.GetIdle() != 1 || pool.GetOpen() != 1 {
+		t.Errorf("reaper should trim idle conns to InitSize, idle:%d, open:%d\n", pool.GetIdle(), pool.GetOpen())
+	}
+}
@@ -0,0 +1,145 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 配置项的值不合法，由 NewThriftPoolFromConfig 包装在 *ConfigError 中返回
+var ErrBadConfig = errors.New("thriftpool bad config")
+
+// 可序列化的连接池配置，用于从 JSON/YAML 等配置文件声明式地创建连接池，参见 NewThriftPoolFromConfig。
+// 时长的单位均为毫秒，为0的项使用默认值或不启用，与对应的 Option 一致
+type PoolConfig struct {
+	Name				string	`json:"name,omitempty" yaml:"name,omitempty"`						// 连接池名称
+	Endpoint			string	`json:"endpoint" yaml:"endpoint"`									// 服务端的端点
+	DialTimeout			int32	`json:"dial_timeout,omitempty" yaml:"dial_timeout,omitempty"`		// 拨号超时
+	IdleTimeout			int32	`json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`		// 空闲连接超时
+	MaxSize				int32	`json:"max_size,omitempty" yaml:"max_size,omitempty"`				// 最大连接数，为0时为100
+	InitSize			int32	`json:"init_size,omitempty" yaml:"init_size,omitempty"`				// 初始连接数
+	MaxIdle				int32	`json:"max_idle,omitempty" yaml:"max_idle,omitempty"`				// 最大空闲连接数
+	MaxConnLifetime		int32	`json:"max_conn_lifetime,omitempty" yaml:"max_conn_lifetime,omitempty"`	// 连接最长存活时长
+	MaxBorrowTime		int32	`json:"max_borrow_time,omitempty" yaml:"max_borrow_time,omitempty"`	// 连接最长借出时长
+	MaxOverflow			int32	`json:"max_overflow,omitempty" yaml:"max_overflow,omitempty"`		// 连接池耗尽时的临时连接数
+	MaxConcurrentDials	int32	`json:"max_concurrent_dials,omitempty" yaml:"max_concurrent_dials,omitempty"`	// Get 同时拨号的最大数
+	MaxProbes			int32	`json:"max_probes,omitempty" yaml:"max_probes,omitempty"`			// 同时借出的探测连接的最大数
+	FramedTransport		bool	`json:"framed_transport,omitempty" yaml:"framed_transport,omitempty"`	// 是否使用 framed transport
+	MaxFrameSize		int32	`json:"max_frame_size,omitempty" yaml:"max_frame_size,omitempty"`	// framed transport 允许读取的最大帧
+	LenientSizes		bool	`json:"lenient_sizes,omitempty" yaml:"lenient_sizes,omitempty"`		// 是否接受被调整的连接数参数
+}
+
+// 配置项不合法，Field 为配置项的 json 名称
+type ConfigError struct {
+	Field	string	// 不合法的配置项
+	Err		error	// 原因，包装了 ErrBadConfig、ErrBadEndpoint 或 ErrBadSize
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("thriftpool config %s: %s", e.Field, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// 按配置创建连接池，opts 用于补充无法序列化的配置（如 WithLogger），在配置之后生效。
+// 配置不合法时不创建连接池，返回 *ConfigError；连接数参数需要调整时同 NewThriftPoolChecked，
+// 除非 LenientSizes 为 true。与 NewThriftPool 一样不预先拨号
+func NewThriftPoolFromConfig(cfg PoolConfig, opts ...Option) (*ThriftPool, error) {
+	endpoint, err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	t := NewThriftPool(endpoint, cfg.DialTimeout, cfg.IdleTimeout, cfg.MaxSize, cfg.InitSize,
+		append(cfg.options(), opts...)...)
+	if err := t.checkSizes(); err != nil {
+		t.Close()
+		return nil, &ConfigError{Field: "max_size", Err: t.nameErr(err)}
+	}
+	return t, nil
+}
+
+// 当前连接池的配置，可序列化后用 NewThriftPoolFromConfig 重建同样配置的连接池
+// 连接数为调整后的实际值，因此 LenientSizes 总是 false
+func (t *ThriftPool) Config() PoolConfig {
+	cfg := PoolConfig{
+		Name:               t.Name,
+		Endpoint:           t.GetEndpoint(),
+		DialTimeout:        int32(t.DialTimeout / time.Millisecond),
+		IdleTimeout:        int32(t.IdleTimeout / time.Millisecond),
+		MaxSize:            t.GetMaxSize(),
+		InitSize:           t.GetInitSize(),
+		MaxIdle:            t.GetMaxIdle(),
+		MaxConnLifetime:    int32(t.MaxConnLifetime / time.Millisecond),
+		MaxBorrowTime:      int32(t.MaxBorrowTime / time.Millisecond),
+		MaxOverflow:        t.GetMaxOverflow(),
+		MaxConcurrentDials: t.MaxConcurrentDials,
+		MaxProbes:          t.MaxProbes,
+		FramedTransport:    t.FramedTransport,
+	}
+	if t.FramedTransport {
+		cfg.MaxFrameSize = int32(t.MaxFrameSize)
+	}
+	return cfg
+}
+
+// 校验配置，返回规范化后的端点
+func (cfg PoolConfig) validate() (string, error) {
+	endpoint, err := NormalizeEndpoint(cfg.Endpoint)
+	if err != nil {
+		return "", &ConfigError{Field: "endpoint", Err: err}
+	}
+	fields := []struct {
+		name	string
+		value	int32
+	}{
+		{"dial_timeout", cfg.DialTimeout},
+		{"idle_timeout", cfg.IdleTimeout},
+		{"max_size", cfg.MaxSize},
+		{"init_size", cfg.InitSize},
+		{"max_idle", cfg.MaxIdle},
+		{"max_conn_lifetime", cfg.MaxConnLifetime},
+		{"max_borrow_time", cfg.MaxBorrowTime},
+		{"max_overflow", cfg.MaxOverflow},
+		{"max_concurrent_dials", cfg.MaxConcurrentDials},
+		{"max_probes", cfg.MaxProbes},
+		{"max_frame_size", cfg.MaxFrameSize},
+	}
+	for _, field := range fields {
+		if field.value < 0 {
+			return "", &ConfigError{Field: field.name, Err: fmt.Errorf("%w: %d is negative", ErrBadConfig, field.value)}
+		}
+	}
+	if cfg.MaxSize > 0 && cfg.InitSize > cfg.MaxSize {
+		return "", &ConfigError{Field: "init_size", Err: fmt.Errorf("%w: %d exceeds max_size %d",
+			ErrBadConfig, cfg.InitSize, cfg.MaxSize)}
+	}
+	if cfg.MaxFrameSize > 0 && !cfg.FramedTransport {
+		return "", &ConfigError{Field: "max_frame_size", Err: fmt.Errorf("%w: set without framed_transport", ErrBadConfig)}
+	}
+	return endpoint, nil
+}
+
+// 配置对应的 Option，连接数和超时由 NewThriftPool 的参数传入
+func (cfg PoolConfig) options() []Option {
+	opts := []Option{
+		WithLenientSizes(cfg.LenientSizes),
+		WithMaxIdle(cfg.MaxIdle),
+		WithMaxConnLifetime(cfg.MaxConnLifetime),
+		WithMaxConcurrentDials(cfg.MaxConcurrentDials),
+		WithMaxProbes(cfg.MaxProbes),
+		WithOverflow(cfg.MaxOverflow),
+	}
+	if cfg.Name != "" {
+		opts = append(opts, WithName(cfg.Name))
+	}
+	if cfg.MaxBorrowTime > 0 {
+		opts = append(opts, WithMaxBorrowTime(cfg.MaxBorrowTime, nil))
+	}
+	if cfg.FramedTransport {
+		opts = append(opts, WithFramedTransport(cfg.MaxFrameSize))
+	}
+	return opts
+}
@@ -0,0 +1,76 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewThriftPoolFromConfig(t *testing.T) {
+	data := []byte(`{"name":"echo","endpoint":"127.0.0.1:9898","dial_timeout":500,"idle_timeout":30000,
+		"max_size":20,"init_size":5,"max_idle":10,"max_conn_lifetime":60000,"framed_transport":true}`)
+	var cfg PoolConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("unmarshal config failed:%s\n", err)
+	}
+	pool, err := NewThriftPoolFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewThriftPoolFromConfig failed:%s\n", err)
+	}
+	defer pool.Close()
+	if pool.Name != "echo" || pool.DialTimeout != 500*time.Millisecond || pool.GetMaxSize() != 20 ||
+		pool.GetInitSize() != 5 || pool.GetMaxIdle() != 10 || pool.MaxConnLifetime != time.Minute || !pool.FramedTransport {
+		t.Errorf("pool should follow the config, got %+v\n", pool.Config())
+	}
+
+	// 导出的配置可以重建同样配置的连接池
+	data, err = json.Marshal(pool.Config())
+	if err != nil {
+		t.Fatalf("marshal config failed:%s\n", err)
+	}
+	var restored PoolConfig
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("unmarshal config failed:%s\n", err)
+	}
+	if restored != pool.Config() {
+		t.Errorf("config should survive a round trip, got %+v\n", restored)
+	}
+	clone, err := NewThriftPoolFromConfig(restored)
+	if err != nil {
+		t.Fatalf("restore config failed:%s\n", err)
+	}
+	if clone.Config() != pool.Config() {
+		t.Errorf("restored pool should have the same config, got %+v\n", clone.Config())
+	}
+	clone.Close()
+}
+
+func TestBadConfig(t *testing.T) {
+	cases := []struct {
+		cfg		PoolConfig
+		field	string
+		err		error
+	}{
+		{PoolConfig{Endpoint: "localhost"}, "endpoint", ErrBadEndpoint},
+		{PoolConfig{Endpoint: "127.0.0.1:9898", IdleTimeout: -1}, "idle_timeout", ErrBadConfig},
+		{PoolConfig{Endpoint: "127.0.0.1:9898", MaxSize: 5, InitSize: 8}, "init_size", ErrBadConfig},
+		{PoolConfig{Endpoint: "127.0.0.1:9898", MaxSize: 10, InitSize: 8}, "max_size", ErrBadSize},
+		{PoolConfig{Endpoint: "127.0.0.1:9898", MaxFrameSize: 1024}, "max_frame_size", ErrBadConfig},
+	}
+	for _, c := range cases {
+		pool, err := NewThriftPoolFromConfig(c.cfg)
+		var configErr *ConfigError
+		if pool != nil || !errors.As(err, &configErr) || configErr.Field != c.field || !errors.Is(err, c.err) {
+			t.Errorf("config %+v should fail on %s with %v, got %v\n", c.cfg, c.field, c.err, err)
+		}
+	}
+
+	pool, err := NewThriftPoolFromConfig(PoolConfig{Endpoint: "127.0.0.1:9898", MaxSize: 10, InitSize: 8, LenientSizes: true})
+	if err != nil {
+		t.Errorf("lenient sizes should not fail:%s\n", err)
+	} else {
+		pool.Close()
+	}
+}
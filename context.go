@@ -0,0 +1,35 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+)
+
+// context 中没有连接池，由 DoContext 返回
+var ErrNoPoolInContext = errors.New("thriftpool no pool in context")
+
+type poolContextKey struct{}
+
+// 返回携带连接池的子context，供中间件把连接池传给下游处理函数，而不必使用全局变量
+func NewContext(ctx context.Context, pool *ThriftPool) context.Context {
+	return context.WithValue(ctx, poolContextKey{}, pool)
+}
+
+// 取出 NewContext 放入的连接池
+func FromContext(ctx context.Context) (*ThriftPool, bool) {
+	pool, ok := ctx.Value(poolContextKey{}).(*ThriftPool)
+	return pool, ok && pool != nil
+}
+
+// 用 context 中的连接池执行 fn，参见 ThriftPool 的 Do；取连接受 ctx 的截止时间和取消控制
+func DoContext(ctx context.Context, fn func(conn *ThriftConn) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	pool, ok := FromContext(ctx)
+	if !ok {
+		return ErrNoPoolInContext
+	}
+	return pool.doCtx(ctx, fn)
+}
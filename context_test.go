@@ -0,0 +1,162 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+func TestDoContext(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1)
+	defer pool.Close()
+
+	if err := DoContext(context.Background(), func(conn *ThriftConn) error { return nil }); err != ErrNoPoolInContext {
+		t.Errorf("DoContext error is %v, want ErrNoPoolInContext\n", err)
+	}
+
+	ctx := NewContext(context.Background(), pool)
+	if p, ok := FromContext(ctx); !ok || p != pool {
+		t.Fatalf("FromContext should return the stored pool\n")
+	}
+	err := DoContext(ctx, func(conn *ThriftConn) error {
+		if conn.GetEndpoint() != endpoint {
+			t.Errorf("conn endpoint is %s, want %s\n", conn.GetEndpoint(), endpoint)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DoContext error:%s\n", err.Error())
+	}
+	if pool.GetIdle() != 1 || pool.GetUsed() != 0 {
+		t.Errorf("conn should be returned, idle:%d, used:%d\n", pool.GetIdle(), pool.GetUsed())
+	}
+
+	callErr := thrift.NewTTransportException(thrift.END_OF_FILE, "call failed")
+	if err = DoContext(ctx, func(conn *ThriftConn) error { return callErr }); err != callErr {
+		t.Errorf("DoContext error is %v, want the call error\n", err)
+	}
+	if pool.GetIdle() != 0 || pool.GetUsed() != 0 || pool.GetOpen() != 0 {
+		t.Errorf("failed conn should be discarded, idle:%d, used:%d, open:%d\n",
+			pool.GetIdle(), pool.GetUsed(), pool.GetOpen())
+	}
+}
+
+func TestGetCancelledContext(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1)
+	defer pool.Close()
+	if err := pool.WarmUp(context.Background()); err != nil {
+		t.Fatalf("pool.WarmUp error:%s\n", err.Error())
+	}
+
+	// 即使有空闲连接，ctx 已取消时也不借出
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.Get(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get with a cancelled ctx should return context.Canceled, got %v\n", err)
+	}
+	if pool.GetIdle() != 1 || pool.GetUsed() != 0 {
+		t.Errorf("cancelled Get should not take a conn, idle:%d, used:%d\n", pool.GetIdle(), pool.GetUsed())
+	}
+}
+
+// 模拟不响应的端点：拨号阻塞到 ctx 结束
+func blockDial(t *testing.T) {
+	saved := dialContext
+	t.Cleanup(func() { dialContext = saved })
+	dialContext = func(dialer *net.Dialer, ctx context.Context, endpoint string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+}
+
+func TestGetDeadlineShorterThanDialTimeout(t *testing.T) {
+	blockDial(t)
+	pool := NewThriftPool("127.0.0.1:9898", 5000, 60000, 10, 0)
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	startTime := time.Now()
+	if _, err := pool.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get from a blocked endpoint should fail with the ctx deadline, got %v\n", err)
+	}
+	if elapsed := time.Since(startTime); elapsed > time.Second {
+		t.Errorf("ctx deadline should bound the dial, elapsed:%s\n", elapsed)
+	}
+	if pool.GetUsed() != 0 || pool.GetOpen() != 0 {
+		t.Errorf("timed out Get should release used and open, used:%d, open:%d\n", pool.GetUsed(), pool.GetOpen())
+	}
+}
+
+func TestDoContextCancelDuringDial(t *testing.T) {
+	blockDial(t)
+	pool := NewThriftPool("127.0.0.1:9898", 5000, 60000, 10, 0)
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(NewContext(context.Background(), pool))
+	go func() {
+		for pool.GetDialing() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	called := false
+	startTime := time.Now()
+	err := DoContext(ctx, func(conn *ThriftConn) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("DoContext cancelled during the dial should return context.Canceled, got %v\n", err)
+	}
+	if elapsed := time.Since(startTime); elapsed > time.Second {
+		t.Errorf("cancelled ctx should stop the dial, elapsed:%s\n", elapsed)
+	}
+	if called {
+		t.Errorf("fn should not run without a conn\n")
+	}
+	if pool.GetUsed() != 0 || pool.GetOpen() != 0 {
+		t.Errorf("cancelled DoContext should release used and open, used:%d, open:%d\n", pool.GetUsed(), pool.GetOpen())
+	}
+}
+
+func TestGetDeadlineWaitingDialSlot(t *testing.T) {
+	blockDial(t)
+	pool := NewThriftPool("127.0.0.1:9898", 5000, 60000, 10, 0, WithMaxConcurrentDials(1))
+	defer pool.Close()
+
+	// 占住唯一的拨号名额
+	holdCtx, release := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = pool.Get(holdCtx)
+	}()
+	for pool.GetDialing() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	startTime := time.Now()
+	if _, err := pool.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get waiting for a dial slot should return the ctx error, got %v\n", err)
+	}
+	if elapsed := time.Since(startTime); elapsed > time.Second {
+		t.Errorf("ctx deadline should bound the wait for a dial slot, elapsed:%s\n", elapsed)
+	}
+	release()
+	<-done
+	if pool.GetUsed() != 0 {
+		t.Errorf("used should be 0, got %d\n", pool.GetUsed())
+	}
+}
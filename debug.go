@@ -0,0 +1,56 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Put 的连接未被借出，如重复 Put 或归还从未借出的连接
+var ErrNotBorrowed = errors.New("thriftpool put of connection not borrowed")
+
+// 开启调试模式：同一个连接被重复借出或归还未借出的连接（如重复 Put）时 panic，用于在开发期发现连接被多个协程共用的问题。
+// 默认关闭，关闭时同样检测这些问题，但只记录警告日志：重复借出时照常借出，
+// 归还未借出的连接时 Put 返回 ErrNotBorrowed 而不改动连接池的状态，避免空闲队列和计数被破坏
+func WithDebug(debug bool) Option {
+	return func(t *ThriftPool) {
+		t.Debug = debug
+	}
+}
+
+// 借出时标记连接为使用中
+func (t *ThriftPool) markInUse(conn *ThriftConn) {
+	if atomic.CompareAndSwapInt32(&conn.inUse, 0, 1) {
+		conn.borrowedTime = t.now().UnixNano()
+		atomic.AddInt64(&conn.uses, 1)
+		return
+	}
+	if t.Debug {
+		panic(fmt.Sprintf("thriftpool %s: conn %p borrowed twice", t.GetEndpoint(), conn))
+	}
+	t.logf("WARNING: conn %p to %s borrowed twice", conn, conn.GetEndpoint())
+}
+
+// 归还时清除使用中标记，连接未被借出时返回 false
+func (t *ThriftPool) clearInUse(conn *ThriftConn) bool {
+	if atomic.CompareAndSwapInt32(&conn.inUse, 1, 0) {
+		return true
+	}
+	if t.Debug {
+		panic(fmt.Sprintf("thriftpool %s: put of conn %p not in use", t.GetEndpoint(), conn))
+	}
+	t.logf("WARNING: put of conn %p to %s not borrowed, Put called more times than Get", conn, conn.GetEndpoint())
+	return false
+}
+
+// 归还连接后扣减已用连接数，扣减为负说明 Put 多于 Get
+// Close 会把已用连接数清零，之后（包括 CloseGracefully 超时后）归还的连接不再扣减
+func (t *ThriftPool) subUsedOnPut() {
+	if t.ctx.Err() != nil {
+		return
+	}
+	if used := t.subUsed(); used < 0 {
+		t.logf("WARNING: used connections dropped to %d, Put called more times than Get", used)
+	}
+}
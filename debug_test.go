@@ -0,0 +1,113 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDebugDoublePut(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1, WithDebug(true))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("double Put should panic in debug mode\n")
+		}
+	}()
+	_ = pool.Put(conn)
+}
+
+func TestDebugDoubleBorrow(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1, WithDebug(true))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("borrowing a conn in use should panic in debug mode\n")
+		}
+	}()
+	// 模拟连接在借出期间又被放回队列（如被其它协程错误地归还）后再次借出
+	pool.markInUse(conn)
+}
+
+func TestPutAfterClose(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	logger := &testLogger{}
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithLogger(logger))
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Close()
+	if err := pool.Put(conn); err != nil {
+		t.Errorf("pool.Put after Close error:%s\n", err.Error())
+	}
+	if pool.GetUsed() != 0 {
+		t.Errorf("used should stay 0 after Close, got %d\n", pool.GetUsed())
+	}
+	if !conn.IsClose() {
+		t.Errorf("conn put after Close should be closed\n")
+	}
+	for _, line := range logger.lines {
+		if strings.Contains(line, "WARNING") {
+			t.Errorf("Put after Close should not log a warning, got %s\n", line)
+		}
+	}
+}
+
+func TestDoublePut(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	logger := &testLogger{}
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1, WithLogger(logger))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if err := pool.Put(conn); err != nil {
+		t.Fatalf("pool.Put error:%s\n", err.Error())
+	}
+	if err := pool.Put(conn); !errors.Is(err, ErrNotBorrowed) {
+		t.Errorf("double Put should return ErrNotBorrowed, got %v\n", err)
+	}
+	if err := pool.Put(&ThriftConn{Endpoint: endpoint, pool: pool}); !errors.Is(err, ErrNotBorrowed) {
+		t.Errorf("Put of a conn never borrowed should return ErrNotBorrowed, got %v\n", err)
+	}
+	if pool.GetIdle() != 1 || pool.GetUsed() != 0 || pool.GetOpen() != 1 {
+		t.Errorf("rejected Put should not change the pool, idle:%d used:%d open:%d\n",
+			pool.GetIdle(), pool.GetUsed(), pool.GetOpen())
+	}
+	if len(logger.lines) != 2 || !strings.Contains(logger.lines[0], "WARNING") {
+		t.Errorf("rejected Put should log a warning, got %v\n", logger.lines)
+	}
+
+	// 连接仍可正常借出和归还
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if err := pool.Put(conn); err != nil {
+		t.Errorf("Put after a rejected Put failed:%s\n", err)
+	}
+}
@@ -0,0 +1,128 @@
+// Package debugpage serves the internal state of thriftpools over http
+package debugpage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tianxingpan/thriftpool"
+)
+
+// 连接池内部状态的调试页面 http.Handler，相当于连接池的 /debug/pprof，如
+//   http.Handle("/debug/thriftpool", debugpage.Handler(pool))
+// 展示各连接池的计数、熔断状态、每个空闲连接的端点、年龄、借出次数，以及最近的事件（需 WithEventLog 开启）。
+// 未指定连接池时展示全局注册表中的连接池（见 thriftpool.WithRegister）。
+// 默认输出文本，?format=json 时输出 JSON。快照只短暂取出空闲连接，不影响连接池的正常使用
+func Handler(pools ...*thriftpool.ThriftPool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targets := pools
+		if len(targets) == 0 {
+			targets = thriftpool.RegisteredPools()
+		}
+		states := make([]poolState, 0, len(targets))
+		for _, pool := range targets {
+			states = append(states, newPoolState(pool.State()))
+		}
+		if r.URL.Query().Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(states)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, state := range states {
+			writeText(w, state)
+		}
+	})
+}
+
+// 事件的 JSON 形式，error 转为字符串
+type event struct {
+	Type		string		`json:"type"`
+	Time		time.Time	`json:"time"`
+	Endpoint	string		`json:"endpoint"`
+	Tag			string		`json:"tag,omitempty"`
+	Err			string		`json:"err,omitempty"`
+	Origin		string		`json:"origin,omitempty"`
+}
+
+// 空闲连接的 JSON 形式
+type conn struct {
+	Endpoint		string			`json:"endpoint"`
+	Origin			string			`json:"origin"`
+	CreatedTime		time.Time		`json:"created_time"`
+	UsedTime		time.Time		`json:"used_time"`
+	Age				time.Duration	`json:"age_ns"`
+	IdleTime		time.Duration	`json:"idle_ns"`
+	Uses			int64			`json:"uses"`
+	BytesRead		int64			`json:"bytes_read"`
+	BytesWritten	int64			`json:"bytes_written"`
+}
+
+// 连接池状态的 JSON 形式
+type poolState struct {
+	Stats			thriftpool.PoolStats	`json:"stats"`
+	IdleConns		[]conn					`json:"idle_conns"`
+	RecentEvents	[]event					`json:"recent_events"`
+}
+
+func newPoolState(state thriftpool.PoolState) poolState {
+	s := poolState{
+		Stats:        state.Stats,
+		IdleConns:    make([]conn, 0, len(state.IdleConns)),
+		RecentEvents: make([]event, 0, len(state.RecentEvents)),
+	}
+	for _, c := range state.IdleConns {
+		s.IdleConns = append(s.IdleConns, conn{
+			Endpoint:     c.Endpoint,
+			Origin:       c.Origin.String(),
+			CreatedTime:  c.CreatedTime,
+			UsedTime:     c.UsedTime,
+			Age:          c.Age,
+			IdleTime:     c.IdleTime,
+			Uses:         c.Uses,
+			BytesRead:    c.BytesRead,
+			BytesWritten: c.BytesWritten,
+		})
+	}
+	for _, e := range state.RecentEvents {
+		ev := event{Type: e.Type.String(), Time: e.Time, Endpoint: e.Endpoint, Tag: e.Tag}
+		if e.Err != nil {
+			ev.Err = e.Err.Error()
+		}
+		if e.Origin != thriftpool.OriginUnknown {
+			ev.Origin = e.Origin.String()
+		}
+		s.RecentEvents = append(s.RecentEvents, ev)
+	}
+	return s
+}
+
+func writeText(w io.Writer, state poolState) {
+	stats := state.Stats
+	fmt.Fprintf(w, "%s\n", stats.String())
+	fmt.Fprintf(w, "  dialing:%d, probes:%d, overflow:%d, breaker open:%v, dial failures:%d, throttled:%v\n",
+		stats.Dialing, stats.Probes, stats.Overflow, stats.BreakerOpen, stats.DialFailures, stats.Throttled)
+	fmt.Fprintf(w, "  dial p50/p99:%s/%s, hold p50/p99:%s/%s\n",
+		stats.DialLatencyP50, stats.DialLatencyP99, stats.HoldTimeP50, stats.HoldTimeP99)
+	fmt.Fprintf(w, "  idle conns (%d):\n", len(state.IdleConns))
+	for _, c := range state.IdleConns {
+		fmt.Fprintf(w, "    %s origin:%s age:%s idle:%s uses:%d created:%s\n",
+			c.Endpoint, c.Origin, c.Age.Round(time.Millisecond), c.IdleTime.Round(time.Millisecond),
+			c.Uses, c.CreatedTime.Format(time.RFC3339))
+	}
+	fmt.Fprintf(w, "  recent events (%d):\n", len(state.RecentEvents))
+	for _, e := range state.RecentEvents {
+		line := fmt.Sprintf("    %s %s %s", e.Time.Format("15:04:05.000"), e.Type, e.Endpoint)
+		if e.Tag != "" {
+			line += " tag:" + e.Tag
+		}
+		if e.Err != "" {
+			line += " err:" + e.Err
+		}
+		fmt.Fprintf(w, "%s\n", line)
+	}
+	fmt.Fprintf(w, "\n")
+}
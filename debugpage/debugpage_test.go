@@ -0,0 +1,84 @@
+// Package debugpage serves the internal state of thriftpools over http
+package debugpage
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tianxingpan/thriftpool"
+)
+
+func startServer(t *testing.T) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error:%s\n", err.Error())
+	}
+	go func() {
+		for {
+			if _, err := listener.Accept(); err != nil {
+				return
+			}
+		}
+	}()
+	return listener.Addr().String(), func() { _ = listener.Close() }
+}
+
+func get(handler http.Handler, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	return recorder
+}
+
+func TestHandler(t *testing.T) {
+	endpoint, stop := startServer(t)
+	defer stop()
+	pool := thriftpool.NewThriftPool(endpoint, 1000, 60000, 10, 0,
+		thriftpool.WithName("billing"), thriftpool.WithEventLog(10))
+	defer pool.Close()
+	conn, err := pool.GetTagged(context.Background(), "checkout")
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+	handler := Handler(pool)
+
+	text := get(handler, "/debug/thriftpool").Body.String()
+	for _, want := range []string{"thriftpool(billing)", "idle conns (1)", "origin:Dialed", "Borrowed", "tag:checkout"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text page should contain %q:\n%s\n", want, text)
+		}
+	}
+
+	var states []poolState
+	recorder := get(handler, "/debug/thriftpool?format=json")
+	if err := json.NewDecoder(recorder.Body).Decode(&states); err != nil {
+		t.Fatalf("decode state error:%s\n", err.Error())
+	}
+	if len(states) != 1 || len(states[0].IdleConns) != 1 || states[0].IdleConns[0].Uses != 1 {
+		t.Fatalf("unexpected json state:%+v\n", states)
+	}
+	if len(states[0].RecentEvents) != 3 || states[0].RecentEvents[1].Tag != "checkout" {
+		t.Errorf("unexpected recent events:%+v\n", states[0].RecentEvents)
+	}
+	if pool.GetIdle() != 1 {
+		t.Errorf("rendering should not take idle conns, idle:%d\n", pool.GetIdle())
+	}
+}
+
+func TestHandlerRegistered(t *testing.T) {
+	endpoint, stop := startServer(t)
+	defer stop()
+	pool := thriftpool.NewThriftPool(endpoint, 1000, 60000, 10, 0,
+		thriftpool.WithName("registered"), thriftpool.WithRegister())
+	defer pool.Close()
+
+	text := get(Handler(), "/debug/thriftpool").Body.String()
+	if !strings.Contains(text, "thriftpool(registered)") {
+		t.Errorf("handler without pools should show registered pools:\n%s\n", text)
+	}
+}
@@ -0,0 +1,100 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 限制 Get 同时拨号的数量，小于1时不限制（默认）
+// 空连接池突然涌入大量 Get 时，超出的 Get 不再各自拨号，而是等待拨号名额或其它协程归还的空闲连接，
+// 最多等待 DialTimeout（ctx 先结束时提前返回），以平滑服务端的建连压力
+func WithMaxConcurrentDials(maxDials int32) Option {
+	return func(t *ThriftPool) {
+		if maxDials < 1 {
+			t.MaxConcurrentDials = 0
+		} else {
+			t.MaxConcurrentDials = maxDials
+		}
+	}
+}
+
+// 拨号使用的本地地址，用于多网卡主机指定出口网卡，如 &net.TCPAddr{IP: net.ParseIP("10.0.0.5")}，
+// 端口一般为0由系统分配。本地地址不可用时拨号失败，与其它拨号失败一样计入连续拨号失败次数
+func WithLocalAddr(addr net.Addr) Option {
+	return func(t *ThriftPool) {
+		t.LocalAddr = addr
+	}
+}
+
+// socket 的内核接收缓冲大小（SO_RCVBUF），单位字节，小于1时使用系统默认值（默认）
+// 与 BufferSize（thrift 带缓冲的transport）无关，作用于内核 socket，适合大报文的场景。
+// 只是尽力而为：系统可能调整或限制该值（如 Linux 会翻倍并受 net.core.rmem_max 限制），设置失败时只记录日志
+func WithReadBufferSize(size int32) Option {
+	return func(t *ThriftPool) {
+		if size < 1 {
+			t.ReadBufferSize = 0
+		} else {
+			t.ReadBufferSize = size
+		}
+	}
+}
+
+// socket 的内核发送缓冲大小（SO_SNDBUF），单位字节，参见 WithReadBufferSize
+func WithWriteBufferSize(size int32) Option {
+	return func(t *ThriftPool) {
+		if size < 1 {
+			t.WriteBufferSize = 0
+		} else {
+			t.WriteBufferSize = size
+		}
+	}
+}
+
+// 建立 TCP 连接，测试时可替换以模拟拨号缓慢的端点
+var dialContext = func(dialer *net.Dialer, ctx context.Context, endpoint string) (net.Conn, error) {
+	return dialer.DialContext(ctx, "tcp", endpoint)
+}
+
+// 打开到端点的socket，设置了 LocalAddr 时从该地址拨号，拨号超时为 DialTimeout，ctx 结束时放弃拨号
+func (t *ThriftPool) openSocket(ctx context.Context, endpoint string) (*thrift.TSocket, error) {
+	dialer := net.Dialer{Timeout: t.DialTimeout, LocalAddr: t.LocalAddr}
+	netConn, err := dialContext(&dialer, ctx, endpoint)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Get 的 ctx 到期或被取消，不包装成传输层错误，connectTo 带上端点后调用方仍可用 errors.Is 判断
+			return nil, ctx.Err()
+		}
+		return nil, thrift.NewTTransportExceptionFromError(err)
+	}
+	return thrift.NewTSocketFromConnTimeout(netConn, t.DialTimeout), nil
+}
+
+// 为新打开的socket设置内核读写缓冲大小，失败时只记录日志，不影响拨号
+func (t *ThriftPool) setSocketBuffers(socket *thrift.TSocket) {
+	if t.ReadBufferSize < 1 && t.WriteBufferSize < 1 {
+		return
+	}
+	tcpConn, ok := socket.Conn().(*net.TCPConn)
+	if !ok {
+		return
+	}
+	if t.ReadBufferSize > 0 {
+		if err := tcpConn.SetReadBuffer(int(t.ReadBufferSize)); err != nil {
+			t.logf("set read buffer %d failed:%s", t.ReadBufferSize, err.Error())
+		}
+	}
+	if t.WriteBufferSize > 0 {
+		if err := tcpConn.SetWriteBuffer(int(t.WriteBufferSize)); err != nil {
+			t.logf("set write buffer %d failed:%s", t.WriteBufferSize, err.Error())
+		}
+	}
+}
+
+// 正在拨号的数量
+func (t *ThriftPool) GetDialing() int32 {
+	return atomic.LoadInt32(&t.dialing)
+}
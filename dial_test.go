@@ -0,0 +1,146 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+func TestMaxConcurrentDials(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	var inFlight, peak int32
+	wrapper := func(socket *thrift.TSocket) (thrift.TTransport, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return socket, nil
+	}
+	pool := NewThriftPool(endpoint, 2000, 60000, 50, 1,
+		WithTransportWrapper(wrapper), WithMaxConcurrentDials(2))
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := pool.Get(context.Background())
+			if err != nil {
+				t.Errorf("pool.Get error:%s\n", err.Error())
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+			_ = pool.Put(conn)
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("concurrent dials should not exceed 2, got %d\n", peak)
+	}
+	if pool.GetDialing() != 0 || pool.Stats().Dialing != 0 {
+		t.Errorf("dialing should be 0 after all Gets, got %d\n", pool.GetDialing())
+	}
+	if pool.GetUsed() != 0 {
+		t.Errorf("used should be 0, got %d\n", pool.GetUsed())
+	}
+}
+
+func TestLocalAddr(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	localAddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1, WithLocalAddr(localAddr))
+	defer pool.Close()
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	netConn, ok := conn.NetConn()
+	if !ok || !netConn.LocalAddr().(*net.TCPAddr).IP.Equal(localAddr.IP) {
+		t.Errorf("conn should be dialed from %s\n", localAddr)
+	}
+	_ = pool.Put(conn)
+
+	// 本地地址不可用时拨号失败，计入连续拨号失败次数
+	unavailable := &net.TCPAddr{IP: net.ParseIP("192.0.2.1")}
+	failPool := NewThriftPool(endpoint, 1000, 60000, 10, 1, WithLocalAddr(unavailable))
+	defer failPool.Close()
+	if _, err := failPool.Get(context.Background()); err == nil {
+		t.Errorf("dial from an unavailable local address should fail\n")
+	}
+	if failPool.GetDialFailures() != 1 {
+		t.Errorf("dial failure should be counted, got %d\n", failPool.GetDialFailures())
+	}
+}
+
+func TestSocketBuffers(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	logger := &testLogger{}
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1,
+		WithReadBufferSize(256*1024), WithWriteBufferSize(256*1024), WithLogger(logger))
+	defer pool.Close()
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	defer pool.Put(conn)
+	if _, ok := conn.NetConn(); !ok {
+		t.Errorf("conn should have a net.Conn\n")
+	}
+	if len(logger.lines) != 0 {
+		t.Errorf("setting socket buffers should not fail:%v\n", logger.lines)
+	}
+}
+
+func TestDialFailureReleasesUsed(t *testing.T) {
+	// 拿到一个无人监听的端口
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error:%s\n", err.Error())
+	}
+	unreachable := listener.Addr().String()
+	_ = listener.Close()
+
+	pool := NewThriftPool(unreachable, 100, 60000, 4, 0)
+	defer pool.Close()
+	// 失败次数超过 MaxSize，已用连接数泄漏时后续 Get 会返回 ErrPoolExhausted
+	for i := 0; i < 10; i++ {
+		if _, err := pool.Get(context.Background()); err == nil || err == ErrPoolExhausted {
+			t.Fatalf("Get %d should fail with a dial error, got %v\n", i, err)
+		}
+	}
+	if pool.GetUsed() != 0 || pool.GetOpen() != 0 {
+		t.Errorf("failed dials should release used and open, used:%d open:%d\n", pool.GetUsed(), pool.GetOpen())
+	}
+
+	// 后端恢复后连接池仍可正常拨号
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool.Rebind(endpoint)
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get after recovery error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+	if pool.GetUsed() != 0 {
+		t.Errorf("used should be 0, got %d\n", pool.GetUsed())
+	}
+}
@@ -0,0 +1,68 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 打开socket失败时重试 retries 次（小于1时不重试，默认），首次重试前等待 backoff 毫秒，之后每次翻倍。
+// 每次尝试各自受 DialTimeout 限制；包括重试和退避等待在内的总时长受 ctx 和 MaxDialElapsed（见 WithMaxDialElapsed）限制，
+// 总时长用尽时立即停止，即使正在退避等待或拨号中，返回最后一次尝试的错误。
+// 只重试打开socket，TransportWrapper 和 OnConnReady 失败不重试；整次拨号失败只计一次连续拨号失败
+func WithDialRetry(retries, backoff int32) Option {
+	return func(t *ThriftPool) {
+		if retries < 1 {
+			t.DialRetries = 0
+		} else {
+			t.DialRetries = retries
+		}
+		if backoff < 1 {
+			t.DialRetryBackoff = 0
+		} else {
+			t.DialRetryBackoff = time.Duration(backoff) * time.Millisecond
+		}
+	}
+}
+
+// 一次拨号（包括重试和退避等待）的总时长上限，单位毫秒，小于1时只受 ctx 限制（默认），参见 WithDialRetry
+func WithMaxDialElapsed(maxElapsed int32) Option {
+	return func(t *ThriftPool) {
+		if maxElapsed < 1 {
+			t.MaxDialElapsed = 0
+		} else {
+			t.MaxDialElapsed = time.Duration(maxElapsed) * time.Millisecond
+		}
+	}
+}
+
+// 打开socket，失败时按 DialRetries 重试
+func (t *ThriftPool) dialSocket(ctx context.Context, endpoint string) (*thrift.TSocket, error) {
+	if t.MaxDialElapsed > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.MaxDialElapsed)
+		defer cancel()
+	}
+	backoff := t.DialRetryBackoff
+	socket, err := t.openSocket(ctx, endpoint)
+	for i := int32(0); err != nil && i < t.DialRetries; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		t.logf("dial %s failed:%s, retry %d/%d", endpoint, err.Error(), i+1, t.DialRetries)
+		if backoff > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, err
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+		socket, err = t.openSocket(ctx, endpoint)
+	}
+	return socket, err
+}
@@ -0,0 +1,107 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 已关闭的端口，拨号立即被拒绝
+func refusedEndpoint(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error:%s\n", err.Error())
+	}
+	endpoint := listener.Addr().String()
+	_ = listener.Close()
+	return endpoint
+}
+
+func TestDialRetry(t *testing.T) {
+	endpoint := refusedEndpoint(t)
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithDialRetry(2, 10))
+	defer pool.Close()
+
+	var attempts int32
+	saved := dialContext
+	defer func() { dialContext = saved }()
+	dialContext = func(dialer *net.Dialer, ctx context.Context, endpoint string) (net.Conn, error) {
+		atomic.AddInt32(&attempts, 1)
+		return saved(dialer, ctx, endpoint)
+	}
+
+	startTime := time.Now()
+	if _, err := pool.Get(context.Background()); err == nil {
+		t.Fatalf("get from refused endpoint should fail\n")
+	}
+	// 1次拨号 + 2次重试，退避 10ms + 20ms
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Errorf("dial should be attempted 3 times, got %d\n", n)
+	}
+	if elapsed := time.Since(startTime); elapsed < 30*time.Millisecond {
+		t.Errorf("retries should back off, elapsed:%s\n", elapsed)
+	}
+	if pool.GetDialFailures() != 1 {
+		t.Errorf("a retried dial should count as one failure, got %d\n", pool.GetDialFailures())
+	}
+}
+
+func TestDialRetryBudget(t *testing.T) {
+	endpoint := refusedEndpoint(t)
+
+	// 总时长在退避等待中用尽时立即停止
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithDialRetry(10, 200), WithMaxDialElapsed(50))
+	defer pool.Close()
+	startTime := time.Now()
+	if _, err := pool.Get(context.Background()); err == nil {
+		t.Fatalf("get from refused endpoint should fail\n")
+	}
+	if elapsed := time.Since(startTime); elapsed > 150*time.Millisecond {
+		t.Errorf("MaxDialElapsed should stop the retries mid-backoff, elapsed:%s\n", elapsed)
+	}
+
+	// ctx 同样限制总时长
+	ctxPool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithDialRetry(10, 200))
+	defer ctxPool.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	startTime = time.Now()
+	if _, err := ctxPool.Get(ctx); err == nil {
+		t.Fatalf("get from refused endpoint should fail\n")
+	}
+	if elapsed := time.Since(startTime); elapsed > 150*time.Millisecond {
+		t.Errorf("ctx should stop the retries mid-backoff, elapsed:%s\n", elapsed)
+	}
+}
+
+func TestDialRetryPerAttemptTimeout(t *testing.T) {
+	// 模拟不响应的端点：拨号一直阻塞到单次超时或 ctx 结束
+	var attempts int32
+	saved := dialContext
+	defer func() { dialContext = saved }()
+	dialContext = func(dialer *net.Dialer, ctx context.Context, endpoint string) (net.Conn, error) {
+		atomic.AddInt32(&attempts, 1)
+		ctx, cancel := context.WithTimeout(ctx, dialer.Timeout)
+		defer cancel()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	pool := NewThriftPool("127.0.0.1:1", 30, 60000, 10, 0, WithDialRetry(10, 0), WithMaxDialElapsed(100))
+	defer pool.Close()
+	startTime := time.Now()
+	if _, err := pool.Get(context.Background()); err == nil {
+		t.Fatalf("get from unresponsive endpoint should fail\n")
+	}
+	elapsed := time.Since(startTime)
+	// 每次尝试 30ms，100ms 内约 4 次，不会用满 11 次
+	if n := atomic.LoadInt32(&attempts); n < 2 || n > 5 {
+		t.Errorf("each attempt should be bounded by DialTimeout, attempts:%d\n", n)
+	}
+	if elapsed < 90*time.Millisecond || elapsed > 200*time.Millisecond {
+		t.Errorf("total dial time should be bounded by MaxDialElapsed, elapsed:%s\n", elapsed)
+	}
+}
@@ -0,0 +1,28 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+)
+
+// 从连接池取一个连接执行 fn，执行完后归还
+// fn 返回的错误表明连接已不能复用时（见 IsConnectionError）关闭连接而不放回池，
+// 服务端返回的应用异常等其它错误不影响连接，连接照常放回池。
+// fn 经 ClientFactory 或 GuardedTransport 调用时，Write 或 Flush 失败的连接无论 fn 返回什么都会被关闭，不会被放回池
+func (t *ThriftPool) Do(fn func(conn *ThriftConn) error) error {
+	return t.doCtx(context.Background(), fn)
+}
+
+// 同 Do，取连接（包括等待和拨号）受 ctx 的截止时间和取消控制
+func (t *ThriftPool) doCtx(ctx context.Context, fn func(conn *ThriftConn) error) error {
+	conn, err := t.Get(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn(conn)
+	if IsConnectionError(err) {
+		_ = conn.CloseTransport()
+	}
+	_ = t.Put(conn)
+	return err
+}
@@ -0,0 +1,70 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// 端点格式错误，由 NormalizeEndpoint 和 NewThriftPoolChecked 返回
+var ErrBadEndpoint = errors.New("thriftpool bad endpoint")
+
+// 校验并规范化 host:port 格式的端点，返回可直接拨号的端点：
+// IPv4 如 127.0.0.1:9898，主机名如 localhost:9898，IPv6 须加方括号如 [::1]:9898，
+// 链路本地地址可带 zone 如 [fe80::1%eth0]:9898。端口可以是数字或服务名（如 http），统一转为数字。
+// 缺少端口、IPv6 未加方括号、端口越界等情况返回包装了 ErrBadEndpoint 的错误
+func NormalizeEndpoint(endpoint string) (string, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		if net.ParseIP(endpoint) != nil || net.ParseIP(strings.Trim(endpoint, "[]")) != nil {
+			return "", fmt.Errorf("%w %q: missing port", ErrBadEndpoint, endpoint)
+		}
+		if strings.Count(endpoint, ":") > 1 && !strings.HasPrefix(endpoint, "[") {
+			return "", fmt.Errorf("%w %q: IPv6 address must be enclosed in brackets, e.g. [::1]:9898", ErrBadEndpoint, endpoint)
+		}
+		return "", fmt.Errorf("%w %q: %s", ErrBadEndpoint, endpoint, err.Error())
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		if portNum, err = net.LookupPort("tcp", port); err != nil {
+			return "", fmt.Errorf("%w %q: unknown port %q", ErrBadEndpoint, endpoint, port)
+		}
+	}
+	if portNum < 1 || portNum > 65535 {
+		return "", fmt.Errorf("%w %q: port %d out of range", ErrBadEndpoint, endpoint, portNum)
+	}
+	if err := checkHost(host); err != nil {
+		return "", fmt.Errorf("%w %q: %s", ErrBadEndpoint, endpoint, err.Error())
+	}
+	return net.JoinHostPort(host, strconv.Itoa(portNum)), nil
+}
+
+// 校验端点的主机部分，可以是 IP（IPv6 可带 zone）或主机名
+func checkHost(host string) error {
+	if host == "" {
+		return errors.New("missing host")
+	}
+	ip := host
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		if i == len(host)-1 {
+			return errors.New("empty IPv6 zone")
+		}
+		ip = host[:i]
+		if parsed := net.ParseIP(ip); parsed == nil || parsed.To4() != nil {
+			return fmt.Errorf("zone is only allowed on IPv6 addresses, got %q", host)
+		}
+		return nil
+	}
+	if net.ParseIP(ip) != nil {
+		return nil
+	}
+	for _, c := range host {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.' || c == '_') {
+			return fmt.Errorf("invalid character %q in host", c)
+		}
+	}
+	return nil
+}
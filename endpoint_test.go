@@ -0,0 +1,77 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestNormalizeEndpoint(t *testing.T) {
+	cases := []struct {
+		endpoint	string
+		want		string
+	}{
+		{"127.0.0.1:9898", "127.0.0.1:9898"},
+		{"localhost:9898", "localhost:9898"},
+		{"thrift-server.example.com:09898", "thrift-server.example.com:9898"},
+		{"[::1]:9898", "[::1]:9898"},
+		{"[2001:db8::1]:9898", "[2001:db8::1]:9898"},
+		{"[fe80::1%eth0]:9898", "[fe80::1%eth0]:9898"},
+	}
+	for _, c := range cases {
+		got, err := NormalizeEndpoint(c.endpoint)
+		if err != nil {
+			t.Errorf("NormalizeEndpoint(%q) error:%s\n", c.endpoint, err.Error())
+		} else if got != c.want {
+			t.Errorf("NormalizeEndpoint(%q) = %q, want %q\n", c.endpoint, got, c.want)
+		}
+	}
+
+	bad := []string{
+		"",
+		"127.0.0.1",
+		"localhost",
+		"::1",
+		"[::1]",
+		"::1:9898",
+		"2001:db8::1:9898",
+		"127.0.0.1:0",
+		"127.0.0.1:70000",
+		"127.0.0.1:no-such-port",
+		":9898",
+		"[fe80::1%]:9898",
+		"[127.0.0.1%eth0]:9898",
+		"bad host:9898",
+	}
+	for _, endpoint := range bad {
+		if got, err := NormalizeEndpoint(endpoint); !errors.Is(err, ErrBadEndpoint) {
+			t.Errorf("NormalizeEndpoint(%q) = %q, %v, want ErrBadEndpoint\n", endpoint, got, err)
+		}
+	}
+}
+
+func TestCheckedBadEndpoint(t *testing.T) {
+	pool, err := NewThriftPoolChecked("::1:9898", 100, 60000, 10, 1)
+	if pool != nil || !errors.Is(err, ErrBadEndpoint) {
+		t.Errorf("malformed endpoint should be rejected at construction, got %v\n", err)
+	}
+}
+
+func TestDialIPv6(t *testing.T) {
+	if listener, err := net.Listen("tcp", "[::1]:0"); err != nil {
+		t.Skipf("IPv6 loopback unavailable:%s\n", err.Error())
+	} else {
+		_ = listener.Close()
+	}
+	endpoint, stop := startTestServer(t, "[::1]:0")
+	defer stop()
+	pool, err := NewThriftPoolChecked(endpoint, 1000, 60000, 10, 1)
+	if err != nil {
+		t.Fatalf("NewThriftPoolChecked error:%s\n", err.Error())
+	}
+	defer pool.Close()
+	if pool.GetIdle() != 1 {
+		t.Errorf("IPv6 endpoint should be dialed, idle:%d\n", pool.GetIdle())
+	}
+}
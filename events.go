@@ -0,0 +1,106 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// 连接池事件类型
+type EventType int32
+
+const (
+	EventDialed		EventType = iota	// 拨号成功
+	EventDialFailed						// 拨号失败
+	EventBorrowed						// 连接被借出
+	EventReturned						// 连接被归还
+	EventEvicted						// 连接被连接池关闭，如闲置超时、超过最大连接数或借出超时
+	EventClosed							// 连接池被关闭
+	EventAdopted						// 接管了外部的连接，见 Adopt
+)
+
+func (e EventType) String() string {
+	switch e {
+	case EventDialed:
+		return "Dialed"
+	case EventDialFailed:
+		return "DialFailed"
+	case EventBorrowed:
+		return "Borrowed"
+	case EventReturned:
+		return "Returned"
+	case EventEvicted:
+		return "Evicted"
+	case EventClosed:
+		return "Closed"
+	case EventAdopted:
+		return "Adopted"
+	default:
+		return "Unknown"
+	}
+}
+
+// 连接池事件
+type PoolEvent struct {
+	Type		EventType	// 事件类型
+	Time		time.Time	// 事件发生的时间
+	Endpoint	string		// 连接池的端点
+	Tag			string		// 借出方的业务操作名，仅借出、归还和借出超时回收事件带有，见 GetTagged
+	Err			error		// 拨号失败的原因，其它事件为nil
+	Origin		ConnOrigin	// 连接的来源，仅拨号成功和接管事件带有
+}
+
+// 开启事件通知，size 为事件channel的缓冲大小，小于1时不开启（默认）
+// 事件以非阻塞方式发送：缓冲已满时直接丢弃该事件并计入 GetDroppedEvents，
+// 因此消费过慢只会丢事件，不会阻塞连接池
+func WithEvents(size int32) Option {
+	return func(t *ThriftPool) {
+		if size > 0 {
+			t.events = make(chan PoolEvent, size)
+		} else {
+			t.events = nil
+		}
+	}
+}
+
+// 返回事件channel，未开启事件通知时返回nil
+// 连接池关闭时发送 EventClosed 事件，但不会关闭该channel
+func (t *ThriftPool) Events() <-chan PoolEvent {
+	return t.events
+}
+
+// 因事件channel已满而被丢弃的事件数
+func (t *ThriftPool) GetDroppedEvents() int64 {
+	return atomic.LoadInt64(&t.droppedEvents)
+}
+
+func (t *ThriftPool) emit(eventType EventType, err error) {
+	t.emitTagged(eventType, "", err)
+}
+
+func (t *ThriftPool) emitTagged(eventType EventType, tag string, err error) {
+	t.emitEvent(PoolEvent{Type: eventType, Tag: tag, Err: err})
+}
+
+// 补全事件的时间和端点后发送
+func (t *ThriftPool) emitEvent(event PoolEvent) {
+	if t.statsd != nil {
+		t.countStatsd(event.Type)
+	}
+	if t.events == nil && t.eventLog == nil {
+		return
+	}
+	event.Time = time.Now()
+	event.Endpoint = t.GetEndpoint()
+	if t.eventLog != nil {
+		t.eventLog.add(event)
+	}
+	if t.events == nil {
+		return
+	}
+	select {
+	case t.events <- event:
+	default:
+		atomic.AddInt64(&t.droppedEvents, 1)
+	}
+}
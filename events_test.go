@@ -0,0 +1,83 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1, WithEvents(4))
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+	pool.Close()
+
+	want := []EventType{EventDialed, EventBorrowed, EventReturned}
+	for _, eventType := range want {
+		event := <-pool.Events()
+		if event.Type != eventType || event.Endpoint != endpoint || event.Time.IsZero() {
+			t.Errorf("got event %s, want %s\n", event.Type, eventType)
+		}
+	}
+	event := <-pool.Events()
+	if event.Type != EventClosed {
+		t.Errorf("got event %s, want %s\n", event.Type, EventClosed)
+	}
+	if pool.GetDroppedEvents() != 0 {
+		t.Errorf("dropped %d events, want 0\n", pool.GetDroppedEvents())
+	}
+
+	// 缓冲只有1个，Borrowed 和 Returned 应被丢弃
+	dropPool := NewThriftPool(endpoint, 100, 60000, 10, 1, WithEvents(1))
+	defer dropPool.Close()
+	conn, err = dropPool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = dropPool.Put(conn)
+	if dropPool.GetDroppedEvents() != 2 {
+		t.Errorf("dropped %d events, want 2\n", dropPool.GetDroppedEvents())
+	}
+	if event := <-dropPool.Events(); event.Type != EventDialed {
+		t.Errorf("got event %s, want %s\n", event.Type, EventDialed)
+	}
+}
+
+func TestGetTagged(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1, WithEvents(16), WithMaxBorrowTime(20, nil))
+	defer pool.Close()
+
+	conn, err := pool.GetTagged(context.Background(), "checkout")
+	if err != nil {
+		t.Fatalf("pool.GetTagged error:%s\n", err.Error())
+	}
+	if conn.GetTag() != "checkout" {
+		t.Errorf("conn should carry the tag, got %q\n", conn.GetTag())
+	}
+
+	// 不归还，等待借出超时回收
+	deadline := time.After(time.Second)
+	tags := make(map[EventType]string)
+	for len(tags) < 2 {
+		select {
+		case event := <-pool.Events():
+			if event.Type == EventBorrowed || event.Type == EventEvicted {
+				tags[event.Type] = event.Tag
+			}
+		case <-deadline:
+			t.Fatalf("missing events, got %v\n", tags)
+		}
+	}
+	if tags[EventBorrowed] != "checkout" || tags[EventEvicted] != "checkout" {
+		t.Errorf("events should carry the tag, got %v\n", tags)
+	}
+}
@@ -0,0 +1,28 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync/atomic"
+)
+
+// 连接被连接池关闭的原因，连接池关闭时关闭的连接不计入
+type evictReason int
+
+const (
+	evictIdle		evictReason = iota	// 闲置超时，包括 Shrink
+	evictOverMax						// 空闲连接数已达上限（MaxIdle 或队列容量），或超出 ThrottleTo 的限流值
+	evictBroken							// 连接不可用（调用方 CloseTransport）、心跳或校验失败
+	evictStale							// Rebind 或 SetTargets 之后属于旧端点的连接，以及 RefreshEndpoint 替换的连接
+	evictReclaimed						// 借出超时被看门狗回收
+	evictLifetime						// 超过最长存活时间 MaxConnLifetime
+	evictBytes							// 累计读写超过 MaxConnBytes
+	evictReasons
+)
+
+func (t *ThriftPool) countEvicted(reason evictReason) {
+	atomic.AddInt64(&t.evicted[reason], 1)
+}
+
+func (t *ThriftPool) getEvicted(reason evictReason) int64 {
+	return atomic.LoadInt64(&t.evicted[reason])
+}
@@ -0,0 +1,106 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sort"
+)
+
+// 按容量回收（Shrink、TrimPolicy 和回收协程的闲置超时回收）空闲连接时，先关闭哪些连接
+type EvictionOrder int32
+
+const (
+	EvictLRU	EvictionOrder = iota	// 先关闭最久未使用的空闲连接（默认）
+	EvictOldest							// 先关闭最早建立的空闲连接，蓝绿发布时让连接尽快轮换到新的后端
+)
+
+func (o EvictionOrder) String() string {
+	switch o {
+	case EvictLRU:
+		return "LRU"
+	case EvictOldest:
+		return "Oldest"
+	default:
+		return "Unknown"
+	}
+}
+
+// 按容量回收空闲连接时的顺序，参见 EvictionOrder。
+// 回收协程每轮回收的连接数为闲置超过 IdleTimeout 的连接数（至少保留 InitSize 个），关闭哪些连接同样按此顺序选取；
+// EvictOldest 每次回收都要按建立时间给空闲连接排序，开销和空闲连接数成正比；
+// 与 MaxConnLifetime 互补：后者只在连接归还时关闭超龄连接，前者在缩容时优先关闭旧连接
+func WithEvictionOrder(order EvictionOrder) Option {
+	return func(t *ThriftPool) {
+		t.EvictionOrder = order
+	}
+}
+
+func (t *ThriftPool) GetEvictionOrder() EvictionOrder {
+	return t.EvictionOrder
+}
+
+// 按 EvictionOrder 关闭至多 n 个空闲连接，返回关闭的连接数
+func (t *ThriftPool) evictIdleConns(n int32) int32 {
+	if n < 1 {
+		return 0
+	}
+	if t.EvictionOrder == EvictOldest {
+		return t.evictOldest(n)
+	}
+	var closed int32
+	for closed < n {
+		// 队首是最久未使用的连接
+		conn := t.idleConns.popFront()
+		if conn == nil {
+			return closed
+		}
+		t.evictIdleConn(conn)
+		closed++
+	}
+	return closed
+}
+
+// 关闭至多 n 个最早建立的空闲连接，其余连接保持原顺序
+func (t *ThriftPool) evictOldest(n int32) int32 {
+	var byAge []*ThriftConn
+	t.idleConns.each(func(conn *ThriftConn) {
+		byAge = append(byAge, conn)
+	})
+	sort.SliceStable(byAge, func(i, j int) bool {
+		return byAge[i].createdTime.Before(byAge[j].createdTime)
+	})
+	if int(n) < len(byAge) {
+		byAge = byAge[:n]
+	}
+	selected := make(map[*ThriftConn]bool, len(byAge))
+	for _, conn := range byAge {
+		selected[conn] = true
+	}
+	// 排序后到取出前可能有连接被借走，只关闭仍在队列中的
+	evicted := t.idleConns.removeIf(func(conn *ThriftConn) bool {
+		return selected[conn]
+	})
+	for _, conn := range evicted {
+		t.evictIdleConn(conn)
+	}
+	return int32(len(evicted))
+}
+
+// 闲置超过 IdleTimeout 的空闲连接数
+func (t *ThriftPool) countIdleExpired() int32 {
+	var n int32
+	nowTime := t.now()
+	t.rangeIdle(func(conn *ThriftConn) {
+		if nowTime.Sub(conn.usedTime) > t.IdleTimeout {
+			n++
+		}
+	})
+	return n
+}
+
+// 关闭一个已从队列取出的空闲连接
+func (t *ThriftPool) evictIdleConn(conn *ThriftConn) {
+	t.subIdle()
+	t.discard(conn)
+	t.countEvicted(evictIdle)
+	t.emit(EventEvicted, nil)
+}
@@ -0,0 +1,112 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 依次拨号三个连接（建立时间递增），再按相反顺序归还，使最早建立的连接成为最近使用的
+func newAgedConns(t *testing.T, order EvictionOrder, opts ...Option) (*ThriftPool, []*ThriftConn, *fakeClock, func()) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	clock := newFakeClock()
+	opts = append([]Option{withClock(clock.Now), WithEvictionOrder(order)}, opts...)
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1, opts...)
+
+	conns := make([]*ThriftConn, 3)
+	for i := range conns {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns[i] = conn
+		clock.Advance(time.Second)
+	}
+	for i := len(conns) - 1; i >= 0; i-- {
+		_ = pool.Put(conns[i])
+	}
+	return pool, conns, clock, func() {
+		pool.Close()
+		stop()
+	}
+}
+
+func TestEvictionOrder(t *testing.T) {
+	// LRU 先关闭最早归还的 conns[2]，Oldest 先关闭最早建立的 conns[0]
+	for _, tc := range []struct {
+		order	EvictionOrder
+		kept	int
+	}{
+		{EvictLRU, 0},
+		{EvictOldest, 2},
+	} {
+		pool, conns, _, cleanup := newAgedConns(t, tc.order)
+		if pool.GetEvictionOrder() != tc.order {
+			t.Errorf("unexpected eviction order:%s\n", pool.GetEvictionOrder())
+		}
+		if closed := pool.Shrink(); closed != 2 {
+			t.Errorf("%s: shrink should close 2 conns, closed:%d\n", tc.order, closed)
+		}
+		for i, conn := range conns {
+			if conn.IsClose() != (i != tc.kept) {
+				t.Errorf("%s: conn %d closed:%v\n", tc.order, i, conn.IsClose())
+			}
+		}
+		if pool.GetIdle() != 1 || pool.GetOpen() != 1 || pool.Stats().EvictedIdle != 2 {
+			t.Errorf("%s: idle:%d, open:%d\n", tc.order, pool.GetIdle(), pool.GetOpen())
+		}
+		cleanup()
+	}
+}
+
+func TestEvictionOrderTrimPolicy(t *testing.T) {
+	var target int32
+	pool, conns, _, cleanup := newAgedConns(t, EvictOldest, WithTrimPolicy(func(stats PoolStats) int32 {
+		return atomic.LoadInt32(&target)
+	}))
+	defer cleanup()
+	atomic.StoreInt32(&target, 1)
+	closed := pool.trimIdle()
+	atomic.StoreInt32(&target, 0)
+	if closed != 1 {
+		t.Errorf("trim should close 1 conn, closed:%d\n", closed)
+	}
+	if !conns[0].IsClose() || conns[1].IsClose() || conns[2].IsClose() {
+		t.Errorf("trim should close the oldest conn first\n")
+	}
+	// 其余连接按原顺序放回，conns[1] 仍在 conns[2] 之后
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if conn != conns[2] {
+		t.Errorf("remaining idle conns should keep their queue order\n")
+	}
+	_ = pool.Put(conn)
+}
+
+func TestEvictionOrderReaper(t *testing.T) {
+	for _, tc := range []struct {
+		order	EvictionOrder
+		kept	int
+	}{
+		{EvictLRU, 0},
+		{EvictOldest, 2},
+	} {
+		pool, conns, clock, cleanup := newAgedConns(t, tc.order)
+		// 三个连接都闲置超时，回收协程保留 InitSize 个，按 EvictionOrder 选取关闭的连接
+		clock.Advance(time.Hour)
+		pool.releaseIdle()
+		for i, conn := range conns {
+			if conn.IsClose() != (i != tc.kept) {
+				t.Errorf("%s: conn %d closed:%v\n", tc.order, i, conn.IsClose())
+			}
+		}
+		if pool.GetIdle() != 1 || pool.GetOpen() != 1 || pool.Stats().EvictedIdle != 2 {
+			t.Errorf("%s: idle:%d, open:%d, evicted:%d\n", tc.order, pool.GetIdle(), pool.GetOpen(), pool.Stats().EvictedIdle)
+		}
+		cleanup()
+	}
+}
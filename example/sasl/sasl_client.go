@@ -0,0 +1,165 @@
+// package main provides thriftpool test cases
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/tianxingpan/thriftpool"
+	"github.com/tianxingpan/thriftpool/example/echo"
+	"io"
+	"os"
+)
+
+var (
+	help = flag.Bool("h", false, "Display a help message and exit")
+	server = flag.String("server", "127.0.0.1:9898", "Server of SASL secured Thrift to connect.")
+	user = flag.String("user", "", "SASL PLAIN user name.")
+	password = flag.String("password", "", "SASL PLAIN password.")
+)
+
+// SASL协商消息的状态
+const (
+	saslStart		= 1
+	saslOk			= 2
+	saslBad			= 3
+	saslError		= 4
+	saslComplete	= 5
+)
+
+// 完成SASL PLAIN握手后的transport，
+// 握手后的每个消息都以4字节长度开头
+type saslPlainTransport struct {
+	socket		*thrift.TSocket
+	writeBuf	bytes.Buffer
+	readBuf		bytes.Buffer
+}
+
+// 返回完成SASL PLAIN握手的socket包装函数
+func saslPlain(user, password string) func(socket *thrift.TSocket) (thrift.TTransport, error) {
+	return func(socket *thrift.TSocket) (thrift.TTransport, error) {
+		if err := writeSaslMessage(socket, saslStart, []byte("PLAIN")); err != nil {
+			return nil, err
+		}
+		response := []byte("\x00" + user + "\x00" + password)
+		if err := writeSaslMessage(socket, saslComplete, response); err != nil {
+			return nil, err
+		}
+		status, payload, err := readSaslMessage(socket)
+		if err != nil {
+			return nil, err
+		}
+		if status != saslComplete {
+			return nil, errors.New(fmt.Sprintf("sasl negotiation failed, status:%d, message:%s", status, payload))
+		}
+		return &saslPlainTransport{socket: socket}, nil
+	}
+}
+
+func writeSaslMessage(socket *thrift.TSocket, status byte, payload []byte) error {
+	header := make([]byte, 5)
+	header[0] = status
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := socket.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return socket.Flush()
+}
+
+func readSaslMessage(socket *thrift.TSocket) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(socket, header); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	if _, err := io.ReadFull(socket, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+func (s *saslPlainTransport) Open() error {
+	return s.socket.Open()
+}
+
+func (s *saslPlainTransport) IsOpen() bool {
+	return s.socket.IsOpen()
+}
+
+func (s *saslPlainTransport) Close() error {
+	return s.socket.Close()
+}
+
+func (s *saslPlainTransport) Read(buf []byte) (int, error) {
+	if s.readBuf.Len() == 0 {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(s.socket, header); err != nil {
+			return 0, err
+		}
+		frame := make([]byte, binary.BigEndian.Uint32(header))
+		if _, err := io.ReadFull(s.socket, frame); err != nil {
+			return 0, err
+		}
+		s.readBuf.Write(frame)
+	}
+	return s.readBuf.Read(buf)
+}
+
+func (s *saslPlainTransport) Write(buf []byte) (int, error) {
+	return s.writeBuf.Write(buf)
+}
+
+func (s *saslPlainTransport) Flush() error {
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(s.writeBuf.Len()))
+	if _, err := s.socket.Write(append(header, s.writeBuf.Bytes()...)); err != nil {
+		s.writeBuf.Reset()
+		return err
+	}
+	s.writeBuf.Reset()
+	return s.socket.Flush()
+}
+
+func (s *saslPlainTransport) RemainingBytes() uint64 {
+	return uint64(s.readBuf.Len())
+}
+
+func main() {
+	flag.Parse()
+	if *help {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *server == "" || *user == "" {
+		fmt.Printf("Parameter[-server] or [-user] is not set.\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	thriftPool := thriftpool.NewThriftPool(*server, 5000, 5000, 10, 1)
+	defer thriftPool.Close()
+	thriftPool.SetTransportWrapper(saslPlain(*user, *password))
+
+	thriftConn, err := thriftPool.Get(context.Background())
+	if err != nil {
+		fmt.Printf("Get a Thrift connection from pool failed: %s\n", err.Error())
+		os.Exit(1)
+	}
+	protoF := thrift.NewTBinaryProtocolFactoryDefault()
+	client := echo.NewEchoClientFactory(thriftConn.GuardedTransport(), protoF)
+
+	req := echo.EchoReq{Msg:"Hello"}
+	res, err := client.Echo(&req)
+	if err != nil {
+		fmt.Printf("[ECHO]%s\n", err.Error())
+		_ = thriftConn.Close()
+		_ = thriftPool.Put(thriftConn)
+		os.Exit(1)
+	}
+	_ = thriftPool.Put(thriftConn)
+	fmt.Printf("[ECHO]%s\n", res.GetMsg())
+}
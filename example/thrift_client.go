@@ -2,9 +2,9 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"git.apache.org/thrift.git/lib/go/thrift"
 	"github.com/tianxingpan/thriftpool"
 	"github.com/tianxingpan/thriftpool/example/echo"
 	"os"
@@ -50,7 +50,7 @@ func main() {
 	fmt.Printf("PID is %d\n", os.Getpid())
 	thriftPool = thriftpool.NewThriftPool(*server,
 		int32(*dialTimeout), int32(*idleTimeout),
-		int32(*maxSize), int32(*initSize))
+		int32(*maxSize), int32(*initSize), thriftpool.WithFramedTransport(0))
 
 	stopChan = make(chan bool)
 	// 启动metric协程
@@ -114,17 +114,14 @@ func consumer(index int) {
 }
 
 func request(index int) {
-	thriftConn, err := thriftPool.Get()
+	thriftConn, err := thriftPool.Get(context.Background())
 	if err != nil {
 		atomic.AddInt32(&numPoolFailedRequests, 1)
 		fmt.Printf("Get a Thrift connection from pool failed: %s\n", err.Error())
 		return
 	}
-	socket := thriftConn.GetSocket()
-	transF := thrift.NewTFramedTransportFactory(thrift.NewTTransportFactory())
-	protoF := thrift.NewTBinaryProtocolFactoryDefault()
-	useTrans := transF.GetTransport(socket)
-	client := echo.NewEchoClientFactory(useTrans, protoF)
+	in, out := thriftConn.Protocols()
+	client := echo.NewEchoClientProtocol(thriftConn.GuardedTransport(), in, out)
 
 	req := echo.EchoReq{Msg:"Hello"}
 	_, err = client.Echo(&req)
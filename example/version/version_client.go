@@ -0,0 +1,65 @@
+// package main provides thriftpool test cases
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/tianxingpan/thriftpool"
+	"github.com/tianxingpan/thriftpool/example/echo"
+	"os"
+)
+
+var (
+	help = flag.Bool("h", false, "Display a help message and exit")
+	server = flag.String("server", "127.0.0.1:9898", "Server of Thrift to connect.")
+	version = flag.String("version", "1", "Protocol version announced after connect.")
+)
+
+// 返回在新连接上交换版本号的协商函数：发送 "version:<v>"，服务端应答 "success" 表示接受
+func versionExchange(v string) func(ctx context.Context, conn *thriftpool.ThriftConn) error {
+	return func(ctx context.Context, conn *thriftpool.ThriftConn) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		client := thriftpool.ClientFor(conn, echo.NewEchoClientFactory)
+		res, err := client.Echo(&echo.EchoReq{Msg: "version:" + v})
+		if err != nil {
+			return err
+		}
+		if res.GetMsg() != "success" {
+			return errors.New(fmt.Sprintf("version %s rejected: %s", v, res.GetMsg()))
+		}
+		return nil
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *help {
+		flag.Usage()
+		os.Exit(1)
+	}
+	// 版本交换在连接池拨号时完成，复用的空闲连接不会重复交换
+	pool := thriftpool.NewThriftPool(*server, 5000, 5000, 10, 1,
+		thriftpool.WithFramedTransport(0), thriftpool.WithOnConnReady(versionExchange(*version)))
+	defer pool.Close()
+
+	for i := 0; i < 3; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			fmt.Printf("Get a Thrift connection from pool failed: %s\n", err.Error())
+			os.Exit(1)
+		}
+		res, err := thriftpool.ClientFor(conn, echo.NewEchoClientFactory).Echo(&echo.EchoReq{Msg: "Hello"})
+		if err != nil {
+			_ = conn.CloseTransport()
+			_ = pool.Put(conn)
+			fmt.Printf("Echo failed: %s\n", err.Error())
+			os.Exit(1)
+		}
+		_ = pool.Put(conn)
+		fmt.Printf("Echo: %s\n", res.GetMsg())
+	}
+}
@@ -0,0 +1,96 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// 主备连接池
+// 正常情况下从主端点的连接池取连接，主端点熔断（连续拨号失败）后改从备用端点的连接池取连接，
+// 后台协程定期探测主端点，探测成功即关闭熔断，之后的 Get 自动切回主端点。
+// 主备两个连接池都由 PoolManager 持有，需在管理器上设置 BreakerThreshold 才会发生切换
+type FailoverPool struct {
+	Primary			string				// 主端点
+	Standby			string				// 备用端点
+	ProbeInterval	time.Duration		// 主端点熔断期间的探测间隔，默认1s
+	manager			*PoolManager
+	closed			int32				// 为 1 表示已关闭
+	stopChan		chan bool			// 通知探测协程退出
+}
+
+// 创建主备连接池，总是返回非nil值
+// 注意在使用完后，应调用成员函数 Close 停止探测协程，连接池本身仍由 manager 负责关闭
+func NewFailoverPool(manager *PoolManager, primary, standby string, probeInterval int32) *FailoverPool {
+	failoverPool := new(FailoverPool)
+	failoverPool.Primary = primary
+	failoverPool.Standby = standby
+	if probeInterval < 1 {
+		failoverPool.ProbeInterval = time.Duration(1000) * time.Millisecond
+	} else {
+		failoverPool.ProbeInterval = time.Duration(probeInterval) * time.Millisecond
+	}
+	failoverPool.manager = manager
+	failoverPool.stopChan = make(chan bool)
+
+	go failoverPool.probePrimary()
+	return failoverPool
+}
+
+// 从当前可用端点的连接池取一个连接，应和 Put 一对一成对调用
+// 主端点取连接失败并因此熔断时，本次调用直接改从备用端点取
+func (f *FailoverPool) Get(ctx context.Context) (*ThriftConn, error) {
+	primaryPool := f.manager.GetPool(f.Primary)
+	if !primaryPool.IsBreakerOpen() {
+		conn, err := primaryPool.Get(ctx)
+		if err == nil || !primaryPool.IsBreakerOpen() {
+			return conn, err
+		}
+	}
+	return f.manager.GetPool(f.Standby).Get(ctx)
+}
+
+// 连接用完后归还回其所属端点的连接池，应和 Get 一对一成对调用
+func (f *FailoverPool) Put(conn *ThriftConn) error {
+	return f.manager.GetPool(conn.GetEndpoint()).Put(conn)
+}
+
+// 当前提供服务的端点
+func (f *FailoverPool) GetActiveEndpoint() string {
+	if f.manager.GetPool(f.Primary).IsBreakerOpen() {
+		return f.Standby
+	}
+	return f.Primary
+}
+
+// 当前提供服务的端点的连接池状态
+func (f *FailoverPool) Stats() PoolStats {
+	return f.manager.GetPool(f.GetActiveEndpoint()).Stats()
+}
+
+// 停止探测协程，返回值为满足 Pool 接口，总是nil
+func (f *FailoverPool) Close() error {
+	if atomic.CompareAndSwapInt32(&f.closed, 0, 1) {
+		close(f.stopChan)
+	}
+	return nil
+}
+
+// 主端点熔断期间定期探测，直到主端点恢复
+func (f *FailoverPool) probePrimary() {
+	ticker := time.NewTicker(f.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stopChan:
+			return
+		case <-ticker.C:
+		}
+		primaryPool := f.manager.GetPool(f.Primary)
+		if primaryPool.IsBreakerOpen() {
+			_ = primaryPool.Probe()
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFailoverPool(t *testing.T) {
+	standby, stopStandby := startTestServer(t, "127.0.0.1:0")
+	defer stopStandby()
+	// 先占用一个端口再释放，作为暂时不可用的主端点
+	primary, stopPrimary := startTestServer(t, "127.0.0.1:0")
+	stopPrimary()
+
+	manager := NewPoolManager(100, 1000, 10, 1)
+	manager.BreakerThreshold = 1
+	defer manager.Close()
+	pool := NewFailoverPool(manager, primary, standby, 20)
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if conn.GetEndpoint() != standby {
+		t.Errorf("conn endpoint is %s, want standby %s\n", conn.GetEndpoint(), standby)
+	}
+	if pool.GetActiveEndpoint() != standby {
+		t.Errorf("active endpoint is %s, want standby %s\n", pool.GetActiveEndpoint(), standby)
+	}
+	stats := Pool(pool).Stats()
+	if stats.Endpoint != standby || stats.Used != 1 {
+		t.Errorf("stats should come from the standby pool:%s\n", stats)
+	}
+	if err = pool.Put(conn); err != nil {
+		t.Errorf("pool.Put error:%s\n", err.Error())
+	}
+
+	// 主端点恢复后，后台探测应关闭熔断并切回主端点
+	_, stopPrimary = startTestServer(t, primary)
+	defer stopPrimary()
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.GetActiveEndpoint() != primary && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pool.GetActiveEndpoint() != primary {
+		t.Fatalf("active endpoint is %s, want primary %s\n", pool.GetActiveEndpoint(), primary)
+	}
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if conn.GetEndpoint() != primary {
+		t.Errorf("conn endpoint is %s, want primary %s\n", conn.GetEndpoint(), primary)
+	}
+	_ = pool.Put(conn)
+}
+
+func TestBreakerOpen(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	stop()
+
+	pool := NewThriftPool(endpoint, 100, 1000, 10, 1)
+	defer pool.Close()
+	pool.SetBreakerThreshold(2)
+	pool.SetBreakerCooldown(60000)
+
+	for i := 0; i < 2; i++ {
+		if _, err := pool.Get(context.Background()); err == nil || err == ErrBreakerOpen {
+			t.Fatalf("pool.Get should fail with dial error, got %v\n", err)
+		}
+	}
+	if !pool.IsBreakerOpen() {
+		t.Fatalf("breaker should be open after %d dial failures\n", pool.GetDialFailures())
+	}
+	if _, err := pool.Get(context.Background()); err != ErrBreakerOpen {
+		t.Errorf("pool.Get error is %v, want ErrBreakerOpen\n", err)
+	}
+	if pool.GetUsed() != 0 {
+		t.Errorf("used is %d, want 0\n", pool.GetUsed())
+	}
+}
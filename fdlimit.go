@@ -0,0 +1,90 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 进程的文件描述符接近上限，拒绝拨号
+var ErrFDLimit = errors.New("thriftpool fd limit")
+
+// 返回进程当前打开的文件描述符数和上限，用于拨号前检查，参见 WithFDLimit
+type FDChecker func() (open, limit int, err error)
+
+// 拨号前检查进程的文件描述符，剩余不超过 reserve 个时拒绝拨号并返回 ErrFDLimit，同时记录日志。
+// 用于与大量其它资源共用进程、ulimit 较低的场景，避免 MaxSize 配置过大耗尽文件描述符。
+// checker 为nil时使用 ProcFDChecker，仅支持 Linux；checker 返回错误时（如其它平台）不做限制。
+// 每次拨号都会调用 checker，ProcFDChecker 需要遍历 /proc/self/fd，打开的文件很多时有一定开销
+func WithFDLimit(reserve int32, checker FDChecker) Option {
+	return func(t *ThriftPool) {
+		if checker == nil {
+			checker = ProcFDChecker
+		}
+		t.FDReserve = reserve
+		t.FDChecker = checker
+	}
+}
+
+// 通过 /proc 读取当前进程的文件描述符：打开数为 /proc/self/fd 的条目数，
+// 上限为 /proc/self/limits 中 "Max open files" 的软限制，仅 Linux 可用
+func ProcFDChecker() (int, int, error) {
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, 0, err
+	}
+	limit, err := readFDLimit("/proc/self/limits")
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(fds), limit, nil
+}
+
+// 从 limits 文件中读取打开文件数的软限制，unlimited 时返回-1
+func readFDLimit(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(fields) == 0 {
+			break
+		}
+		if fields[0] == "unlimited" {
+			return -1, nil
+		}
+		return strconv.Atoi(fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no open files limit in %s", path)
+}
+
+// 检查文件描述符是否足够拨号，未启用或无法检查时返回nil
+func (t *ThriftPool) checkFDs() error {
+	if t.FDChecker == nil {
+		return nil
+	}
+	open, limit, err := t.FDChecker()
+	if err != nil || limit < 0 {
+		return nil
+	}
+	if limit-open > int(t.FDReserve) {
+		return nil
+	}
+	t.logf("refuse to dial, open fds:%d, limit:%d, reserve:%d", open, limit, t.FDReserve)
+	return fmt.Errorf("%w, open:%d, limit:%d, reserve:%d", ErrFDLimit, open, limit, t.FDReserve)
+}
@@ -0,0 +1,57 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFDLimit(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	open := int32(50)
+	checker := func() (int, int, error) {
+		return int(atomic.LoadInt32(&open)), 100, nil
+	}
+	logger := &testLogger{}
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithFDLimit(10, checker), WithLogger(logger))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("dial with enough fds failed:%s\n", err)
+	}
+	_ = pool.Put(conn)
+
+	// 空闲连接不需要拨号，仍可借出
+	atomic.StoreInt32(&open, 90)
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("idle conn should be borrowed without dialing:%s\n", err)
+	}
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrFDLimit) {
+		t.Errorf("dial near the fd limit should fail with ErrFDLimit, got %v\n", err)
+	}
+	if len(logger.lines) != 1 || pool.GetUsed() != 1 || pool.GetOpen() != 1 || pool.GetDialFailures() != 0 {
+		t.Errorf("refused dial should be logged without counting as a failure, logs:%v used:%d open:%d\n",
+			logger.lines, pool.GetUsed(), pool.GetOpen())
+	}
+	_ = pool.Put(conn)
+}
+
+func TestProcFDChecker(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("ProcFDChecker only supports linux")
+	}
+	open, limit, err := ProcFDChecker()
+	if err != nil {
+		t.Fatalf("ProcFDChecker failed:%s\n", err)
+	}
+	if open < 1 || (limit != -1 && limit < open) {
+		t.Errorf("unexpected fds, open:%d limit:%d\n", open, limit)
+	}
+}
@@ -0,0 +1,31 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 为每个连接套上 TFramedTransport，服务端使用 framed transport 时需要开启。
+// maxFrameSize 为允许读取的最大帧（字节），小于1时为 thrift 默认的 DEFAULT_MAX_LENGTH；
+// 读到超过该大小的帧头时直接返回 "Incorrect frame size" 错误，而不会按帧头分配内存，
+// 避免异常或恶意的服务端导致大量内存分配
+func WithFramedTransport(maxFrameSize int32) Option {
+	return func(t *ThriftPool) {
+		t.FramedTransport = true
+		if maxFrameSize < 1 {
+			t.MaxFrameSize = thrift.DEFAULT_MAX_LENGTH
+		} else {
+			t.MaxFrameSize = uint32(maxFrameSize)
+		}
+	}
+}
+
+// 为连接套上 framed transport，位于缓冲之外
+// framed transport 在拨号时创建一次并随连接放回池中，同一连接上的多次调用复用其读写缓冲，
+// 因此无需 Java 版的 TFastFramedTransport（Go 版 thrift 也没有提供）
+func (t *ThriftPool) wrapFramed(conn *ThriftConn) {
+	if !t.FramedTransport {
+		return
+	}
+	conn.transport = thrift.NewTFramedTransportMaxLength(conn.transport, t.MaxFrameSize)
+}
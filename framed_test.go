@@ -0,0 +1,44 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestMaxFrameSize(t *testing.T) {
+	// 服务端对每个连接都回一个声称 1GB 的帧头
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error:%s\n", err.Error())
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			header := make([]byte, 4)
+			binary.BigEndian.PutUint32(header, 1<<30)
+			_, _ = conn.Write(header)
+		}
+	}()
+
+	pool := NewThriftPool(listener.Addr().String(), 1000, 60000, 10, 1, WithFramedTransport(1024))
+	defer pool.Close()
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	buf := make([]byte, 16)
+	_, err = conn.GetTransport().Read(buf)
+	if err == nil || !strings.Contains(err.Error(), "frame size") {
+		t.Errorf("oversized frame should be rejected, got %v\n", err)
+	}
+	_ = conn.CloseTransport()
+	_ = pool.Put(conn)
+}
@@ -0,0 +1,28 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 借出连接，连接池耗尽时最多等待 maxWait 让其它协程归还连接或释放连接名额，而不是像 Get 那样立即失败。
+// 等待按 GetPriority 的 PriorityLow 排队，归还的连接直接交给等待者；maxWait 同时限制期间拨号的耗时。
+// maxWait 用尽时返回包装了 ErrPoolExhausted 的错误，附带等待时长和连接数；
+// ctx 先结束时返回包装了 ctx.Err() 的错误；maxWait 小于等于0时同 Get。应和 Put 一对一成对调用
+func (t *ThriftPool) GetWait(ctx context.Context, maxWait time.Duration) (*ThriftConn, error) {
+	if maxWait <= 0 {
+		return t.Get(ctx)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+	conn, err := t.GetPriority(waitCtx, PriorityLow)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		// 是 maxWait 而不是调用方的 ctx 到期
+		return nil, t.nameErr(fmt.Errorf("%w: waited %s, used:%d, max:%d, waiting:%d",
+			ErrPoolExhausted, maxWait, t.GetUsed(), t.GetEffectiveMaxSize(), t.GetWaiting()))
+	}
+	return conn, err
+}
@@ -0,0 +1,76 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetWait(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 1, 0)
+	defer pool.Close()
+
+	held, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolExhausted) {
+		t.Fatalf("Get from an exhausted pool should fail, got %v\n", err)
+	}
+
+	// 归还的连接交给等待中的 GetWait
+	type result struct {
+		conn	*ThriftConn
+		err		error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := pool.GetWait(context.Background(), time.Second)
+		done <- result{conn, err}
+	}()
+	for pool.GetWaiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := pool.Put(held); err != nil {
+		t.Fatalf("pool.Put error:%s\n", err.Error())
+	}
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("GetWait error:%s\n", r.err.Error())
+		}
+		if r.conn != held {
+			t.Errorf("GetWait should get the returned conn\n")
+		}
+		held = r.conn
+	case <-time.After(time.Second):
+		t.Fatalf("Put should unblock GetWait\n")
+	}
+
+	// 等待超时
+	startTime := time.Now()
+	_, err = pool.GetWait(context.Background(), 50*time.Millisecond)
+	if !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("GetWait should return ErrPoolExhausted after maxWait, got %v\n", err)
+	}
+	if elapsed := time.Since(startTime); elapsed < 50*time.Millisecond {
+		t.Errorf("GetWait should wait for maxWait, elapsed:%s\n", elapsed)
+	}
+
+	// 调用方的 ctx 先结束
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = pool.GetWait(ctx, time.Second)
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("GetWait should return the ctx error when ctx ends first, got %v\n", err)
+	}
+
+	_ = pool.Put(held)
+	if pool.GetUsed() != 0 || pool.GetWaiting() != 0 {
+		t.Errorf("used and waiting should be 0, used:%d, waiting:%d\n", pool.GetUsed(), pool.GetWaiting())
+	}
+}
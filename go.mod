@@ -1,5 +1,5 @@
 module github.com/tianxingpan/thriftpool
 
-go 1.14
+go 1.18
 
 require git.apache.org/thrift.git v0.0.0-20190309152529-a9b748bb0e02
@@ -0,0 +1,33 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// 优雅关闭时检查借出连接是否都已归还的间隔
+var drainCheckInterval = 10 * time.Millisecond
+
+// 优雅关闭连接池：立即停止借出连接（Get 返回 ErrPoolClosed），等待借出的连接都归还后再 Close。
+// ctx 结束时仍有连接未归还则直接 Close，并返回包装了 ctx.Err() 的错误；
+// 此后归还的连接在 Put 时关闭
+func (t *ThriftPool) CloseGracefully(ctx context.Context) error {
+	atomic.StoreInt32(&t.draining, 1)
+	defer t.Close()
+
+	ticker := time.NewTicker(drainCheckInterval)
+	defer ticker.Stop()
+	for t.GetUsed()+t.GetOverflow() > 0 {
+		select {
+		case <-t.ctx.Done():
+			return nil
+		case <-ctx.Done():
+			return t.nameErr(fmt.Errorf("%w: close with %d connections in use", ctx.Err(), t.GetUsed()))
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Package graceful wires graceful shutdown of a thriftpool to os signals
+package graceful
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tianxingpan/thriftpool"
+)
+
+// 收到任一信号时优雅关闭连接池，最多等待 timeout 让借出的连接归还，如
+//   graceful.CloseOnSignal(pool, 10*time.Second, syscall.SIGTERM, os.Interrupt)
+// 未指定信号时监听 os.Interrupt 和 SIGTERM；不会监听所有信号，运行时用于抢占的 SIGURG 及 SIGCHLD、SIGWINCH 等不应关闭连接池。
+// 返回的函数用于取消监听，可重复调用，连接池自行关闭时也会取消监听。
+// 收到信号后只关闭连接池，进程是否退出由调用方决定
+func CloseOnSignal(pool *thriftpool.ThriftPool, timeout time.Duration, sig ...os.Signal) (stop func()) {
+	if len(sig) == 0 {
+		sig = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig...)
+	stopChan := closeOn(pool, timeout, signals)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(stopChan)
+		})
+	}
+}
+
+func closeOn(pool *thriftpool.ThriftPool, timeout time.Duration, signals <-chan os.Signal) chan struct{} {
+	stopChan := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			_ = pool.CloseGracefully(ctx)
+		case <-pool.Done():
+		case <-stopChan:
+		}
+	}()
+	return stopChan
+}
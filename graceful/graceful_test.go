@@ -0,0 +1,69 @@
+// Package graceful wires graceful shutdown of a thriftpool to os signals
+package graceful
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tianxingpan/thriftpool"
+)
+
+func TestCloseOn(t *testing.T) {
+	pool := thriftpool.NewThriftPool("127.0.0.1:1", 100, 60000, 10, 1)
+	signals := make(chan os.Signal, 1)
+	closeOn(pool, time.Second, signals)
+
+	signals <- os.Interrupt
+	select {
+	case <-pool.Done():
+	case <-time.After(time.Second):
+		t.Errorf("pool should be closed after the signal\n")
+	}
+}
+
+func TestStop(t *testing.T) {
+	pool := thriftpool.NewThriftPool("127.0.0.1:1", 100, 60000, 10, 1)
+	defer pool.Close()
+	stop := CloseOnSignal(pool, time.Second, os.Interrupt)
+	stop()
+	// 重复调用不会 panic
+	stop()
+
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case <-pool.Done():
+		t.Errorf("pool should stay open after stop\n")
+	default:
+	}
+}
+
+func TestDefaultSignals(t *testing.T) {
+	pool := thriftpool.NewThriftPool("127.0.0.1:1", 100, 60000, 10, 1)
+	defer pool.Close()
+	stop := CloseOnSignal(pool, time.Second)
+	defer stop()
+
+	// 与终止无关的信号不关闭连接池
+	for _, sig := range []syscall.Signal{syscall.SIGURG, syscall.SIGWINCH, syscall.SIGCHLD} {
+		if err := syscall.Kill(os.Getpid(), sig); err != nil {
+			t.Fatalf("kill %s error:%s\n", sig, err.Error())
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-pool.Done():
+		t.Fatalf("pool should stay open on unrelated signals\n")
+	default:
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("kill SIGTERM error:%s\n", err.Error())
+	}
+	select {
+	case <-pool.Done():
+	case <-time.After(time.Second):
+		t.Errorf("pool should be closed after SIGTERM\n")
+	}
+}
@@ -0,0 +1,55 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCloseGracefully(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1)
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = pool.Put(conn)
+	}()
+
+	done := make(chan error)
+	go func() {
+		done <- pool.CloseGracefully(context.Background())
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Get while draining should return ErrPoolClosed, got %v\n", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("CloseGracefully error:%s\n", err)
+	}
+	if pool.GetOpen() != 0 {
+		t.Errorf("all connections should be closed, open:%d\n", pool.GetOpen())
+	}
+
+	// 超时仍有连接未归还
+	pool = NewThriftPool(endpoint, 1000, 60000, 10, 1)
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.CloseGracefully(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CloseGracefully should time out, got %v\n", err)
+	}
+	_ = pool.Put(conn)
+	if !conn.IsClose() || pool.GetOpen() != 0 {
+		t.Errorf("conn returned after close should be closed, open:%d\n", pool.GetOpen())
+	}
+}
@@ -0,0 +1,67 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 套在连接最外层的 transport，由 GuardedTransport 和 ClientFactory 返回，保证协议状态不确定的连接不会被放回池：
+// 1) Write 或 Flush 出错时（如 oprot.Flush 写到一半失败）立即关闭socket并标记连接不可用，
+//    即使调用方忽略了错误直接 Put，连接也会被关闭而不是带着半截请求借给下一个调用方；
+// 2) 记录写入后尚未 Flush 的数据，缓冲、framed 和 zlib 会把它们留在内存中，
+//    下一次借出的 Flush 会连同这些残留数据一起发出，因此 Put 时有未 Flush 数据的连接同样被关闭
+type guardTransport struct {
+	thrift.TTransport
+	conn	*ThriftConn
+	pending	bool	// 写入后尚未 Flush
+}
+
+func (g *guardTransport) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		g.pending = true
+	}
+	n, err := g.TTransport.Write(p)
+	if err != nil {
+		g.fail(err)
+	}
+	return n, err
+}
+
+func (g *guardTransport) Flush() error {
+	if err := g.TTransport.Flush(); err != nil {
+		g.fail(err)
+		return err
+	}
+	g.pending = false
+	return nil
+}
+
+// 写失败后连接的协议状态不确定，不能再复用
+func (g *guardTransport) fail(err error) {
+	if !g.conn.IsUsable() {
+		return
+	}
+	if g.conn.pool != nil {
+		g.conn.pool.logf("write to %s failed, conn discarded:%s", g.conn.GetEndpoint(), err.Error())
+	}
+	_ = g.conn.CloseTransport()
+}
+
+// 在连接的最外层套上 guardTransport，GetTransport 仍返回原来的 transport
+func (t *ThriftPool) guard(conn *ThriftConn) {
+	conn.guard = &guardTransport{TTransport: conn.transport, conn: conn}
+}
+
+// 返回带写失败保护的 transport，自行创建 protocol 时应使用它而不是 GetTransport：
+// 经它 Write 或 Flush 出错的连接被标记为不可用，Put 时有未 Flush 数据的连接被关闭
+func (t *ThriftConn) GuardedTransport() thrift.TTransport {
+	if t.guard == nil {
+		return t.transport
+	}
+	return t.guard
+}
+
+// 是否有写入后未 Flush 的数据
+func (t *ThriftConn) hasPendingWrites() bool {
+	return t.guard != nil && t.guard.pending
+}
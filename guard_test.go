@@ -0,0 +1,91 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/tianxingpan/thriftpool/example/echo"
+	"github.com/tianxingpan/thriftpool/testutil"
+)
+
+// failFlush 不为0时 Flush 失败；套在 framed 之内时帧已写出，模拟 oprot.Flush 写到一半出错
+type flakyTransport struct {
+	thrift.TTransport
+	failFlush	*int32
+}
+
+func (f *flakyTransport) Flush() error {
+	if atomic.LoadInt32(f.failFlush) != 0 {
+		return errors.New("injected flush failure")
+	}
+	return f.TTransport.Flush()
+}
+
+func TestGuardFlushFailure(t *testing.T) {
+	endpoint, stop := testutil.StartEchoServer(t)
+	defer stop()
+
+	var failFlush int32
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithFramedTransport(0),
+		WithTransportWrapper(func(socket *thrift.TSocket) (thrift.TTransport, error) {
+			return &flakyTransport{TTransport: socket, failFlush: &failFlush}, nil
+		}))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	atomic.StoreInt32(&failFlush, 1)
+	if _, err := echo.NewEchoClientFactory(conn.ClientFactory()).Echo(&echo.EchoReq{Msg: "hello"}); err == nil {
+		t.Fatalf("Echo should fail on flush\n")
+	}
+	if conn.IsUsable() {
+		t.Errorf("conn should be unusable right after the failed flush\n")
+	}
+	// 调用方忽略错误直接 Put，连接仍被关闭
+	_ = pool.Put(conn)
+	if !conn.IsClose() || pool.GetIdle() != 0 || pool.GetOpen() != 0 || pool.Stats().EvictedBroken != 1 {
+		t.Errorf("conn failing flush should be discarded, idle:%d, open:%d\n", pool.GetIdle(), pool.GetOpen())
+	}
+
+	atomic.StoreInt32(&failFlush, 0)
+	next, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if next == conn {
+		t.Errorf("discarded conn should not be borrowed again\n")
+	}
+	if _, err := echo.NewEchoClientFactory(next.ClientFactory()).Echo(&echo.EchoReq{Msg: "hello"}); err != nil {
+		t.Errorf("Echo on fresh conn error:%s\n", err.Error())
+	}
+	_ = pool.Put(next)
+	if pool.GetIdle() != 1 {
+		t.Errorf("healthy conn should be pooled, idle:%d\n", pool.GetIdle())
+	}
+}
+
+func TestGuardPendingWrites(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithFramedTransport(0))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if _, err := conn.GuardedTransport().Write([]byte("half a request")); err != nil {
+		t.Fatalf("write error:%s\n", err.Error())
+	}
+	// 残留在 framed 缓冲中的数据会随下一次 Flush 发出，连接不能复用
+	_ = pool.Put(conn)
+	if !conn.IsClose() || pool.GetIdle() != 0 {
+		t.Errorf("conn with unflushed writes should be discarded, idle:%d\n", pool.GetIdle())
+	}
+}
@@ -0,0 +1,77 @@
+// Package health serves http health checks backed by a thriftpool
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tianxingpan/thriftpool"
+)
+
+// PoolHealthHandler 两次试探拨号之间的最小间隔
+const DefaultDialInterval = 5 * time.Second
+
+// 连接池的健康检查 http.Handler，可挂载为 Kubernetes 的 liveness/readiness 探针
+type Handler struct {
+	pool			*thriftpool.ThriftPool
+	dialInterval	time.Duration	// 两次试探拨号之间的最小间隔
+	mutex			sync.Mutex
+	lastDial		time.Time		// 最近一次试探拨号的时间
+	lastErr			error			// 最近一次试探拨号的结果
+}
+
+// 创建连接池的健康检查 Handler，试探拨号的间隔为 DefaultDialInterval，参见 NewHandler
+func PoolHealthHandler(pool *thriftpool.ThriftPool) http.Handler {
+	return NewHandler(pool, DefaultDialInterval)
+}
+
+// 创建连接池的健康检查 Handler
+// 连接池有空闲或借出中的连接时返回200；否则用 Ping 试探（拨号探测连接并做健康检查），成功时返回200，失败时返回503，
+// 连接池已关闭时总是返回503。响应体为 JSON 格式的 Stats。
+// 两次试探的间隔不小于 dialInterval（小于1时为 DefaultDialInterval），间隔内的请求沿用上次的试探结果，
+// 避免探针频繁拨号冲击服务端
+func NewHandler(pool *thriftpool.ThriftPool, dialInterval time.Duration) *Handler {
+	if dialInterval < 1 {
+		dialInterval = DefaultDialInterval
+	}
+	return &Handler{
+		pool:         pool,
+		dialInterval: dialInterval,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	if err := h.check(r.Context()); err != nil {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(h.pool.Stats())
+}
+
+// 检查连接池是否健康，不健康时返回原因
+func (h *Handler) check(ctx context.Context) error {
+	select {
+	case <-h.pool.Done():
+		return thriftpool.ErrPoolClosed
+	default:
+	}
+	if h.pool.GetIdle() > 0 || h.pool.GetUsed() > 0 {
+		return nil
+	}
+
+	// 并发的探针请求等待同一次试探的结果
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if !h.lastDial.IsZero() && time.Since(h.lastDial) < h.dialInterval {
+		return h.lastErr
+	}
+	h.lastDial = time.Now()
+	err := h.pool.Ping(ctx)
+	h.lastErr = err
+	return err
+}
@@ -0,0 +1,79 @@
+// Package health serves http health checks backed by a thriftpool
+package health
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tianxingpan/thriftpool"
+)
+
+func serve(t *testing.T, handler http.Handler) (int, thriftpool.PoolStats) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var stats thriftpool.PoolStats
+	if err := json.NewDecoder(recorder.Body).Decode(&stats); err != nil {
+		t.Errorf("decode stats error:%s\n", err.Error())
+	}
+	return recorder.Code, stats
+}
+
+func TestHealthy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error:%s\n", err.Error())
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			if _, err := listener.Accept(); err != nil {
+				return
+			}
+		}
+	}()
+
+	pool := thriftpool.NewThriftPool(listener.Addr().String(), 2000, 60000, 5, 0)
+	defer pool.Close()
+	code, stats := serve(t, PoolHealthHandler(pool))
+	if code != http.StatusOK {
+		t.Errorf("healthy pool should return 200, got %d\n", code)
+	}
+	if stats.Endpoint != listener.Addr().String() {
+		t.Errorf("unexpected stats endpoint:%s\n", stats.Endpoint)
+	}
+	if pool.GetIdle() != 0 || pool.GetOpen() != 0 || pool.GetProbes() != 0 {
+		t.Errorf("probe conn should be closed without using pool capacity, idle:%d open:%d probes:%d\n",
+			pool.GetIdle(), pool.GetOpen(), pool.GetProbes())
+	}
+}
+
+func TestUnhealthy(t *testing.T) {
+	pool := thriftpool.NewThriftPool("127.0.0.1:1", 100, 60000, 5, 0)
+	defer pool.Close()
+	handler := NewHandler(pool, time.Hour)
+
+	code, _ := serve(t, handler)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("unreachable pool should return 503, got %d\n", code)
+	}
+	code, _ = serve(t, handler)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("cached probe result should return 503, got %d\n", code)
+	}
+	if pool.GetDialFailures() != 1 {
+		t.Errorf("probe should dial once within the interval, dial failures:%d\n", pool.GetDialFailures())
+	}
+}
+
+func TestClosed(t *testing.T) {
+	pool := thriftpool.NewThriftPool("127.0.0.1:1", 100, 60000, 5, 0)
+	pool.Close()
+	code, _ := serve(t, PoolHealthHandler(pool))
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("closed pool should return 503, got %d\n", code)
+	}
+}
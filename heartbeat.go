@@ -0,0 +1,75 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"time"
+)
+
+// 心跳函数，通常调用服务端的空操作方法，返回错误表示连接已失效
+// 应在 ctx 超时前返回，thrift 0.9.3 的调用不接受 ctx 时由socket的读写超时兜底
+type HeartbeatFunc func(ctx context.Context, conn *ThriftConn) error
+
+// 开启空闲连接心跳：每隔 interval 毫秒对空闲连接调用一次 fn，每次调用的超时为 timeout 毫秒，
+// 心跳失败或超时的连接被关闭。心跳既能发现已被对端半关闭的连接，也能保持NAT映射不过期。
+// interval 小于1时为30秒，timeout 小于1时为5秒；fn 为nil时不开启（默认）。
+// 心跳不会刷新连接的最近使用时间，闲置超时回收不受影响
+func WithHeartbeat(fn HeartbeatFunc, interval, timeout int32) Option {
+	return func(t *ThriftPool) {
+		t.HeartbeatFunc = fn
+		if interval < 1 {
+			t.HeartbeatInterval = time.Duration(30000) * time.Millisecond
+		} else {
+			t.HeartbeatInterval = time.Duration(interval) * time.Millisecond
+		}
+		if timeout < 1 {
+			t.HeartbeatTimeout = time.Duration(5000) * time.Millisecond
+		} else {
+			t.HeartbeatTimeout = time.Duration(timeout) * time.Millisecond
+		}
+	}
+}
+
+// 定期对空闲连接发送心跳
+func (t *ThriftPool) monitorHeartbeat() {
+	ticker := time.NewTicker(t.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		idleSize := t.GetIdle()
+		for i := 0; i < int(idleSize); i++ {
+			conn, _ := t.get(t.ctx, true)
+			if conn == nil {
+				break
+			}
+			if err := t.heartbeat(conn); err != nil {
+				t.logf("heartbeat %s failed:%s", conn.GetEndpoint(), err.Error())
+				t.discard(conn)
+				t.countEvicted(evictBroken)
+				t.subUsed()
+				t.emit(EventEvicted, nil)
+				continue
+			}
+			err := t.put(conn, true)
+			if err != nil {
+				t.logf("put back after heartbeat failed:%s", err.Error())
+			}
+		}
+	}
+}
+
+func (t *ThriftPool) heartbeat(conn *ThriftConn) error {
+	ctx, cancel := context.WithTimeout(t.ctx, t.HeartbeatTimeout)
+	defer cancel()
+	err := t.HeartbeatFunc(ctx, conn)
+	if err == nil {
+		err = ctx.Err()
+	}
+	return err
+}
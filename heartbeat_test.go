@@ -0,0 +1,59 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	var mutex sync.Mutex
+	var broken *ThriftConn
+	beats := make(map[*ThriftConn]int)
+	heartbeat := func(ctx context.Context, conn *ThriftConn) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		beats[conn]++
+		if conn == broken {
+			return errors.New("heartbeat failed")
+		}
+		return nil
+	}
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 2, WithHeartbeat(heartbeat, 20, 100))
+	defer pool.Close()
+
+	conn1, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	conn2, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	mutex.Lock()
+	broken = conn2
+	mutex.Unlock()
+	_ = pool.Put(conn1)
+	_ = pool.Put(conn2)
+
+	time.Sleep(200 * time.Millisecond)
+	if pool.GetIdle() != 1 || pool.GetUsed() != 0 || pool.GetOpen() != 1 {
+		t.Errorf("unexpected counters, idle:%d, used:%d, open:%d\n", pool.GetIdle(), pool.GetUsed(), pool.GetOpen())
+	}
+	// Close 等待心跳协程退出后再检查连接状态
+	pool.Close()
+	if !conn2.IsClose() {
+		t.Errorf("conn failing heartbeat should be evicted\n")
+	}
+	mutex.Lock()
+	if beats[conn1] < 2 || beats[conn2] != 1 {
+		t.Errorf("unexpected heartbeats, healthy:%d, broken:%d\n", beats[conn1], beats[conn2])
+	}
+	mutex.Unlock()
+}
@@ -0,0 +1,82 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync"
+	"time"
+)
+
+// 按固定间隔记录 Stats 快照，保留最近 size 个，供管理页面展示趋势，不依赖外部时序数据库
+// interval 单位毫秒，小于1时默认1s；size 小于1时不记录（默认）
+func WithStatsHistory(size, interval int32) Option {
+	return func(t *ThriftPool) {
+		if size < 1 {
+			t.HistorySize = 0
+		} else {
+			t.HistorySize = size
+		}
+		if interval < 1 {
+			t.HistoryInterval = time.Second
+		} else {
+			t.HistoryInterval = time.Duration(interval) * time.Millisecond
+		}
+	}
+}
+
+// Stats 快照的环形缓冲，写满后覆盖最旧的快照
+type statsRing struct {
+	mutex	sync.Mutex
+	samples	[]PoolStats
+	next	int		// 下一个写入的位置
+	full	bool	// 是否已写满一圈
+}
+
+func newStatsRing(size int32) *statsRing {
+	return &statsRing{samples: make([]PoolStats, size)}
+}
+
+func (r *statsRing) add(stats PoolStats) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.samples[r.next] = stats
+	r.next++
+	if r.next == len(r.samples) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// 按时间先后返回快照的副本
+func (r *statsRing) snapshot() []PoolStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.full {
+		return append([]PoolStats(nil), r.samples[:r.next]...)
+	}
+	history := make([]PoolStats, 0, len(r.samples))
+	history = append(history, r.samples[r.next:]...)
+	return append(history, r.samples[:r.next]...)
+}
+
+// 最近记录的 Stats 快照，按时间先后排列，最多 HistorySize 个；未开启时返回nil
+func (t *ThriftPool) History() []PoolStats {
+	if t.history == nil {
+		return nil
+	}
+	return t.history.snapshot()
+}
+
+// 定期记录 Stats 快照，连接池关闭时退出
+func (t *ThriftPool) sampleStats() {
+	ticker := time.NewTicker(t.HistoryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		t.history.add(t.Stats())
+	}
+}
@@ -0,0 +1,46 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsRing(t *testing.T) {
+	ring := newStatsRing(3)
+	if len(ring.snapshot()) != 0 {
+		t.Errorf("empty ring should have no samples\n")
+	}
+	for i := int32(1); i <= 5; i++ {
+		ring.add(PoolStats{Used: i})
+	}
+	history := ring.snapshot()
+	if len(history) != 3 || history[0].Used != 3 || history[1].Used != 4 || history[2].Used != 5 {
+		t.Errorf("ring should keep the latest samples in order, got %v\n", history)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1)
+	if pool.History() != nil {
+		t.Errorf("history should be nil when disabled\n")
+	}
+	pool.Close()
+
+	pool = NewThriftPool(endpoint, 1000, 60000, 10, 1, WithStatsHistory(4, 5))
+	defer pool.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(pool.History()) < 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	history := pool.History()
+	if len(history) != 4 {
+		t.Fatalf("history should be bounded by its size, got %d samples\n", len(history))
+	}
+	if history[3].Endpoint != endpoint || history[3].MaxSize != 10 {
+		t.Errorf("unexpected sample:%s\n", history[3])
+	}
+}
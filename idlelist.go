@@ -0,0 +1,121 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync"
+)
+
+// 空闲连接队列，按放回的先后排列，队首是最久未使用的连接
+// 用互斥锁保护的切片代替channel，遍历、按条件挑选和关闭指定连接都不必取出整个队列，
+// 因此并发的 Get 不会因队列被临时取空而拨号或失败。空闲连接数仍由连接池的 idle 计数预占和扣减
+type idleList struct {
+	mutex	sync.Mutex
+	conns	[]*ThriftConn
+	max		int				// 队列容量
+	closed	bool
+	ready	chan struct{}	// 有连接放回时发出通知，供等待拨号名额的 Get 改取空闲连接
+}
+
+// 切片随放回的连接按需增长，capacity 只是上限，不预分配
+func newIdleList(capacity int32) *idleList {
+	return &idleList{
+		max:   int(capacity),
+		ready: make(chan struct{}, 1),
+	}
+}
+
+// 放回队尾，队列已关闭或已满时返回 false
+func (l *idleList) push(conn *ThriftConn) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed || len(l.conns) >= l.max {
+		return false
+	}
+	l.conns = append(l.conns, conn)
+	l.notify()
+	return true
+}
+
+// 通知一个等待者，调用时须持有锁
+func (l *idleList) notify() {
+	select {
+	case l.ready <- struct{}{}:
+	default:
+	}
+}
+
+// 取出队首的连接，队列为空时返回nil
+func (l *idleList) popFront() *ThriftConn {
+	return l.pick(func(conns []*ThriftConn) int { return 0 })
+}
+
+// 取出 choose 选中的连接，choose 在持有锁时调用，参数非空，返回下标，小于0表示不取；队列为空时返回nil
+func (l *idleList) pick(choose func(conns []*ThriftConn) int) *ThriftConn {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.conns) == 0 {
+		return nil
+	}
+	i := choose(l.conns)
+	if i < 0 {
+		return nil
+	}
+	conn := l.conns[i]
+	l.removeAt(i)
+	if len(l.conns) > 0 {
+		// 还有空闲连接，把通知传给下一个等待者
+		l.notify()
+	}
+	return conn
+}
+
+// 取出全部满足 match 的连接，其余连接保持原顺序
+func (l *idleList) removeIf(match func(conn *ThriftConn) bool) []*ThriftConn {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	var removed []*ThriftConn
+	kept := l.conns[:0]
+	for _, conn := range l.conns {
+		if match(conn) {
+			removed = append(removed, conn)
+		} else {
+			kept = append(kept, conn)
+		}
+	}
+	for i := len(kept); i < len(l.conns); i++ {
+		l.conns[i] = nil
+	}
+	l.conns = kept
+	return removed
+}
+
+func (l *idleList) removeAt(i int) {
+	copy(l.conns[i:], l.conns[i+1:])
+	l.conns[len(l.conns)-1] = nil
+	l.conns = l.conns[:len(l.conns)-1]
+}
+
+// 在持有锁时按队列顺序遍历空闲连接，f 应尽快返回且不能再操作队列
+func (l *idleList) each(f func(conn *ThriftConn)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, conn := range l.conns {
+		f(conn)
+	}
+}
+
+func (l *idleList) len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.conns)
+}
+
+// 关闭队列并取出剩余的连接，之后 push 都返回 false
+func (l *idleList) close() []*ThriftConn {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.closed = true
+	conns := l.conns
+	l.conns = nil
+	return conns
+}
@@ -0,0 +1,114 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 空闲连接的快照
+type ConnInfo struct {
+	Endpoint		string		// 连接的端点
+	Origin			ConnOrigin	// 连接的来源
+	CreatedTime		time.Time	// 建立时间
+	UsedTime		time.Time	// 最近一次使用（归还）的时间
+	Age				time.Duration	// 自建立起的时长
+	IdleTime		time.Duration	// 自最近一次使用起的时长
+	Uses			int64		// 被借出的次数
+	BytesRead		int64		// 累计读取的字节数，开启字节计数时统计
+	BytesWritten	int64		// 累计写入的字节数，开启字节计数时统计
+}
+
+// 连接池内部状态的快照，比 Stats 更详细，用于排查卡住的连接池等问题
+type PoolState struct {
+	Stats			PoolStats	// 计数、熔断状态等
+	IdleConns		[]ConnInfo	// 各空闲连接，按队列顺序排列（队首最先被借出）
+	RecentEvents	[]PoolEvent	// 最近的事件，按时间先后排列，需用 WithEventLog 开启
+}
+
+// 保留最近 size 个事件供 State 展示，小于1时不保留（默认）
+// 与 WithEvents 相互独立：事件日志只保留最新的事件，不需要消费
+func WithEventLog(size int32) Option {
+	return func(t *ThriftPool) {
+		if size < 1 {
+			t.eventLog = nil
+		} else {
+			t.eventLog = &eventRing{events: make([]PoolEvent, size)}
+		}
+	}
+}
+
+// 事件的环形缓冲，写满后覆盖最旧的事件
+type eventRing struct {
+	mutex	sync.Mutex
+	events	[]PoolEvent
+	next	int		// 下一个写入的位置
+	full	bool	// 是否已写满一圈
+}
+
+func (r *eventRing) add(event PoolEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events[r.next] = event
+	r.next++
+	if r.next == len(r.events) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// 按时间先后返回事件的副本
+func (r *eventRing) snapshot() []PoolEvent {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.full {
+		return append([]PoolEvent(nil), r.events[:r.next]...)
+	}
+	events := make([]PoolEvent, 0, len(r.events))
+	events = append(events, r.events[r.next:]...)
+	return append(events, r.events[:r.next]...)
+}
+
+// 最近的事件，按时间先后排列；未开启事件日志时返回nil
+func (t *ThriftPool) RecentEvents() []PoolEvent {
+	if t.eventLog == nil {
+		return nil
+	}
+	return t.eventLog.snapshot()
+}
+
+// 各空闲连接的快照，按队列顺序排列
+// 快照在持有空闲队列的锁时生成，不会取出连接，并发的 Get 只会短暂等待锁
+func (t *ThriftPool) IdleConns() []ConnInfo {
+	var conns []ConnInfo
+	nowTime := t.now()
+	t.rangeIdle(func(conn *ThriftConn) {
+		conns = append(conns, ConnInfo{
+			Endpoint:     conn.GetEndpoint(),
+			Origin:       conn.GetOrigin(),
+			CreatedTime:  conn.createdTime,
+			UsedTime:     conn.usedTime,
+			Age:          nowTime.Sub(conn.createdTime),
+			IdleTime:     nowTime.Sub(conn.usedTime),
+			Uses:         conn.GetUseCount(),
+			BytesRead:    conn.GetBytesRead(),
+			BytesWritten: conn.GetBytesWritten(),
+		})
+	})
+	return conns
+}
+
+// 连接池内部状态的快照，各部分分别读取，彼此之间不保证严格一致
+func (t *ThriftPool) State() PoolState {
+	return PoolState{
+		Stats:        t.Stats(),
+		IdleConns:    t.IdleConns(),
+		RecentEvents: t.RecentEvents(),
+	}
+}
+
+// 连接被借出的次数
+func (t *ThriftConn) GetUseCount() int64 {
+	return atomic.LoadInt64(&t.uses)
+}
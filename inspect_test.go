@@ -0,0 +1,103 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestPoolState(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithEventLog(3))
+	defer pool.Close()
+
+	conn1, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	conn2, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn1)
+	conn1, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn1)
+	_ = pool.Put(conn2)
+
+	state := pool.State()
+	if state.Stats.Idle != 2 || len(state.IdleConns) != 2 {
+		t.Fatalf("unexpected idle conns:%d\n", len(state.IdleConns))
+	}
+	if state.IdleConns[0].Uses != 2 || state.IdleConns[1].Uses != 1 {
+		t.Errorf("unexpected use counts:%d, %d\n", state.IdleConns[0].Uses, state.IdleConns[1].Uses)
+	}
+	info := state.IdleConns[0]
+	if info.Endpoint != endpoint || info.Origin != OriginDialed || info.CreatedTime.IsZero() || info.Age < 0 {
+		t.Errorf("unexpected conn info:%+v\n", info)
+	}
+	if pool.GetIdle() != 2 {
+		t.Errorf("snapshot should keep idle conns in the pool, idle:%d\n", pool.GetIdle())
+	}
+
+	// 事件日志只保留最近 3 个事件
+	events := state.RecentEvents
+	if len(events) != 3 {
+		t.Fatalf("event log should keep 3 events, got %d\n", len(events))
+	}
+	if events[0].Type != EventBorrowed || events[1].Type != EventReturned || events[2].Type != EventReturned {
+		t.Errorf("unexpected recent events:%s, %s, %s\n", events[0].Type, events[1].Type, events[2].Type)
+	}
+	if events[2].Endpoint != endpoint || events[2].Time.Before(events[0].Time) {
+		t.Errorf("events should be stamped and ordered\n")
+	}
+}
+
+func TestPoolStateConcurrentGet(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	// 连接都已打开且空闲，快照期间 Get 必须取到空闲连接，不能因队列被取空而报满
+	pool := NewThriftPool(endpoint, 1000, 60000, 2, 0)
+	defer pool.Close()
+	conn1, _ := pool.Get(context.Background())
+	conn2, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn1)
+	_ = pool.Put(conn2)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				if state := pool.State(); len(state.IdleConns) > 2 {
+					t.Errorf("unexpected idle conns:%d\n", len(state.IdleConns))
+				}
+			}
+		}
+	}()
+	for i := 0; i < 500; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Errorf("pool.Get error during snapshot:%s\n", err.Error())
+			break
+		}
+		_ = pool.Put(conn)
+	}
+	close(done)
+	wg.Wait()
+	if pool.GetOpen() != 2 || len(pool.IdleConns()) != 2 {
+		t.Errorf("open:%d, idle conns:%d, want 2, 2\n", pool.GetOpen(), len(pool.IdleConns()))
+	}
+}
@@ -0,0 +1,24 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+)
+
+// 连接池接口，ThriftPool 和 FailoverPool 都实现了该接口，
+// 使用方依赖该接口而不是具体类型，便于在测试中替换实现或组合多个连接池
+type Pool interface {
+	// 取一个连接，应和 Put 一对一成对调用
+	Get(ctx context.Context) (*ThriftConn, error)
+	// 归还 Get 取到的连接
+	Put(conn *ThriftConn) error
+	// 关闭连接池
+	Close() error
+	// 连接池的状态快照
+	Stats() PoolStats
+}
+
+var (
+	_ Pool = (*ThriftPool)(nil)
+	_ Pool = (*FailoverPool)(nil)
+)
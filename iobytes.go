@@ -0,0 +1,88 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync/atomic"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 统计每个连接读写的字节数，见 ThriftConn.GetBytesRead、GetBytesWritten 和 ThriftPool.GetIOBytes
+// 计数的 transport 位于 TransportWrapper 之外、缓冲和 framed 之内，统计的是实际读写socket的字节数（不含SASL等握手）。
+// 每次读写socket多两次原子加，开启缓冲时按缓冲块计，开销很小
+func WithByteCounting() Option {
+	return func(t *ThriftPool) {
+		t.CountBytes = true
+	}
+}
+
+// 连接累计读写超过 maxBytes 字节后在 Put 时关闭，用于在按连接固定后端的四层负载均衡下定期重建连接，
+// 使流量重新分布。设置后自动开启字节计数（见 WithByteCounting），小于1时不限制
+func WithMaxConnBytes(maxBytes int64) Option {
+	return func(t *ThriftPool) {
+		if maxBytes < 1 {
+			t.MaxConnBytes = 0
+		} else {
+			t.MaxConnBytes = maxBytes
+			t.CountBytes = true
+		}
+	}
+}
+
+// 统计读写字节数的 transport
+type countingTransport struct {
+	thrift.TTransport
+	conn	*ThriftConn
+}
+
+func (c *countingTransport) Read(p []byte) (int, error) {
+	n, err := c.TTransport.Read(p)
+	if n > 0 {
+		c.count(&c.conn.bytesRead, n)
+	}
+	return n, err
+}
+
+func (c *countingTransport) Write(p []byte) (int, error) {
+	n, err := c.TTransport.Write(p)
+	if n > 0 {
+		c.count(&c.conn.bytesWritten, n)
+	}
+	return n, err
+}
+
+// 累加连接和所属连接池的字节数，Release 之后、Adopt 之前连接不属于任何连接池，只累加连接的
+func (c *countingTransport) count(connBytes *int64, n int) {
+	atomic.AddInt64(connBytes, int64(n))
+	if pool := c.conn.pool; pool != nil {
+		atomic.AddInt64(&pool.ioBytes, int64(n))
+	}
+}
+
+// 开启字节计数时为连接套上计数的 transport，位于缓冲之内
+func (t *ThriftPool) wrapCounting(conn *ThriftConn) {
+	if !t.CountBytes {
+		return
+	}
+	conn.transport = &countingTransport{TTransport: conn.transport, conn: conn}
+}
+
+// 连接累计读取的字节数，未开启字节计数时为0
+func (t *ThriftConn) GetBytesRead() int64 {
+	return atomic.LoadInt64(&t.bytesRead)
+}
+
+// 连接累计写入的字节数，未开启字节计数时为0
+func (t *ThriftConn) GetBytesWritten() int64 {
+	return atomic.LoadInt64(&t.bytesWritten)
+}
+
+// 所有连接累计读写的字节数，包括已关闭的连接，未开启字节计数时为0
+func (t *ThriftPool) GetIOBytes() int64 {
+	return atomic.LoadInt64(&t.ioBytes)
+}
+
+// 连接累计读写是否已超过 MaxConnBytes
+func (t *ThriftPool) isOverBytes(conn *ThriftConn) bool {
+	return t.MaxConnBytes > 0 && conn.GetBytesRead()+conn.GetBytesWritten() > t.MaxConnBytes
+}
@@ -0,0 +1,71 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tianxingpan/thriftpool/example/echo"
+	"github.com/tianxingpan/thriftpool/testutil"
+)
+
+func TestMaxConnBytes(t *testing.T) {
+	endpoint, stop := testutil.StartEchoServer(t)
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithFramedTransport(0), WithMaxConnBytes(200))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if _, err := echo.NewEchoClientFactory(conn.ClientFactory()).Echo(&echo.EchoReq{Msg: "hello"}); err != nil {
+		t.Fatalf("Echo error:%s\n", err.Error())
+	}
+	read, written := conn.GetBytesRead(), conn.GetBytesWritten()
+	if read == 0 || written == 0 || pool.GetIOBytes() != read+written {
+		t.Errorf("bytes should be counted, read:%d written:%d pool:%d\n", read, written, pool.GetIOBytes())
+	}
+	if read+written > 200 {
+		t.Fatalf("a single echo should stay under the budget, used %d bytes\n", read+written)
+	}
+	_ = pool.Put(conn)
+	if pool.GetIdle() != 1 {
+		t.Errorf("conn under the byte budget should be kept, idle:%d\n", pool.GetIdle())
+	}
+
+	// 超过字节预算后 Put 时关闭
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	for conn.GetBytesRead()+conn.GetBytesWritten() <= 200 {
+		if _, err := echo.NewEchoClientFactory(conn.ClientFactory()).Echo(&echo.EchoReq{Msg: "hello"}); err != nil {
+			t.Fatalf("Echo error:%s\n", err.Error())
+		}
+	}
+	_ = pool.Put(conn)
+	stats := pool.Stats()
+	if stats.EvictedBytes != 1 || pool.GetOpen() != 0 || stats.IOBytes <= 200 {
+		t.Errorf("conn over the byte budget should be evicted, got %+v\n", stats)
+	}
+}
+
+func TestByteCountingDisabled(t *testing.T) {
+	endpoint, stop := testutil.StartEchoServer(t)
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithFramedTransport(0))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if _, err := echo.NewEchoClientFactory(conn.ClientFactory()).Echo(&echo.EchoReq{Msg: "hello"}); err != nil {
+		t.Fatalf("Echo error:%s\n", err.Error())
+	}
+	if conn.GetBytesRead() != 0 || pool.GetIOBytes() != 0 {
+		t.Errorf("bytes should not be counted by default\n")
+	}
+	_ = pool.Put(conn)
+}
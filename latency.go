@@ -0,0 +1,64 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	latencyBase		= 100 * time.Microsecond	// 第一个桶的上界
+	latencyBuckets	= 22						// 第 i 个桶的上界为 latencyBase*2^i，最后一个桶约为3.5分钟
+)
+
+// 延迟直方图，按指数划分桶，每个桶一个原子计数，记录和读取都无锁、无内存分配。
+// 分位数按所在桶的上界估算，误差不超过一倍
+type latencyHistogram struct {
+	buckets	[latencyBuckets]int64
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	i := 0
+	if d > 0 {
+		i = bits.Len64(uint64(d / latencyBase))
+	}
+	if i >= latencyBuckets {
+		i = latencyBuckets - 1
+	}
+	atomic.AddInt64(&h.buckets[i], 1)
+}
+
+// 分位数，p 取值 (0, 1]，没有记录时返回0
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	var counts [latencyBuckets]int64
+	var total int64
+	for i := range h.buckets {
+		counts[i] = atomic.LoadInt64(&h.buckets[i])
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int64(p*float64(total) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var sum int64
+	for i, count := range counts {
+		sum += count
+		if sum >= rank {
+			return latencyBase << uint(i)
+		}
+	}
+	return latencyBase << uint(latencyBuckets-1)
+}
+
+// 记录连接从借出到归还的持有时长，反映调用耗时加上业务在借出期间的处理时间
+func (t *ThriftPool) recordHold(conn *ThriftConn) {
+	if conn.borrowedTime == 0 {
+		return
+	}
+	t.holdLatency.record(time.Duration(t.now().UnixNano() - conn.borrowedTime))
+	conn.borrowedTime = 0
+}
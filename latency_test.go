@@ -0,0 +1,87 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	if h.percentile(0.5) != 0 {
+		t.Errorf("empty histogram should report 0\n")
+	}
+	for i := 0; i < 90; i++ {
+		h.record(150 * time.Microsecond)
+	}
+	for i := 0; i < 9; i++ {
+		h.record(3 * time.Millisecond)
+	}
+	h.record(time.Hour)
+
+	if p50 := h.percentile(0.50); p50 != 200*time.Microsecond {
+		t.Errorf("p50 should be 200us, got %s\n", p50)
+	}
+	if p95 := h.percentile(0.95); p95 != 3200*time.Microsecond {
+		t.Errorf("p95 should be 3.2ms, got %s\n", p95)
+	}
+	if p99 := h.percentile(0.99); p99 != 3200*time.Microsecond {
+		t.Errorf("p99 should be 3.2ms, got %s\n", p99)
+	}
+	if max := h.percentile(1); max != latencyBase<<(latencyBuckets-1) {
+		t.Errorf("overflow should land in the last bucket, got %s\n", max)
+	}
+}
+
+func TestDialLatencyStats(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1)
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+	stats := pool.Stats()
+	if stats.DialLatencyP50 <= 0 || stats.DialLatencyP99 < stats.DialLatencyP50 {
+		t.Errorf("unexpected dial latency, p50:%s, p99:%s\n", stats.DialLatencyP50, stats.DialLatencyP99)
+	}
+}
+
+func TestHoldTimeStats(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	clock := newFakeClock()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1, withClock(clock.Now))
+	defer pool.Close()
+
+	for i := 0; i < 20; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		if i == 19 {
+			clock.Advance(time.Second)
+		} else {
+			clock.Advance(3 * time.Millisecond)
+		}
+		_ = pool.Put(conn)
+	}
+	// 未归还的连接不计入
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	defer pool.Put(conn)
+
+	stats := pool.Stats()
+	if stats.HoldTimeP50 != 3200*time.Microsecond || stats.HoldTimeP95 != 3200*time.Microsecond {
+		t.Errorf("unexpected hold time, p50:%s, p95:%s\n", stats.HoldTimeP50, stats.HoldTimeP95)
+	}
+	if stats.HoldTimeP99 < time.Second {
+		t.Errorf("slow borrow should show in p99, got %s\n", stats.HoldTimeP99)
+	}
+}
@@ -0,0 +1,29 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"time"
+)
+
+// 连接最长存活时长，参见 SetMaxConnLifetime
+func WithMaxConnLifetime(maxConnLifetime int32) Option {
+	return func(t *ThriftPool) {
+		t.SetMaxConnLifetime(maxConnLifetime)
+	}
+}
+
+// 设置连接最长存活时长（单位：毫秒），小于1表示不限制（默认）
+// 自连接创建时算起，超过该时长的连接在 Put 时关闭而不放回队列，优先于 MaxIdle 和 IdleTimeout 判断，
+// 用于让连接定期重建，以便服务端扩容或负载均衡变化后重新分布
+func (t *ThriftPool) SetMaxConnLifetime(maxConnLifetime int32) {
+	if maxConnLifetime < 1 {
+		t.MaxConnLifetime = 0
+	} else {
+		t.MaxConnLifetime = time.Duration(maxConnLifetime) * time.Millisecond
+	}
+}
+
+// 连接是否已超过最长存活时长
+func (t *ThriftPool) isExpired(conn *ThriftConn) bool {
+	return t.MaxConnLifetime > 0 && t.now().Sub(conn.createdTime) > t.MaxConnLifetime
+}
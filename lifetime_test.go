@@ -0,0 +1,89 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// 借出 n 个连接
+func getConns(t *testing.T, pool *ThriftPool, n int) []*ThriftConn {
+	conns := make([]*ThriftConn, 0, n)
+	for i := 0; i < n; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+func TestEvictOrder(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	// 同时超过最长存活时间和闲置超时，按最长存活时间关闭
+	clock := newFakeClock()
+	pool := NewThriftPool(endpoint, 1000, 10000, 10, 0, withClock(clock.Now), WithMaxConnLifetime(5000))
+	conns := getConns(t, pool, 1)
+	clock.Advance(11 * time.Second)
+	_ = pool.Put(conns[0])
+	stats := pool.Stats()
+	if stats.EvictedLifetime != 1 || stats.EvictedIdle != 0 || pool.GetOpen() != 0 {
+		t.Errorf("expired and idle conn should be evicted for lifetime, got %+v\n", stats)
+	}
+	pool.Close()
+
+	// 超过最长存活时间的连接在闲置超时之内同样关闭，且优先于空闲连接数上限
+	clock = newFakeClock()
+	pool = NewThriftPool(endpoint, 1000, 60000, 10, 0, withClock(clock.Now),
+		WithMaxConnLifetime(5000), WithMaxIdle(1))
+	conns = getConns(t, pool, 2)
+	_ = pool.Put(conns[0])
+	clock.Advance(6 * time.Second)
+	_ = pool.Put(conns[1])
+	stats = pool.Stats()
+	if stats.EvictedLifetime != 1 || stats.EvictedOverMax != 0 || pool.GetIdle() != 1 {
+		t.Errorf("expired conn over MaxIdle should be evicted for lifetime, got %+v\n", stats)
+	}
+	pool.Close()
+
+	// 同时超过空闲连接数上限和闲置超时，按空闲连接数上限关闭
+	clock = newFakeClock()
+	pool = NewThriftPool(endpoint, 1000, 10000, 10, 0, withClock(clock.Now), WithMaxIdle(1))
+	conns = getConns(t, pool, 2)
+	_ = pool.Put(conns[0])
+	clock.Advance(11 * time.Second)
+	_ = pool.Put(conns[1])
+	stats = pool.Stats()
+	if stats.EvictedOverMax != 1 || stats.EvictedIdle != 0 || pool.GetIdle() != 1 {
+		t.Errorf("idle conn over MaxIdle should be evicted for MaxIdle, got %+v\n", stats)
+	}
+	pool.Close()
+
+	// 不可用的连接优先按不可用关闭
+	clock = newFakeClock()
+	pool = NewThriftPool(endpoint, 1000, 10000, 10, 0, withClock(clock.Now), WithMaxConnLifetime(5000))
+	conns = getConns(t, pool, 1)
+	clock.Advance(11 * time.Second)
+	_ = conns[0].CloseTransport()
+	_ = pool.Put(conns[0])
+	stats = pool.Stats()
+	if stats.EvictedBroken != 1 || stats.EvictedLifetime != 0 {
+		t.Errorf("unusable conn should be evicted as broken, got %+v\n", stats)
+	}
+	pool.Close()
+
+	// 未超过最长存活时间的连接正常放回
+	clock = newFakeClock()
+	pool = NewThriftPool(endpoint, 1000, 60000, 10, 0, withClock(clock.Now), WithMaxConnLifetime(5000))
+	conns = getConns(t, pool, 1)
+	clock.Advance(time.Second)
+	_ = pool.Put(conns[0])
+	if pool.GetIdle() != 1 {
+		t.Errorf("young conn should be kept, idle:%d\n", pool.GetIdle())
+	}
+	pool.Close()
+}
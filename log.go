@@ -0,0 +1,54 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// 日志接口，*log.Logger 即满足该接口
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// 默认输出到标准输出
+var defaultLogger Logger = log.New(os.Stdout, "", log.LstdFlags)
+
+// 连接池名称，会加在该连接池返回的错误和输出的日志前面，便于区分同一进程中的多个连接池
+func WithName(name string) Option {
+	return func(t *ThriftPool) {
+		t.Name = name
+	}
+}
+
+// 日志输出，为nil时使用默认的日志输出
+func WithLogger(logger Logger) Option {
+	return func(t *ThriftPool) {
+		if logger == nil {
+			t.logger = defaultLogger
+		} else {
+			t.logger = logger
+		}
+	}
+}
+
+func (t *ThriftPool) GetName() string {
+	return t.Name
+}
+
+// 输出日志，设置了名称时加上名称前缀
+func (t *ThriftPool) logf(format string, v ...interface{}) {
+	if t.Name != "" {
+		format = "[" + t.Name + "] " + format
+	}
+	t.logger.Printf(format, v...)
+}
+
+// 设置了名称时给错误加上名称前缀，原错误仍可通过 errors.Is 和 errors.As 判断
+func (t *ThriftPool) nameErr(err error) error {
+	if err == nil || t.Name == "" {
+		return err
+	}
+	return fmt.Errorf("thriftpool %s: %w", t.Name, err)
+}
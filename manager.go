@@ -0,0 +1,94 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync"
+	"time"
+)
+
+// thrift连接池管理器，按端点管理多个连接池
+// 连接池在首次访问端点时按管理器的参数创建
+type PoolManager struct {
+	DialTimeout			int32					// 拨号超时，单位毫秒
+	IdleTimeout			int32					// 空闲连接超时，单位毫秒
+	MaxSize				int32					// 每个连接池的最大连接数
+	InitSize			int32					// 每个连接池的初始连接数
+	BreakerThreshold	int32					// 每个连接池的熔断阈值，为0时不启用熔断
+	mutex				sync.Mutex
+	pools				map[string]*ThriftPool	// 端点 -> 连接池
+}
+
+// 创建连接池管理器，参数含义同 NewThriftPool
+// 注意在使用完后，应调用管理器的成员函数 Close 关闭所有连接池
+func NewPoolManager(dialTimeout, idleTimeout, maxSize, initSize int32) *PoolManager {
+	poolManager := new(PoolManager)
+	poolManager.DialTimeout = dialTimeout
+	poolManager.IdleTimeout = idleTimeout
+	poolManager.MaxSize = maxSize
+	poolManager.InitSize = initSize
+	poolManager.pools = make(map[string]*ThriftPool)
+	return poolManager
+}
+
+// 取端点对应的连接池，不存在时创建，总是返回非nil值
+// 查找和创建在同一把锁内完成，多个协程同时首次访问同一端点时也只创建一个连接池；
+// NewThriftPool 不拨号，持锁创建的开销很小
+func (m *PoolManager) GetPool(endpoint string) *ThriftPool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	pool, ok := m.pools[endpoint]
+	if !ok {
+		pool = NewThriftPool(endpoint, m.DialTimeout, m.IdleTimeout, m.MaxSize, m.InitSize)
+		pool.SetBreakerThreshold(m.BreakerThreshold)
+		m.pools[endpoint] = pool
+	}
+	return pool
+}
+
+// 已创建连接池的端点列表
+func (m *PoolManager) GetEndpoints() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	endpoints := make([]string, 0, len(m.pools))
+	for endpoint := range m.pools {
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+// 关闭并移除 maxIdle 内没有使用过（见 ThriftPool.IsActive，心跳、MinIdle 等后台协程取放空闲连接不算使用）的连接池，返回被移除的端点，
+// 用于服务发现变化后清理不再访问的端点。
+// 判断和移除在锁内完成，与 GetPool 互斥；移除后再访问该端点会创建新的连接池，
+// 但此前已通过 GetPool 取得的连接池会被关闭，Get 返回 ErrPoolClosed
+func (m *PoolManager) ReapIdlePools(maxIdle time.Duration) []string {
+	m.mutex.Lock()
+	var endpoints []string
+	var pools []*ThriftPool
+	for endpoint, pool := range m.pools {
+		if !pool.IsActive(maxIdle) {
+			endpoints = append(endpoints, endpoint)
+			pools = append(pools, pool)
+			delete(m.pools, endpoint)
+		}
+	}
+	m.mutex.Unlock()
+
+	for _, pool := range pools {
+		pool.Close()
+	}
+	return endpoints
+}
+
+// 关闭并移除所有连接池
+func (m *PoolManager) Close() {
+	m.mutex.Lock()
+	pools := m.pools
+	m.pools = make(map[string]*ThriftPool)
+	m.mutex.Unlock()
+
+	for _, pool := range pools {
+		pool.Close()
+	}
+}
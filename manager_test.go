@@ -0,0 +1,114 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReapIdlePools(t *testing.T) {
+	endpoint1, stop1 := startTestServer(t, "127.0.0.1:0")
+	defer stop1()
+	endpoint2, stop2 := startTestServer(t, "127.0.0.1:0")
+	defer stop2()
+	manager := NewPoolManager(100, 60000, 10, 1)
+	defer manager.Close()
+
+	busy := manager.GetPool(endpoint1)
+	conn, err := busy.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	idle := manager.GetPool(endpoint2)
+
+	if reaped := manager.ReapIdlePools(time.Hour); len(reaped) != 0 {
+		t.Errorf("recently used pools should not be reaped, got %v\n", reaped)
+	}
+	reaped := manager.ReapIdlePools(0)
+	if len(reaped) != 1 || reaped[0] != endpoint2 {
+		t.Errorf("only the idle pool should be reaped, got %v\n", reaped)
+	}
+	select {
+	case <-idle.Done():
+	default:
+		t.Errorf("reaped pool should be closed\n")
+	}
+	if len(manager.GetEndpoints()) != 1 {
+		t.Errorf("reaped pool should be removed, endpoints:%v\n", manager.GetEndpoints())
+	}
+	if manager.GetPool(endpoint2) == idle {
+		t.Errorf("GetPool after reaping should create a new pool\n")
+	}
+	_ = busy.Put(conn)
+}
+
+func TestReapHeartbeatPool(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	manager := NewPoolManager(100, 60000, 10, 0)
+	defer manager.Close()
+
+	// 只有心跳在取放空闲连接的连接池同样应被回收
+	clock := newFakeClock()
+	heartbeat := func(ctx context.Context, conn *ThriftConn) error {
+		return nil
+	}
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 0, withClock(clock.Now), WithHeartbeat(heartbeat, 10, 100))
+	manager.mutex.Lock()
+	manager.pools[endpoint] = pool
+	manager.mutex.Unlock()
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+
+	clock.Advance(10 * time.Second)
+	time.Sleep(50 * time.Millisecond)
+	var reaped []string
+	deadline := time.Now().Add(time.Second)
+	for len(reaped) == 0 && time.Now().Before(deadline) {
+		// 心跳期间连接短暂借出，稍后重试
+		reaped = manager.ReapIdlePools(5 * time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	if len(reaped) != 1 || reaped[0] != endpoint {
+		t.Errorf("pool kept warm only by heartbeats should be reaped, got %v\n", reaped)
+	}
+	select {
+	case <-pool.Done():
+	default:
+		t.Errorf("reaped pool should be closed\n")
+	}
+}
+
+func TestGetPoolConcurrent(t *testing.T) {
+	manager := NewPoolManager(100, 60000, 10, 0)
+	defer manager.Close()
+
+	const goroutines = 100
+	pools := make([]*ThriftPool, goroutines)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			pools[i] = manager.GetPool("127.0.0.1:9898")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i, pool := range pools {
+		if pool != pools[0] {
+			t.Fatalf("goroutine %d got a different pool for the same endpoint\n", i)
+		}
+	}
+	if endpoints := manager.GetEndpoints(); len(endpoints) != 1 {
+		t.Errorf("manager should hold a single pool, got %v\n", endpoints)
+	}
+}
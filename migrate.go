@@ -0,0 +1,79 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Release 或 Put 的连接不是从该连接池借出的
+var ErrForeignConn = errors.New("thriftpool connection belongs to another pool")
+
+// Adopt 的连接仍属于某个连接池，需先 Release
+var ErrConnNotReleased = errors.New("thriftpool connection not released")
+
+// 把借出的连接移出连接池而不关闭，之后连接不再属于任何连接池，应交给另一个连接池 Adopt 或由调用方 Close。
+// 连接占用的已用连接数、连接名额和缓冲预算都交还给该连接池，相当于 Put 后连接被关闭，但socket保持打开。
+// 用于 Rebind 或 PoolManager 调整配置时把健康的连接迁移到新连接池，避免关闭后重新拨号。
+// 已被看门狗回收的连接返回 ErrConnReclaimed，未借出的连接返回 ErrNotBorrowed
+func (t *ThriftPool) Release(conn *ThriftConn) error {
+	if conn.pool != t {
+		return t.nameErr(ErrForeignConn)
+	}
+	if !t.stopWatchdog(conn) {
+		return t.nameErr(ErrConnReclaimed)
+	}
+	if !t.clearInUse(conn) {
+		return t.nameErr(ErrNotBorrowed)
+	}
+	t.subEndpointUsed(conn)
+	if atomic.CompareAndSwapInt32(&conn.slot, 1, 0) {
+		if conn.overflow {
+			t.releaseOverflow()
+		} else {
+			t.releaseSlot()
+		}
+		t.releaseBuffer(conn)
+	}
+	t.subUsed()
+	conn.pool = nil
+	conn.overflow = false
+	conn.tag = ""
+	return nil
+}
+
+// 接管 Release 移出的连接，放入空闲连接队列，之后和该连接池自己拨号的连接一样使用和回收。
+// 连接的端点须是连接池当前的端点或 SetTargets 设置的目标之一，否则返回 ErrWrongEndpoint；
+// 连接名额已满时返回错误。以上出错时连接仍归调用方，应由调用方 Close。
+// 接管后连接按该连接池的规则处理，如空闲连接已达上限时关闭。
+// 注意连接保留原连接池的 transport 包装（缓冲、framed 等），两个连接池的相关配置应当一致
+func (t *ThriftPool) Adopt(conn *ThriftConn) error {
+	if conn.pool != nil {
+		return t.nameErr(ErrConnNotReleased)
+	}
+	if !conn.IsUsable() {
+		return t.nameErr(fmt.Errorf("thriftpool adopt unusable connection to %s", conn.GetEndpoint()))
+	}
+	bound := t.loadBinding()
+	if bound.targets != nil && !bound.targets.members[conn.Endpoint] ||
+		bound.targets == nil && conn.Endpoint != bound.endpoint {
+		return t.nameErr(fmt.Errorf("%w: conn endpoint %s, pool endpoint %s",
+			ErrWrongEndpoint, conn.GetEndpoint(), bound.endpoint))
+	}
+	if !t.tryReserveSlot() {
+		return t.nameErr(t.poolError(KindFull, t.GetUsed()))
+	}
+	conn.pool = t
+	conn.slot = 1
+	conn.generation = bound.generation
+	if conn.bufferBytes > 0 {
+		atomic.AddInt64(&t.bufferedBytes, conn.bufferBytes)
+	}
+	t.connCreated(conn, OriginAdopted)
+	// 按借出处理后归还，由 put 决定放入队列还是关闭
+	t.addUsed()
+	t.addEndpointUsed(conn)
+	t.touch()
+	return t.nameErr(t.put(conn, true))
+}
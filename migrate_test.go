@@ -0,0 +1,82 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReleaseAdopt(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	poolA := NewThriftPool(endpoint, 1000, 60000, 10, 0)
+	defer poolA.Close()
+	poolB := NewThriftPool(endpoint, 1000, 60000, 10, 0)
+	defer poolB.Close()
+
+	conn, err := poolA.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get error:%s\n", err.Error())
+	}
+	if err := poolB.Release(conn); !errors.Is(err, ErrForeignConn) {
+		t.Errorf("release by another pool should fail with ErrForeignConn, got %v\n", err)
+	}
+	if err := poolB.Adopt(conn); !errors.Is(err, ErrConnNotReleased) {
+		t.Errorf("adopt before release should fail with ErrConnNotReleased, got %v\n", err)
+	}
+
+	if err := poolA.Release(conn); err != nil {
+		t.Fatalf("release error:%s\n", err.Error())
+	}
+	if poolA.GetUsed() != 0 || poolA.GetOpen() != 0 || poolA.GetIdle() != 0 || !conn.IsUsable() {
+		t.Errorf("released conn should leave pool A open, used:%d, open:%d\n", poolA.GetUsed(), poolA.GetOpen())
+	}
+	if err := poolB.Adopt(conn); err != nil {
+		t.Fatalf("adopt error:%s\n", err.Error())
+	}
+	if poolB.GetUsed() != 0 || poolB.GetOpen() != 1 || poolB.GetIdle() != 1 {
+		t.Errorf("adopted conn should be idle in pool B, used:%d, open:%d, idle:%d\n",
+			poolB.GetUsed(), poolB.GetOpen(), poolB.GetIdle())
+	}
+
+	migrated, err := poolB.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get from pool B error:%s\n", err.Error())
+	}
+	if migrated != conn {
+		t.Errorf("pool B should hand out the adopted conn\n")
+	}
+	if err := poolB.Put(migrated); err != nil {
+		t.Errorf("put to pool B error:%s\n", err.Error())
+	}
+	if poolB.GetIdle() != 1 || poolB.GetEndpointUsed(endpoint) != 0 {
+		t.Errorf("adopted conn should be pooled in pool B, idle:%d\n", poolB.GetIdle())
+	}
+}
+
+func TestAdoptWrongEndpoint(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	other, stopOther := startTestServer(t, "127.0.0.1:0")
+	defer stopOther()
+
+	poolA := NewThriftPool(endpoint, 1000, 60000, 10, 0)
+	defer poolA.Close()
+	poolB := NewThriftPool(other, 1000, 60000, 10, 0)
+	defer poolB.Close()
+
+	conn, err := poolA.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get error:%s\n", err.Error())
+	}
+	_ = poolA.Release(conn)
+	if err := poolB.Adopt(conn); !errors.Is(err, ErrWrongEndpoint) {
+		t.Errorf("adopt to a pool of another endpoint should fail with ErrWrongEndpoint, got %v\n", err)
+	}
+	if poolB.GetOpen() != 0 {
+		t.Errorf("failed adopt should not take a slot, open:%d\n", poolB.GetOpen())
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,114 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// 保持至少 minIdle 个健康的空闲连接，用于后端滚动重启期间连接成批失效时，业务仍能少遇到冷拨号：
+// 每隔 interval 毫秒（小于1时为1秒）探测一遍空闲连接，关闭探测失败的连接，再把空闲连接补足到 minIdle 个。
+// 探测使用 healthCheck 的健康检查函数，未设置 WithValidator 或 WithHeartbeat 时只关闭已不可用的连接。
+// 每次补连前随机等待 [0, jitter) 毫秒，错开替换连接的拨号，避免同时冲击刚重启的实例；jitter 小于1时不等待。
+// minIdle 小于1时不开启（默认），超过 MaxSize 或 MaxIdle 时被调低；补连受熔断、暂停和拨号速率限制
+func WithMinIdle(minIdle, interval, jitter int32) Option {
+	return func(t *ThriftPool) {
+		if minIdle < 1 {
+			t.MinIdle = 0
+		} else {
+			t.MinIdle = minIdle
+		}
+		if interval < 1 {
+			t.MinIdleInterval = time.Second
+		} else {
+			t.MinIdleInterval = time.Duration(interval) * time.Millisecond
+		}
+		if jitter < 1 {
+			t.MinIdleJitter = 0
+		} else {
+			t.MinIdleJitter = time.Duration(jitter) * time.Millisecond
+		}
+	}
+}
+
+func (t *ThriftPool) GetMinIdle() int32 {
+	return t.MinIdle
+}
+
+// 最近一轮保持 MinIdle 时补连的连接数，即最近一个 MinIdleInterval 内替换的连接数
+func (t *ThriftPool) GetReplacements() int32 {
+	return atomic.LoadInt32(&t.replacements)
+}
+
+// 定期探测空闲连接并补足 MinIdle，连接池关闭时退出
+func (t *ThriftPool) maintainMinIdle() {
+	ticker := time.NewTicker(t.MinIdleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		t.probeIdle()
+		atomic.StoreInt32(&t.replacements, t.topUpIdle())
+	}
+}
+
+// 逐个取出空闲连接做健康检查，失败的连接被关闭，其余放回
+func (t *ThriftPool) probeIdle() {
+	check, _ := t.healthCheck()
+	idleSize := t.GetIdle()
+	for i := 0; i < int(idleSize); i++ {
+		conn, _ := t.get(t.ctx, true)
+		if conn == nil {
+			break
+		}
+		if !conn.IsUsable() || (check != nil && t.validate(t.ctx, conn) != nil) {
+			t.discard(conn)
+			t.countEvicted(evictBroken)
+			t.subUsed()
+			t.emit(EventEvicted, nil)
+			continue
+		}
+		if err := t.put(conn, true); err != nil {
+			t.logf("put back after probe failed:%s", err.Error())
+		}
+	}
+}
+
+// 拨号把空闲连接补足到 MinIdle，返回补连的连接数，拨号失败时停止，留到下一轮
+func (t *ThriftPool) topUpIdle() int32 {
+	var dialed int32
+	for t.GetIdle() < t.MinIdle {
+		if t.MinIdleJitter > 0 {
+			timer := time.NewTimer(time.Duration(rand.Int63n(int64(t.MinIdleJitter))))
+			select {
+			case <-t.ctx.Done():
+				timer.Stop()
+				return dialed
+			case <-timer.C:
+			}
+		}
+		if t.ctx.Err() != nil || t.IsPaused() || !t.allowDial() {
+			break
+		}
+		if err := t.waitDialToken(t.ctx); err != nil {
+			break
+		}
+		conn, err := t.dial(t.ctx)
+		if err != nil {
+			t.logf("keep min idle %d failed:%s", t.MinIdle, err.Error())
+			break
+		}
+		if !t.tryAddIdle() {
+			t.discard(conn)
+			break
+		}
+		t.restoreIdle(conn)
+		dialed++
+	}
+	return dialed
+}
@@ -0,0 +1,74 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMinIdleTopUp(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 0, WithMinIdle(3, 20, 5))
+	defer pool.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for pool.GetIdle() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pool.GetIdle() != 3 {
+		t.Errorf("pool should keep 3 idle connections, idle:%d\n", pool.GetIdle())
+	}
+	if pool.Stats().MinIdle != 3 {
+		t.Errorf("stats should report min idle 3, got %d\n", pool.Stats().MinIdle)
+	}
+}
+
+func TestMinIdleReplace(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	// 间隔足够长，由测试直接驱动每一轮
+	v := &testValidator{broken: make(map[*ThriftConn]bool)}
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 4,
+		WithMinIdle(4, 60000, 1), WithValidator(v.validate, 100))
+	defer pool.Close()
+	if err := pool.WarmUp(context.Background()); err != nil {
+		t.Fatalf("pool.WarmUp error:%s\n", err.Error())
+	}
+
+	// 模拟后端重启：一个连接已被关闭，两个连接探测失败
+	var i int
+	pool.rangeIdle(func(conn *ThriftConn) {
+		switch i {
+		case 0:
+			_ = conn.CloseTransport()
+		case 1, 2:
+			v.broken[conn] = true
+		}
+		i++
+	})
+	pool.probeIdle()
+	if pool.GetIdle() != 1 {
+		t.Errorf("probe should close 3 dead connections, idle:%d\n", pool.GetIdle())
+	}
+	if n := pool.topUpIdle(); n != 3 {
+		t.Errorf("top up should dial 3 replacements, got %d\n", n)
+	}
+	if pool.GetIdle() != 4 || pool.GetOpen() != 4 {
+		t.Errorf("pool should be back to 4 idle connections, idle:%d open:%d\n", pool.GetIdle(), pool.GetOpen())
+	}
+	if pool.Stats().EvictedBroken != 3 {
+		t.Errorf("dead connections should count as broken, got %d\n", pool.Stats().EvictedBroken)
+	}
+}
+
+func TestMinIdleAdjust(t *testing.T) {
+	pool := NewThriftPool("127.0.0.1:1", 100, 60000, 10, 0, WithMaxIdle(4), WithMinIdle(8, 60000, 0))
+	defer pool.Close()
+	if pool.GetMinIdle() != 4 {
+		t.Errorf("min idle should be lowered to max idle 4, got %d\n", pool.GetMinIdle())
+	}
+}
@@ -0,0 +1,212 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"time"
+)
+
+// 连接池的可选配置，传给 NewThriftPool 或 Clone
+type Option func(t *ThriftPool)
+
+// 拨号超时，单位毫秒，小于1时忽略
+func WithDialTimeout(dialTimeout int32) Option {
+	return func(t *ThriftPool) {
+		if dialTimeout > 0 {
+			t.DialTimeout = time.Duration(dialTimeout) * time.Millisecond
+		}
+	}
+}
+
+// 空闲连接超时，单位毫秒，小于1时忽略
+func WithIdleTimeout(idleTimeout int32) Option {
+	return func(t *ThriftPool) {
+		if idleTimeout > 0 {
+			t.IdleTimeout = time.Duration(idleTimeout) * time.Millisecond
+		}
+	}
+}
+
+// 最大连接数，小于1时忽略，初始连接数超过最大连接数时会被调小
+func WithMaxSize(maxSize int32) Option {
+	return func(t *ThriftPool) {
+		if maxSize > 0 {
+			t.MaxSize = maxSize
+		}
+	}
+}
+
+// 初始连接数，小于0时忽略，为0时闲置超时的空闲连接全部回收
+func WithInitSize(initSize int32) Option {
+	return func(t *ThriftPool) {
+		if initSize >= 0 {
+			t.InitSize = initSize
+		}
+	}
+}
+
+// 最大空闲连接数，超出的连接在 Put 时直接关闭
+// 空闲连接队列随放回的连接按需增长，不按 MaxSize 或 MaxIdle 预分配，MaxSize 很大的连接池也不会预先占用内存；
+// MaxIdle 只限制同时空闲的连接数，未设置时上限为 MaxSize。
+// 小于1或不小于 MaxSize 时不单独限制，小于 InitSize 时按 InitSize 处理
+func WithMaxIdle(maxIdle int32) Option {
+	return func(t *ThriftPool) {
+		if maxIdle > 0 {
+			t.MaxIdle = maxIdle
+		} else {
+			t.MaxIdle = 0
+		}
+	}
+}
+
+// 熔断阈值和冷却时长，参见 SetBreakerThreshold 和 SetBreakerCooldown
+func WithBreaker(threshold, cooldown int32) Option {
+	return func(t *ThriftPool) {
+		t.SetBreakerThreshold(threshold)
+		t.SetBreakerCooldown(cooldown)
+	}
+}
+
+// 连接最长借出时长，参见 SetMaxBorrowTime
+func WithMaxBorrowTime(maxBorrowTime int32, onTimeout func(conn *ThriftConn)) Option {
+	return func(t *ThriftPool) {
+		t.SetMaxBorrowTime(maxBorrowTime, onTimeout)
+	}
+}
+
+// socket包装函数，参见 SetTransportWrapper
+func WithTransportWrapper(wrapper func(socket *thrift.TSocket) (thrift.TTransport, error)) Option {
+	return func(t *ThriftPool) {
+		t.SetTransportWrapper(wrapper)
+	}
+}
+
+// 新连接的协商函数，参见 SetOnConnReady
+func WithOnConnReady(onConnReady func(ctx context.Context, conn *ThriftConn) error) Option {
+	return func(t *ThriftPool) {
+		t.SetOnConnReady(onConnReady)
+	}
+}
+
+// 连接池耗尽（已用连接数超过 MaxSize）时的回调，可用于降载或上报指标。
+// 回调在 Get 中同步执行，此时已用连接数尚未回退；返回非nil错误时 Get 返回该错误，
+// 返回nil时 Get 返回 ErrPoolExhausted
+func WithOnExhausted(onExhausted func(ctx context.Context) error) Option {
+	return func(t *ThriftPool) {
+		t.OnExhausted = onExhausted
+	}
+}
+
+// 连接池耗尽时是否返回带失败时连接池状态的 *PoolError，
+// 默认只返回预先分配的 ErrPoolExhausted，避免过载时每次失败都分配内存；开启后仍可用 errors.Is 判断
+func WithDetailedErrors(detailed bool) Option {
+	return func(t *ThriftPool) {
+		t.DetailedErrors = detailed
+	}
+}
+
+// 连接池耗尽时允许创建最多 maxOverflow 个临时连接，参见 SetOverflow
+func WithOverflow(maxOverflow int32) Option {
+	return func(t *ThriftPool) {
+		t.SetOverflow(maxOverflow)
+	}
+}
+
+// 以当前连接池的配置创建一个新的连接池，opts 用于覆盖部分配置
+// 新连接池有独立的连接队列、计数和后台协程，和当前连接池互不影响，同样需要单独 Close；
+// SetTargets 设置的目标一并复制，由 NewThriftPoolSRV 创建的连接池在新连接池上同样定期重新解析SRV记录
+func (t *ThriftPool) Clone(opts ...Option) *ThriftPool {
+	opts = append([]Option{t.copyConfig}, opts...)
+	p := NewThriftPool(t.GetEndpoint(), 0, 0, 0, 0, opts...)
+	if t.srvName != "" {
+		p.startSRVRefresh(t.srvName)
+	}
+	return p
+}
+
+// 复制配置，不包括连接和计数等运行状态
+func (t *ThriftPool) copyConfig(p *ThriftPool) {
+	if targets := t.loadBinding().targets; targets != nil {
+		p.binding.Store(binding{endpoint: p.Endpoint, targets: targets})
+	}
+	p.Name = t.Name
+	p.logger = t.logger
+	p.RetryClassifier = t.RetryClassifier
+	p.RetryBackoff = t.RetryBackoff
+	p.StartupPolicy = t.StartupPolicy
+	p.WarmUpMaxFailures = t.WarmUpMaxFailures
+	p.WarmUpMaxBackoff = t.WarmUpMaxBackoff
+	p.HeartbeatFunc = t.HeartbeatFunc
+	p.HeartbeatInterval = t.HeartbeatInterval
+	p.HeartbeatTimeout = t.HeartbeatTimeout
+	p.MinIdle = t.MinIdle
+	p.MinIdleInterval = t.MinIdleInterval
+	p.MinIdleJitter = t.MinIdleJitter
+	p.Validator = t.Validator
+	p.ValidateTimeout = t.ValidateTimeout
+	p.ValidateOnBorrow = t.ValidateOnBorrow
+	p.ValidateOnReturn = t.ValidateOnReturn
+	p.MaxConcurrentDials = t.MaxConcurrentDials
+	p.LocalAddr = t.LocalAddr
+	p.OnExhausted = t.OnExhausted
+	p.Debug = t.Debug
+	p.FramedTransport = t.FramedTransport
+	p.MaxFrameSize = t.MaxFrameSize
+	p.DetailedErrors = t.DetailedErrors
+	p.SRVRefreshInterval = t.SRVRefreshInterval
+	p.EndpointResolver = t.EndpointResolver
+	p.nowFunc = t.nowFunc
+	p.AllowOverflow = t.AllowOverflow
+	p.MaxOverflow = t.MaxOverflow
+	p.IdleStrategy = t.IdleStrategy
+	p.EvictionOrder = t.EvictionOrder
+	p.ReadBufferSize = t.ReadBufferSize
+	p.WriteBufferSize = t.WriteBufferSize
+	p.TrimPolicy = t.TrimPolicy
+	p.HistorySize = t.HistorySize
+	p.HistoryInterval = t.HistoryInterval
+	p.DialRateLimit = t.DialRateLimit
+	p.DialBurst = t.DialBurst
+	p.LenientSizes = t.LenientSizes
+	p.MaxProbes = t.MaxProbes
+	p.FDReserve = t.FDReserve
+	p.FDChecker = t.FDChecker
+	p.OnConnReady = t.OnConnReady
+	p.IdlePoolTTL = t.IdlePoolTTL
+	p.OnIdleClose = t.OnIdleClose
+	p.BaseContext = t.BaseContext
+	p.Registered = t.Registered
+	p.DialRetries = t.DialRetries
+	p.DialRetryBackoff = t.DialRetryBackoff
+	p.MaxDialElapsed = t.MaxDialElapsed
+	p.OnConnCreate = t.OnConnCreate
+	p.CountBytes = t.CountBytes
+	p.MaxConnBytes = t.MaxConnBytes
+	p.ZlibTransport = t.ZlibTransport
+	p.ZlibLevel = t.ZlibLevel
+	p.DialTimeout = t.DialTimeout
+	p.IdleTimeout = t.IdleTimeout
+	p.MaxSize = t.MaxSize
+	p.InitSize = t.InitSize
+	p.MaxIdle = t.MaxIdle
+	p.MaxBorrowTime = t.MaxBorrowTime
+	p.MaxConnLifetime = t.MaxConnLifetime
+	p.OnBorrowTimeout = t.OnBorrowTimeout
+	p.TransportWrapper = t.TransportWrapper
+	p.TransportFactory = t.TransportFactory
+	p.ProtocolFactory = t.ProtocolFactory
+	p.BufferSize = t.BufferSize
+	p.BufferBudget = t.BufferBudget
+	p.BreakerThreshold = t.GetBreakerThreshold()
+	p.BreakerCooldown = t.BreakerCooldown
+	p.statsd = t.statsd
+	p.statsdInterval = t.statsdInterval
+	p.statsdPrefix = t.statsdPrefix
+	if t.events != nil {
+		p.events = make(chan PoolEvent, cap(t.events))
+	}
+	if t.eventLog != nil {
+		p.eventLog = &eventRing{events: make([]PoolEvent, len(t.eventLog.events))}
+	}
+}
@@ -0,0 +1,126 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClone(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 2, WithBreaker(3, 1000))
+	defer pool.Close()
+
+	clone := pool.Clone(WithMaxSize(5), WithInitSize(1))
+	if clone.GetEndpoint() != endpoint || clone.IdleTimeout != 60*time.Second {
+		t.Errorf("clone should keep the base configuration\n")
+	}
+	if clone.GetMaxSize() != 5 || clone.GetInitSize() != 1 || clone.GetBreakerThreshold() != 3 {
+		t.Errorf("clone max:%d, init:%d, breaker:%d, want 5, 1, 3\n",
+			clone.GetMaxSize(), clone.GetInitSize(), clone.GetBreakerThreshold())
+	}
+	if pool.GetMaxSize() != 10 {
+		t.Errorf("parent max size changed to %d\n", pool.GetMaxSize())
+	}
+
+	conn, err := clone.Get(context.Background())
+	if err != nil {
+		t.Fatalf("clone.Get error:%s\n", err.Error())
+	}
+	_ = clone.Put(conn)
+	if clone.GetIdle() != 1 || pool.GetIdle() != 0 || pool.GetChanSize() != 0 {
+		t.Errorf("clone should not share the parent's channel\n")
+	}
+
+	clone.Close()
+	select {
+	case <-pool.Done():
+		t.Errorf("closing the clone should not close the parent\n")
+	default:
+	}
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+}
+
+func TestMaxIdle(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1, WithMaxIdle(2))
+	defer pool.Close()
+
+	if pool.idleCap != 2 {
+		t.Errorf("idle capacity is %d, want MaxIdle 2\n", pool.idleCap)
+	}
+	if cap(pool.idleConns.conns) != 0 {
+		t.Errorf("idle queue should grow lazily, preallocated %d\n", cap(pool.idleConns.conns))
+	}
+	conns := make([]*ThriftConn, 0, 4)
+	for i := 0; i < 4; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		if err := pool.Put(conn); err != nil {
+			t.Errorf("pool.Put error:%s\n", err.Error())
+		}
+	}
+	if pool.GetIdle() != 2 || pool.GetOpen() != 2 || pool.GetUsed() != 0 {
+		t.Errorf("idle:%d, open:%d, used:%d, want 2, 2, 0\n", pool.GetIdle(), pool.GetOpen(), pool.GetUsed())
+	}
+	if !conns[2].IsClose() || !conns[3].IsClose() {
+		t.Errorf("surplus conns should be closed\n")
+	}
+}
+
+func TestOnExhausted(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	errShed := errors.New("load shed")
+	var pool *ThriftPool
+	var usedAtCall int32
+	shed := true
+	onExhausted := func(ctx context.Context) error {
+		usedAtCall = pool.GetUsed()
+		if shed {
+			return errShed
+		}
+		return nil
+	}
+	pool = NewThriftPool(endpoint, 1000, 60000, 2, 1, WithOnExhausted(onExhausted))
+	defer pool.Close()
+
+	conns := make([]*ThriftConn, 0, 2)
+	for i := 0; i < 2; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns = append(conns, conn)
+	}
+	if _, err := pool.Get(context.Background()); !errors.Is(err, errShed) {
+		t.Errorf("Get should return the hook error, got %v\n", err)
+	}
+	if usedAtCall != 3 {
+		t.Errorf("hook should run before used is rolled back, got used:%d\n", usedAtCall)
+	}
+	shed = false
+	if _, err := pool.Get(context.Background()); err == nil || errors.Is(err, errShed) {
+		t.Errorf("Get should return the default error when the hook returns nil, got %v\n", err)
+	}
+	if pool.GetUsed() != 2 {
+		t.Errorf("used should be rolled back to 2, got %d\n", pool.GetUsed())
+	}
+	for _, conn := range conns {
+		_ = pool.Put(conn)
+	}
+}
@@ -0,0 +1,71 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync/atomic"
+)
+
+// 连接的来源
+type ConnOrigin int32
+
+const (
+	OriginUnknown	ConnOrigin = iota	// 未知，非连接创建的事件
+	OriginDialed						// 连接池自己拨号创建
+	OriginAdopted						// 由 Adopt 从外部接管
+)
+
+func (o ConnOrigin) String() string {
+	switch o {
+	case OriginDialed:
+		return "Dialed"
+	case OriginAdopted:
+		return "Adopted"
+	default:
+		return "Unknown"
+	}
+}
+
+// 连接加入连接池时的回调，参见 SetOnConnCreate
+func WithOnConnCreate(onConnCreate func(conn *ThriftConn)) Option {
+	return func(t *ThriftPool) {
+		t.SetOnConnCreate(onConnCreate)
+	}
+}
+
+// 设置连接加入连接池（拨号成功或被 Adopt 接管）时的回调，来源见 conn.GetOrigin，
+// 可用于审计连接是否都经由预期的途径创建。回调在拨号或 Adopt 的协程中同步调用，应尽快返回，
+// 且不能 Put 或关闭该连接
+func (t *ThriftPool) SetOnConnCreate(onConnCreate func(conn *ThriftConn)) {
+	t.OnConnCreate = onConnCreate
+}
+
+// 连接的来源，连接加入连接池时确定，之后不再改变
+func (t *ThriftConn) GetOrigin() ConnOrigin {
+	return t.origin
+}
+
+// 累计拨号创建的连接数
+func (t *ThriftPool) GetDialedConns() int64 {
+	return atomic.LoadInt64(&t.dialedConns)
+}
+
+// 累计 Adopt 接管的连接数
+func (t *ThriftPool) GetAdoptedConns() int64 {
+	return atomic.LoadInt64(&t.adoptedConns)
+}
+
+// 记录新加入连接池的连接并发送事件
+func (t *ThriftPool) connCreated(conn *ThriftConn, origin ConnOrigin) {
+	conn.origin = origin
+	eventType := EventDialed
+	if origin == OriginAdopted {
+		atomic.AddInt64(&t.adoptedConns, 1)
+		eventType = EventAdopted
+	} else {
+		atomic.AddInt64(&t.dialedConns, 1)
+	}
+	t.emitEvent(PoolEvent{Type: eventType, Origin: origin})
+	if t.OnConnCreate != nil {
+		t.OnConnCreate(conn)
+	}
+}
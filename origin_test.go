@@ -0,0 +1,69 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestConnOrigin(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	var mutex sync.Mutex
+	var created []ConnOrigin
+	onCreate := func(conn *ThriftConn) {
+		mutex.Lock()
+		created = append(created, conn.GetOrigin())
+		mutex.Unlock()
+	}
+	poolA := NewThriftPool(endpoint, 1000, 60000, 10, 0)
+	defer poolA.Close()
+	poolB := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithEvents(10), WithOnConnCreate(onCreate))
+	defer poolB.Close()
+
+	dialed, err := poolB.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get error:%s\n", err.Error())
+	}
+	if dialed.GetOrigin() != OriginDialed {
+		t.Errorf("dialed conn origin should be Dialed, got %s\n", dialed.GetOrigin())
+	}
+	_ = poolB.Put(dialed)
+
+	conn, err := poolA.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get error:%s\n", err.Error())
+	}
+	if err := poolA.Release(conn); err != nil {
+		t.Fatalf("release error:%s\n", err.Error())
+	}
+	if err := poolB.Adopt(conn); err != nil {
+		t.Fatalf("adopt error:%s\n", err.Error())
+	}
+	if conn.GetOrigin() != OriginAdopted {
+		t.Errorf("adopted conn origin should be Adopted, got %s\n", conn.GetOrigin())
+	}
+
+	stats := poolB.Stats()
+	if stats.DialedConns != 1 || stats.AdoptedConns != 1 {
+		t.Errorf("stats should count 1 dialed and 1 adopted conn, got %d/%d\n", stats.DialedConns, stats.AdoptedConns)
+	}
+	mutex.Lock()
+	if len(created) != 2 || created[0] != OriginDialed || created[1] != OriginAdopted {
+		t.Errorf("OnConnCreate should see each conn with its origin, got %v\n", created)
+	}
+	mutex.Unlock()
+
+	var origins []ConnOrigin
+	for len(poolB.Events()) > 0 {
+		event := <-poolB.Events()
+		if event.Type == EventDialed || event.Type == EventAdopted {
+			origins = append(origins, event.Origin)
+		}
+	}
+	if len(origins) != 2 || origins[0] != OriginDialed || origins[1] != OriginAdopted {
+		t.Errorf("events should carry the conn origin, got %v\n", origins)
+	}
+}
@@ -0,0 +1,80 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// 设置连接池耗尽时可创建的临时连接数，小于1表示不允许（默认）
+// 已用连接数达到 MaxSize 且没有空闲连接时，Get 会创建一个临时连接，临时连接不占用连接名额，
+// 不计入已用连接数，Put 时总是关闭而不放回队列，用于在短时突发时避免 Get 失败
+func (t *ThriftPool) SetOverflow(maxOverflow int32) {
+	if maxOverflow < 1 {
+		t.AllowOverflow = false
+		t.MaxOverflow = 0
+	} else {
+		t.AllowOverflow = true
+		t.MaxOverflow = maxOverflow
+	}
+}
+
+// 借出中的临时连接数
+func (t *ThriftPool) GetOverflow() int32 {
+	return atomic.LoadInt32(&t.overflow)
+}
+
+// 临时连接的最大数，不允许溢出时为0
+func (t *ThriftPool) GetMaxOverflow() int32 {
+	if !t.AllowOverflow {
+		return 0
+	}
+	return t.MaxOverflow
+}
+
+// 预占一个临时连接名额，不允许溢出或已达 MaxOverflow 时返回 false
+func (t *ThriftPool) tryReserveOverflow() bool {
+	if !t.AllowOverflow {
+		return false
+	}
+	for {
+		overflow := atomic.LoadInt32(&t.overflow)
+		if overflow >= t.MaxOverflow {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&t.overflow, overflow, overflow+1) {
+			return true
+		}
+	}
+}
+
+// 释放一个临时连接名额
+func (t *ThriftPool) releaseOverflow() {
+	atomic.AddInt32(&t.overflow, -1)
+}
+
+// 创建临时连接，endpoint 不为空时拨号该端点，调用前须已预占临时连接名额
+func (t *ThriftPool) dialOverflow(ctx context.Context, endpoint string) (*ThriftConn, error) {
+	if !t.allowDial() {
+		t.releaseOverflow()
+		return nil, ErrBreakerOpen
+	}
+	if err := t.waitDialToken(ctx); err != nil {
+		t.releaseOverflow()
+		return nil, err
+	}
+	var conn *ThriftConn
+	var err error
+	if endpoint == "" {
+		conn, err = t.connect(ctx)
+	} else {
+		conn, err = t.connectTo(ctx, t.loadBinding(), endpoint)
+	}
+	if err != nil {
+		t.releaseOverflow()
+		return nil, err
+	}
+	conn.overflow = true
+	t.addEndpointUsed(conn)
+	return conn, nil
+}
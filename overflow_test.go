@@ -0,0 +1,68 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestOverflow(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	pool := NewThriftPool(endpoint, 2000, 60000, 1, 0, WithOverflow(1))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get error:%s\n", err.Error())
+	}
+	extra, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get overflow conn error:%s\n", err.Error())
+	}
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("get beyond max overflow should fail with ErrPoolExhausted, got %v\n", err)
+	}
+	stats := pool.Stats()
+	if stats.Used != 1 || stats.Open != 1 || stats.Overflow != 1 || stats.MaxOverflow != 1 {
+		t.Errorf("unexpected stats with overflow conn:%+v\n", stats)
+	}
+
+	if err := pool.Put(extra); err != nil {
+		t.Errorf("put overflow conn error:%s\n", err.Error())
+	}
+	if !extra.IsClose() {
+		t.Errorf("overflow conn should be closed on put\n")
+	}
+	if pool.GetOverflow() != 0 || pool.GetIdle() != 0 {
+		t.Errorf("overflow conn should not be pooled, overflow:%d, idle:%d\n", pool.GetOverflow(), pool.GetIdle())
+	}
+	if err := pool.Put(conn); err != nil {
+		t.Errorf("put error:%s\n", err.Error())
+	}
+	if pool.GetIdle() != 1 {
+		t.Errorf("regular conn should be pooled, idle:%d\n", pool.GetIdle())
+	}
+}
+
+func TestOverflowDisabled(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	pool := NewThriftPool(endpoint, 2000, 60000, 1, 0)
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get error:%s\n", err.Error())
+	}
+	defer pool.Put(conn)
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("get without overflow should fail with ErrPoolExhausted, got %v\n", err)
+	}
+	if pool.GetMaxOverflow() != 0 {
+		t.Errorf("max overflow should be 0 by default, got %d\n", pool.GetMaxOverflow())
+	}
+}
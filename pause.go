@@ -0,0 +1,31 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// 连接池暂停拨号期间没有空闲连接可用
+var ErrPaused = errors.New("thriftpool paused")
+
+// 暂停拨号新连接，用于后端计划内的维护窗口：
+// 暂停期间 Get 只使用现有的空闲连接，没有空闲连接时返回 ErrPaused，后台预热也不再拨号，
+// 连接池不会被关闭，已借出的连接照常归还
+func (t *ThriftPool) Pause() {
+	if atomic.CompareAndSwapInt32(&t.paused, 0, 1) {
+		t.logf("pause dialing %s", t.GetEndpoint())
+	}
+}
+
+// 恢复拨号
+func (t *ThriftPool) Resume() {
+	if atomic.CompareAndSwapInt32(&t.paused, 1, 0) {
+		t.logf("resume dialing %s", t.GetEndpoint())
+	}
+}
+
+// 是否已暂停拨号
+func (t *ThriftPool) IsPaused() bool {
+	return atomic.LoadInt32(&t.paused) == 1
+}
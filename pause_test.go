@@ -0,0 +1,45 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPause(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1, WithLogger(&testLogger{}))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+
+	pool.Pause()
+	if !pool.IsPaused() {
+		t.Errorf("pool should be paused\n")
+	}
+	idle, err := pool.Get(context.Background())
+	if err != nil || idle != conn {
+		t.Fatalf("paused pool should still serve idle conns, err:%v\n", err)
+	}
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPaused) {
+		t.Errorf("paused pool without idle conns should return ErrPaused, got %v\n", err)
+	}
+	if pool.GetUsed() != 1 || pool.GetOpen() != 1 {
+		t.Errorf("paused Get should not dial, used:%d, open:%d\n", pool.GetUsed(), pool.GetOpen())
+	}
+
+	pool.Resume()
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Errorf("resumed pool should dial again:%s\n", err)
+	} else {
+		_ = pool.Put(conn)
+	}
+	_ = pool.Put(idle)
+}
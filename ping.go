@@ -0,0 +1,28 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+)
+
+// 检查能否与服务端正常通信，返回nil表示健康。
+// 有空闲连接时借出一个空闲连接检查，健康时放回池；否则用 GetProbe 拨号一个探测连接检查，用完即关闭，不占用连接名额。
+// 与借出校验和后台心跳使用相同的健康检查函数：设置了 WithValidator 时调用校验函数，
+// 否则设置了 WithHeartbeat 时调用心跳函数，都未设置时只要能取到连接（拨号成功）即视为健康。
+// 检查失败的连接被关闭，不会回到池中
+func (t *ThriftPool) Ping(ctx context.Context) error {
+	conn, err := t.GetIdleOnly(ctx)
+	if errors.Is(err, ErrNoIdle) {
+		conn, err = t.GetProbe(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	defer t.Put(conn)
+	if err := t.validate(ctx, conn); err != nil {
+		_ = conn.CloseTransport()
+		return t.nameErr(err)
+	}
+	return nil
+}
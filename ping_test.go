@@ -0,0 +1,105 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPingDialOnly(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0)
+	defer pool.Close()
+
+	// 没有空闲连接时拨号探测连接，用完即关闭
+	if err := pool.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping error:%s\n", err.Error())
+	}
+	if pool.GetOpen() != 0 || pool.GetProbes() != 0 {
+		t.Errorf("probe conn should be closed, open:%d, probes:%d\n", pool.GetOpen(), pool.GetProbes())
+	}
+
+	stop()
+	if err := pool.Ping(context.Background()); err == nil {
+		t.Errorf("Ping should fail when the server is down\n")
+	}
+}
+
+func TestPingHealthCheck(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	var beats, validations int32
+	var broken int32
+	heartbeat := func(ctx context.Context, conn *ThriftConn) error {
+		atomic.AddInt32(&beats, 1)
+		if atomic.LoadInt32(&broken) != 0 {
+			return errors.New("heartbeat failed")
+		}
+		return nil
+	}
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1, WithHeartbeat(heartbeat, 60000, 100))
+	defer pool.Close()
+
+	// 有空闲连接时检查空闲连接，健康时放回池
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+	if err := pool.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping error:%s\n", err.Error())
+	}
+	if atomic.LoadInt32(&beats) != 1 || pool.GetIdle() != 1 || conn.IsClose() {
+		t.Errorf("Ping should use the heartbeat and keep the healthy conn, beats:%d, idle:%d\n",
+			atomic.LoadInt32(&beats), pool.GetIdle())
+	}
+
+	atomic.StoreInt32(&broken, 1)
+	if err := pool.Ping(context.Background()); err == nil {
+		t.Errorf("Ping should fail when the heartbeat fails\n")
+	}
+	if !conn.IsClose() || pool.GetIdle() != 0 || pool.GetUsed() != 0 {
+		t.Errorf("conn failing Ping should be discarded, idle:%d, used:%d\n", pool.GetIdle(), pool.GetUsed())
+	}
+
+	// 校验函数优先于心跳函数
+	pool.Validator = func(ctx context.Context, conn *ThriftConn) error {
+		atomic.AddInt32(&validations, 1)
+		return nil
+	}
+	pool.ValidateTimeout = 100 * time.Millisecond
+	if err := pool.Ping(context.Background()); err != nil {
+		t.Errorf("Ping should use the validator first:%s\n", err.Error())
+	}
+	if atomic.LoadInt32(&validations) != 1 {
+		t.Errorf("validator should run once, got %d\n", atomic.LoadInt32(&validations))
+	}
+}
+
+func TestValidateFallsBackToHeartbeat(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	var beats int32
+	heartbeat := func(ctx context.Context, conn *ThriftConn) error {
+		atomic.AddInt32(&beats, 1)
+		return nil
+	}
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0,
+		WithHeartbeat(heartbeat, 60000, 100), WithValidateOnReturn(true))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+	if atomic.LoadInt32(&beats) != 1 {
+		t.Errorf("return validation should use the heartbeat, beats:%d\n", atomic.LoadInt32(&beats))
+	}
+}
@@ -2,42 +2,203 @@
 package thriftpool
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"git.apache.org/thrift.git/lib/go/thrift"
+	"net"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// 连接池中没有空闲连接，由 GetIdleOnly 返回
+var ErrNoIdle = errors.New("thriftpool no idle connection")
+
+// 连接队列已满，归还的连接被关闭，由 Put 返回
+var ErrIdleFull = errors.New("thriftpool idle queue full")
+
+// 归还的连接和连接池的端点不一致，由 Put 返回
+var ErrWrongEndpoint = errors.New("thriftpool wrong endpoint")
+
+// 连接池已关闭
+var ErrPoolClosed = errors.New("thriftpool closed")
+
+// 已用连接数已达 MaxSize，预先分配，连接池过载时失败路径不产生内存分配
+var ErrPoolExhausted = errors.New("thriftpool empty")
+
+// 等待拨号名额超过 DialTimeout，见 WithMaxConcurrentDials
+var ErrTooManyDials = errors.New("thriftpool too many concurrent dials")
+
 // thrift连接
 // 约束：同一个conn不应该同时被多个协程使用
+//
+// 连接的状态：
+// 1) 在池中：空闲，位于连接池的队列里，由 Get 取出后变为使用中
+// 2) 使用中：已被 Get 取出，计入连接池的已用连接数，应通过 Put 归还
+// 3) 不可用：使用中调用了 CloseTransport，socket 已关闭但仍计入已用连接数，
+//    Put 时扣减已用连接数后丢弃，不会放回池
+// 4) 已关闭：调用了 Close，socket 已关闭，不能再使用和放回池，
+//    如果是使用中的连接，仍应调用 Put 扣减已用连接数
 type ThriftConn struct {
 	Endpoint	string				// 服务端的端点
-	closed		bool				// 为 true 表示已被关闭，这种状态的不能再使用和放回池
+	closed		int32				// 为 1 表示已被关闭，这种状态的不能再使用和放回池
+	unusable	int32				// 为 1 表示socket已被关闭，等待 Put 丢弃
 	socket		*thrift.TSocket		// thrift连接
-	//transport	thrift.TTransport	// thrift transport
+	transport	thrift.TTransport	// thrift transport，即经 TransportWrapper、缓冲和 framed 包装后的 socket
 	usedTime	time.Time			// 最近使用时间
+	createdTime	time.Time			// 创建时间
+	borrowed	int32				// 为 1 表示借出中且启用了看门狗
+	slot		int32				// 为 1 表示占用着连接池的一个连接名额
+	pool		*ThriftPool			// 创建该连接的连接池
+	bufferBytes	int64				// 带缓冲的transport占用的缓冲大小
+	generation	int32				// 拨号时连接池的端点代数，见 Rebind
+	inUse		int32				// 为 1 表示借出中，用于发现重复借出和重复 Put
+	borrowedTime	int64			// 最近一次借出的时间，单位纳秒，用于统计持有时长
+	uses		int64				// 被借出的次数
+	tag			string				// 借出方的业务操作名，见 GetTagged
+	watchdog	*time.Timer			// 借出超时看门狗
+	overflow	bool				// 为 true 表示超出 MaxSize 的临时连接，见 WithOverflow
+	probe		bool				// 为 true 表示借出的探测连接，见 GetProbe
+	origin		ConnOrigin			// 连接的来源，加入连接池时确定
+	bytesRead	int64				// 累计读取的字节数，开启字节计数时统计
+	bytesWritten	int64			// 累计写入的字节数，开启字节计数时统计
+	guard		*guardTransport		// 最外层的 transport，写失败时标记连接不可用
+	protocol	thrift.TProtocol	// Protocols 缓存的 protocol，首次调用时创建
 }
 
 // thrift连接池
 type ThriftPool struct {
-	Endpoint		string				// 服务端的端点
+	Name			string				// 连接池名称，用于区分同一进程中的多个连接池
+	Endpoint		string				// 创建时的端点，Rebind 后以 GetEndpoint 为准
+	binding			atomic.Value		// 当前的端点及代数
+	rebindMutex		sync.Mutex			// 串行化 Rebind 和 SetTargets
+	targetCursor	uint32				// 多个目标时的轮询计数
+	EndpointResolver	EndpointResolver	// 每次拨号时解析端点，为nil时按 binding 选取，见 WithEndpointResolver
+	TransportFactory	thrift.TTransportFactory	// 新连接最外层 transport 的工厂，为nil时不包装
+	ProtocolFactory		thrift.TProtocolFactory		// Protocols 和 ClientFactory 使用的 protocol 工厂，为nil时为 binary protocol
+	workers			sync.WaitGroup		// 后台协程，Close 关闭队列前等待其退出
+	goroutines		int32				// 运行中的后台协程数
 	DialTimeout		time.Duration		// 拨号超时/连接超时
 	IdleTimeout		time.Duration		// 空闲连接超时时长，默认10s
 	MaxSize			int32				// 连接池最大连接数，如果没有设置最大值，默认100个
-	InitSize		int32				// 连接池初始连接数，最小值为1
+	InitSize		int32				// 连接池初始连接数，即闲置超时后仍保留的空闲连接数，为0时闲置超时的连接全部回收
+	MaxIdle			int32				// 最大空闲连接数，为0时不单独限制（即 MaxSize）
 	used			int32				// 已用连接数
-	idle			int32				// 空闲连接数（即在 clients 中的连接数）
+	open			int32				// 已打开的连接数（即占用的连接名额数），包括使用中和空闲的连接
+	idle			int32				// 空闲连接数（即在 idleConns 中的连接数，包括已预占待放入的）
 	assessTime		int64				// 最近异常调用Get或者Put的时间，根据它来判定该池是否活跃
 	closed			int32				// 关闭连接池
-	clients chan *ThriftConn			// thrift连接队列
+	draining		int32				// 为 1 表示正在优雅关闭，不再借出连接
+	paused			int32				// 为 1 表示暂停拨号，只使用空闲连接
+	idleConns		*idleList			// 空闲连接队列
+	idleCap			int32				// 空闲连接数的上限，即 MaxIdle，未设置时为 MaxSize
+	ctx				context.Context		// 连接池关闭时被取消，后台协程据此退出
+	cancel			context.CancelFunc
+	MaxBorrowTime	time.Duration		// 连接最长借出时长，超时未归还则强制回收，为0时不限制
+	MaxConnLifetime	time.Duration		// 连接最长存活时间，自创建时算起，为0时不限制
+	OnBorrowTimeout	func(conn *ThriftConn)	// 连接借出超时被回收后的回调
+	TransportWrapper	func(socket *thrift.TSocket) (thrift.TTransport, error)	// 包装新拨号的socket，如完成SASL握手
+	OnConnReady		func(ctx context.Context, conn *ThriftConn) error	// 新连接可用前的协商，如版本交换
+	IdlePoolTTL		time.Duration		// 连接池闲置超过该时长后自动关闭，为0时不自动关闭
+	OnIdleClose		func(pool *ThriftPool)	// 连接池因闲置自动关闭后的回调
+	BaseContext		context.Context		// 连接池的基础 ctx，为nil时为 context.Background()
+	Registered		bool				// 是否登记到全局注册表，见 WithRegister
+	throttle		int32				// ThrottleTo 设置的临时最大连接数，为0时不限流
+	DialRetries		int32				// socket 打开失败后的重试次数，为0时不重试
+	DialRetryBackoff	time.Duration	// 首次重试拨号前的等待时长，之后每次翻倍
+	MaxDialElapsed	time.Duration		// 一次拨号（含重试）的总时长上限，为0时只受 ctx 限制
+	idleClosing		int32				// 为 1 表示已因闲置开始自动关闭
+	OnConnCreate	func(conn *ThriftConn)	// 连接加入连接池时的回调
+	dialedConns		int64				// 累计拨号创建的连接数
+	adoptedConns	int64				// 累计 Adopt 接管的连接数
+	CountBytes		bool				// 是否统计连接读写的字节数，见 WithByteCounting
+	MaxConnBytes	int64				// 连接累计读写的最大字节数，超过后 Put 时关闭，为0时不限制
+	ioBytes			int64				// 所有连接累计读写的字节数
+	ZlibTransport	bool				// 是否为连接套上 zlib 压缩，见 WithZlibTransport
+	ZlibLevel		int32				// zlib 的压缩级别
+	events			chan PoolEvent		// 事件channel，为nil时不发送事件
+	droppedEvents	int64				// 因事件channel已满而丢弃的事件数
+	statsd			StatsdClient		// StatsD客户端，为nil时不上报
+	statsdInterval	time.Duration		// StatsD Gauge的上报间隔
+	statsdPrefix	string				// StatsD指标名前缀
+	BufferSize		int32				// 带缓冲的transport的读写缓冲大小，为0时不套缓冲
+	BufferBudget	int64				// 所有连接的缓冲总预算，为0时不限制
+	bufferedBytes	int64				// 所有连接当前占用的缓冲总大小
+	logger			Logger				// 日志输出
+	RetryClassifier	RetryClassifier		// DoWithRetry 的可重试判断，为nil时使用 IsConnectionError
+	RetryBackoff	time.Duration		// DoWithRetry 首次重试前的等待时长，之后每次翻倍
+	StartupPolicy	StartupPolicy		// NewThriftPoolChecked 拨号不足 InitSize 时的处理策略
+	WarmUpMaxFailures	int32			// 后台预热连续失败多少轮后暂停，为0时不暂停
+	WarmUpMaxBackoff	time.Duration	// 后台预热失败后退避间隔的上限
+	warmUpState		int32				// 后台预热的状态，见 WarmUpState
+	warmUpResume	chan struct{}		// 暂停的后台预热等待拨号成功的通知
+	HeartbeatFunc		HeartbeatFunc	// 空闲连接心跳，为nil时不开启
+	HeartbeatInterval	time.Duration	// 心跳间隔
+	HeartbeatTimeout	time.Duration	// 单次心跳的超时
+	Validator			ValidateFunc	// 连接校验函数，为nil时不校验
+	ValidateTimeout		time.Duration	// 单次校验的超时
+	ValidateOnBorrow	bool			// 借出空闲连接前校验
+	ValidateOnReturn	bool			// 放回连接时校验
+	MaxConcurrentDials	int32			// Get 同时拨号的最大数，为0时不限制
+	dialSem				chan struct{}	// 拨号名额，MaxConcurrentDials 为0时为nil
+	dialing				int32			// 正在拨号的数量
+	LocalAddr			net.Addr		// 拨号使用的本地地址，为nil时由系统选择
+	OnExhausted			func(ctx context.Context) error	// 已用连接数超过 MaxSize 时调用
+	dialLatency			latencyHistogram	// 拨号成功的耗时分布
+	holdLatency			latencyHistogram	// 连接从借出到 Put 归还的持有时长分布
+	evicted				[evictReasons]int64	// 按原因统计的被连接池关闭的连接数
+	Debug				bool			// 调试模式，检查连接的重复借出和重复归还
+	FramedTransport		bool			// 是否为连接套上 TFramedTransport
+	MaxFrameSize		uint32			// framed transport 允许读取的最大帧
+	DetailedErrors		bool			// 连接池耗尽时返回带计数的错误，默认只返回 ErrPoolExhausted
+	SRVRefreshInterval	time.Duration	// SRV记录的重新解析间隔，见 NewThriftPoolSRV
+	srvName				string			// NewThriftPoolSRV 的SRV名称，Clone 据此在新连接池上继续定期解析
+	nowFunc				func() time.Time	// 当前时间，闲置超时等判断都通过它取时间，测试时替换
+	AllowOverflow		bool			// 连接池耗尽时是否创建不入池的临时连接
+	MaxOverflow			int32			// 临时连接的最大数
+	overflow			int32			// 借出中的临时连接数
+	IdleStrategy		IdleStrategy	// 空闲连接的挑选策略
+	EvictionOrder		EvictionOrder	// 按容量回收空闲连接时的顺序
+	ReadBufferSize		int32			// socket 的内核接收缓冲（SO_RCVBUF），为0时使用系统默认值
+	WriteBufferSize		int32			// socket 的内核发送缓冲（SO_SNDBUF），为0时使用系统默认值
+	TrimPolicy			TrimPolicy		// 空闲连接回收策略，为nil时按闲置超时回收
+	HistorySize			int32			// 保留的 Stats 快照数，为0时不记录
+	HistoryInterval		time.Duration	// 记录 Stats 快照的间隔
+	history				*statsRing		// Stats 快照，HistorySize 为0时为nil
+	eventLog			*eventRing		// 最近的事件，未开启时为nil
+	DialRateLimit		int32			// 每秒最多拨号的新连接数，为0时不限制
+	DialBurst			int32			// 允许的突发拨号数
+	dialLimiter			*tokenBucket	// 拨号令牌桶，DialRateLimit 为0时为nil
+	throttledDials		int64			// 因拨号速率限制而等待过的拨号数
+	LenientSizes		bool			// NewThriftPoolChecked 是否接受被调整的连接数参数
+	sizeAdjustments		[]string		// 创建时对连接数参数所做的调整
+	MaxProbes			int32			// 同时借出的探测连接的最大数，见 GetProbe
+	probes				int32			// 借出中的探测连接数
+	FDReserve			int32			// 拨号前须保留的文件描述符数，见 WithFDLimit
+	FDChecker			FDChecker		// 读取进程文件描述符的数量和上限，为nil时不检查
+	waiters				waitQueue		// 连接池耗尽时等待连接的 GetPriority
+	endpointUsed		sync.Map		// 各端点借出中的连接数，map[string]*int32
+
+	BreakerThreshold	int32			// 连续拨号失败达到该次数后熔断，为0时不启用熔断
+	BreakerCooldown		time.Duration	// 熔断后的冷却时长，冷却期过后放行一次拨号试探，默认5s
+	dialFailures		int32			// 连续拨号失败次数
+	breakerOpenTime		int64			// 最近一次熔断（或试探）的时间，单位纳秒
+
+	MinIdle				int32			// 保持的最少空闲连接数，为0时不保持，见 WithMinIdle
+	MinIdleInterval		time.Duration	// 探测空闲连接并补足 MinIdle 的间隔
+	MinIdleJitter		time.Duration	// 每次补连前随机等待的最长时间
+	replacements		int32			// 最近一轮补足 MinIdle 时拨号的连接数
 }
 
 // 创建thrift连接池，总是返回非nil值
+// 创建时不拨号，需要预热 InitSize 个空闲连接时调用 WarmUp，或改用 NewThriftPoolChecked
+// opts 在参数校验之后依次应用，可覆盖前面的参数
 // 注意在使用完后，应调用连接池的成员函数 Close 释放创建连接池时所分配的资源
-func NewThriftPool(endpoint string, dialTimeout, idleTimeout, maxSize, initSize int32) *ThriftPool {
+func NewThriftPool(endpoint string, dialTimeout, idleTimeout, maxSize, initSize int32, opts ...Option) *ThriftPool {
 	thriftPool := new(ThriftPool)
 	thriftPool.Endpoint = endpoint
+	thriftPool.binding.Store(binding{endpoint: endpoint})
 	if dialTimeout < 1 {
 		thriftPool.DialTimeout = time.Duration(5000) * time.Millisecond
 	} else {
@@ -52,21 +213,89 @@ func NewThriftPool(endpoint string, dialTimeout, idleTimeout, maxSize, initSize
 		thriftPool.MaxSize = 100
 	} else if maxSize <= (initSize*2) {
 		thriftPool.MaxSize = initSize * 2
+		if maxSize != thriftPool.MaxSize {
+			thriftPool.adjustSize("max size %d raised to %d (twice the init size)", maxSize, thriftPool.MaxSize)
+		}
 	} else {
 		thriftPool.MaxSize = maxSize
 	}
-	if initSize < 1 {
-		thriftPool.InitSize = 1
+	if initSize < 0 {
+		thriftPool.InitSize = 0
+		thriftPool.adjustSize("init size %d raised to 0", initSize)
 	} else {
 		thriftPool.InitSize = initSize
 	}
 
+	thriftPool.BreakerCooldown = time.Duration(5000) * time.Millisecond
+	thriftPool.nowFunc = time.Now
+	thriftPool.logger = defaultLogger
+	thriftPool.WarmUpMaxBackoff = time.Duration(30000) * time.Millisecond
+	thriftPool.warmUpResume = make(chan struct{}, 1)
+	for _, opt := range opts {
+		opt(thriftPool)
+	}
+	if thriftPool.InitSize > thriftPool.MaxSize {
+		thriftPool.adjustSize("init size %d lowered to max size %d", thriftPool.InitSize, thriftPool.MaxSize)
+		thriftPool.InitSize = thriftPool.MaxSize
+	}
+	if thriftPool.MaxIdle >= thriftPool.MaxSize {
+		thriftPool.MaxIdle = 0
+	} else if thriftPool.MaxIdle > 0 && thriftPool.MaxIdle < thriftPool.InitSize {
+		thriftPool.adjustSize("max idle %d raised to init size %d", thriftPool.MaxIdle, thriftPool.InitSize)
+		thriftPool.MaxIdle = thriftPool.InitSize
+	}
+	if thriftPool.MinIdle > thriftPool.MaxSize {
+		thriftPool.adjustSize("min idle %d lowered to max size %d", thriftPool.MinIdle, thriftPool.MaxSize)
+		thriftPool.MinIdle = thriftPool.MaxSize
+	}
+	if thriftPool.MaxIdle > 0 && thriftPool.MinIdle > thriftPool.MaxIdle {
+		thriftPool.adjustSize("min idle %d lowered to max idle %d", thriftPool.MinIdle, thriftPool.MaxIdle)
+		thriftPool.MinIdle = thriftPool.MaxIdle
+	}
+	for _, adjustment := range thriftPool.sizeAdjustments {
+		thriftPool.logf("%s", adjustment)
+	}
+
 	thriftPool.used = 0
 	thriftPool.idle = 0
 	thriftPool.closed = 0
-	thriftPool.clients = make(chan *ThriftConn, thriftPool.MaxSize)
+	// 新建的连接池视为刚被访问过
+	thriftPool.assessTime = thriftPool.now().Unix()
+	if thriftPool.MaxIdle > 0 {
+		// 空闲连接不会超过 MaxIdle，已打开的连接数由连接名额限制
+		thriftPool.idleCap = thriftPool.MaxIdle
+	} else {
+		thriftPool.idleCap = thriftPool.MaxSize
+	}
+	thriftPool.idleConns = newIdleList(thriftPool.idleCap)
+	if thriftPool.MaxConcurrentDials > 0 {
+		thriftPool.dialSem = make(chan struct{}, thriftPool.MaxConcurrentDials)
+	}
+	if thriftPool.DialRateLimit > 0 {
+		thriftPool.dialLimiter = newTokenBucket(thriftPool.DialRateLimit, thriftPool.DialBurst)
+	}
+	if thriftPool.BaseContext != nil {
+		thriftPool.ctx, thriftPool.cancel = context.WithCancel(thriftPool.BaseContext)
+		thriftPool.watchBaseContext()
+	} else {
+		thriftPool.ctx, thriftPool.cancel = context.WithCancel(context.Background())
+	}
 
-	go thriftPool.releaseIdleConn()
+	thriftPool.goWorker(thriftPool.releaseIdleConn)
+	if thriftPool.statsd != nil {
+		thriftPool.goWorker(thriftPool.reportStatsd)
+	}
+	if thriftPool.HeartbeatFunc != nil {
+		thriftPool.goWorker(thriftPool.monitorHeartbeat)
+	}
+	if thriftPool.MinIdle > 0 {
+		thriftPool.goWorker(thriftPool.maintainMinIdle)
+	}
+	if thriftPool.HistorySize > 0 {
+		thriftPool.history = newStatsRing(thriftPool.HistorySize)
+		thriftPool.goWorker(thriftPool.sampleStats)
+	}
+	thriftPool.register()
 	return thriftPool
 }
 
@@ -74,215 +303,648 @@ func (t *ThriftConn) GetEndpoint() string {
 	return t.Endpoint
 }
 
+// 最近一次借出时的业务操作名，见 GetTagged
+func (t *ThriftConn) GetTag() string {
+	return t.tag
+}
+
 func (t *ThriftConn) GetSocket() *thrift.TSocket {
 	return t.socket
 }
 
-//func (t *ThriftConn) GetTransport() thrift.TTransport {
-//	return t.transport
-//}
+func (t *ThriftConn) GetTransport() thrift.TTransport {
+	return t.transport
+}
+
+// 底层的 net.Conn，可用于获取对端地址或设置连接池未封装的socket选项，
+// socket未打开时第二个返回值为 false。
+// 连接的生命周期仍由连接池管理，调用方不要直接关闭返回的 net.Conn
+func (t *ThriftConn) NetConn() (net.Conn, bool) {
+	if t.socket == nil {
+		return nil, false
+	}
+	netConn := t.socket.Conn()
+	return netConn, netConn != nil
+}
 
 // 纳秒
 func (t *ThriftConn) GetUsedTime() int64 {
 	return t.usedTime.UnixNano()
 }
 
+// 创建时间，纳秒
+func (t *ThriftConn) GetCreatedTime() int64 {
+	return t.createdTime.UnixNano()
+}
+
 func (t *ThriftConn) UpdateUsedTime() int64 {
-	t.usedTime = time.Now()
+	if t.pool != nil {
+		t.usedTime = t.pool.now()
+	} else {
+		t.usedTime = time.Now()
+	}
 	return t.usedTime.UnixNano()
 }
 
-// 关闭thrift连接
+// 关闭thrift连接，可与 IsClose、CloseTransport 并发调用，重复调用时返回nil
 func (t *ThriftConn) Close() error {
-	if t.closed {
+	if !atomic.CompareAndSwapInt32(&t.closed, 0, 1) {
+		return nil
+	}
+	// 已调用过 CloseTransport 时socket已关闭
+	if !atomic.CompareAndSwapInt32(&t.unusable, 0, 1) {
 		return nil
 	}
-	t.closed = true
 	return t.socket.Close()
 }
 
 func (t *ThriftConn) IsClose() bool {
-	return t.closed
+	return atomic.LoadInt32(&t.closed) == 1
+}
+
+// 只关闭socket，将连接标记为不可用，已用连接数留给随后的 Put 扣减
+func (t *ThriftConn) CloseTransport() error {
+	if t.IsClose() || !atomic.CompareAndSwapInt32(&t.unusable, 0, 1) {
+		return nil
+	}
+	return t.socket.Close()
+}
+
+// 标记连接上有被放弃的调用，如 ctx 取消或超时后不再等待回复，同 CloseTransport，Put 时总是关闭而不放回池
+// 服务端在之后仍会发来该调用的回复，连接若被复用，下一个借出方会先读到这份回复而导致协议错乱，
+// 因此取消或超时的调用必须标记连接（Do 和 Borrowed.Fail 在出错时已自动处理）
+func (t *ThriftConn) MarkAbandoned() error {
+	return t.CloseTransport()
+}
+
+// 连接是否可以继续使用或放回池，已关闭和不可用的连接都返回 false
+func (t *ThriftConn) IsUsable() bool {
+	return atomic.LoadInt32(&t.closed) == 0 && atomic.LoadInt32(&t.unusable) == 0
 }
 
 // 更新最近使用时间
 
 // 从连接池取一个连接，
 // 应和 Put 一对一成对调用
+// ctx 已结束时直接返回 ctx.Err()；需要拨号时，拨号超时取 DialTimeout 与 ctx 截止时间中较早者，
+// 等待拨号名额（见 WithMaxConcurrentDials）时 ctx 结束同样返回 ctx.Err()
+// 可用 errors.Is 判断的错误：ErrPoolClosed、ErrPoolExhausted、ErrTooManyDials、ErrBreakerOpen、ErrPaused；
+// 拨号失败的错误形如 "thriftpool dial <端点>: <原始错误>"，可用 errors.As 取出原始错误，见 IsConnectionError
 // 返回两个值：
 // 1) ThriftConn 指针
 // 2) 错误信息
-func (t *ThriftPool) Get() (*ThriftConn, error) {
-	return t.get(false)
+func (t *ThriftPool) Get(ctx context.Context) (*ThriftConn, error) {
+	return t.GetTagged(ctx, "")
 }
 
-func (t *ThriftPool) get(doNotNew bool) (*ThriftConn, error) {
-	accessTime := time.Now().Unix()
-	atomic.StoreInt64(&t.assessTime, accessTime)
+// 同 Get，tag 为借出方的业务操作名，记录在连接上（见 ThriftConn.GetTag），
+// 并带在借出、归还和借出超时回收的事件及日志中，便于把泄漏的连接对应到借出它的业务操作
+func (t *ThriftPool) GetTagged(ctx context.Context, tag string) (*ThriftConn, error) {
+	ctx, cancel := t.withPoolContext(ctx)
+	defer cancel()
+	var conn *ThriftConn
+	var err error
+	if t.EndpointResolver != nil {
+		conn, err = t.getResolved(ctx)
+	} else {
+		conn, err = t.get(ctx, false)
+	}
+	if err != nil {
+		return nil, t.nameErr(err)
+	}
+	conn.tag = tag
+	t.markInUse(conn)
+	t.startWatchdog(conn)
+	t.emitTagged(EventBorrowed, tag, nil)
+	return conn, nil
+}
+
+// 只从连接池取空闲连接，没有空闲连接时返回 ErrNoIdle，不会拨号新连接
+// 适合低优先级的后台任务利用空闲容量，而不增加连接压力
+// 取到连接时，同样应和 Put 一对一成对调用
+func (t *ThriftPool) GetIdleOnly(ctx context.Context) (*ThriftConn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, t.nameErr(err)
+	}
+	t.touch()
+	conn, err := t.get(ctx, true)
+	if err != nil {
+		return nil, t.nameErr(err)
+	}
+	if conn == nil {
+		return nil, t.nameErr(ErrNoIdle)
+	}
+	conn.tag = ""
+	t.markInUse(conn)
+	t.startWatchdog(conn)
+	t.emit(EventBorrowed, nil)
+	return conn, nil
+}
+
+func (t *ThriftPool) get(ctx context.Context, doNotNew bool) (*ThriftConn, error) {
+	return t.getFor(ctx, doNotNew, "")
+}
+
+// 取连接，endpoint 不为空时只复用连到该端点的空闲连接，没有时拨号该端点（GetForKey 和 EndpointResolver）
+func (t *ThriftPool) getFor(ctx context.Context, doNotNew bool, endpoint string) (*ThriftConn, error) {
+	if atomic.LoadInt32(&t.closed) == 1 || atomic.LoadInt32(&t.draining) == 1 {
+		return nil, ErrPoolClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !doNotNew {
+		t.touch()
+	}
 	curUsed := t.addUsed()
 
-	select {
-	case conn := <-t.clients:
-		t.subIdle()
-		return conn, nil
-	default:
+	if conn := t.popIdle(doNotNew, endpoint); conn != nil {
+		return t.takeIdle(ctx, conn, doNotNew, endpoint)
+	}
+	{
 		if doNotNew {
 			t.subUsed()
 			return nil, nil
 		}
-		if curUsed > t.MaxSize {
-			newUsed := t.subUsed()
-			return nil, errors.New(fmt.Sprintf("thriftpool empty, used:%d/%d, init:%d, max:%d",
-				curUsed, newUsed, t.InitSize, t.MaxSize))
+		if t.IsPaused() {
+			t.subUsed()
+			return nil, ErrPaused
 		}
-		var err error
-		var socket *thrift.TSocket
-
-		if t.DialTimeout > 0 {
-			socket, err = thrift.NewTSocketTimeout(t.Endpoint, t.DialTimeout)
-		} else {
-			socket, err = thrift.NewTSocket(t.Endpoint)
+		if curUsed > t.GetEffectiveMaxSize() {
+			if t.tryReserveOverflow() {
+				// 溢出连接不计入已用连接数
+				t.subUsed()
+				return t.dialOverflow(ctx, endpoint)
+			}
+			if t.OnExhausted != nil {
+				// 在回退已用连接数之前调用，回调看到的是超出时的状态
+				if err := t.OnExhausted(ctx); err != nil {
+					t.subUsed()
+					return nil, err
+				}
+			}
+			t.subUsed()
+			if !t.DetailedErrors {
+				return nil, ErrPoolExhausted
+			}
+			return nil, t.poolError(KindExhausted, curUsed)
 		}
-
-		if err != nil {
-			// 错误处理还得继续
+		if !t.allowDial() {
+			t.subUsed()
+			return nil, ErrBreakerOpen
+		}
+		if err := t.waitDialToken(ctx); err != nil {
 			t.subUsed()
 			return nil, err
 		}
-
-		err = socket.Open()
+		if t.dialSem != nil {
+			// 同时拨号数已达上限时，等待拨号名额或其它协程归还的空闲连接
+			timer := time.NewTimer(t.DialTimeout)
+		WAIT:
+			for {
+				select {
+				case t.dialSem <- struct{}{}:
+					timer.Stop()
+					defer func() { <-t.dialSem }()
+					break WAIT
+				case <-t.idleConns.ready:
+					if conn := t.popIdle(doNotNew, endpoint); conn != nil {
+						timer.Stop()
+						return t.takeIdle(ctx, conn, doNotNew, endpoint)
+					}
+				case <-ctx.Done():
+					timer.Stop()
+					t.subUsed()
+					return nil, ctx.Err()
+				case <-timer.C:
+					t.subUsed()
+					return nil, fmt.Errorf("%w, dialing:%d, max:%d", ErrTooManyDials, t.GetDialing(), t.MaxConcurrentDials)
+				}
+			}
+		}
+		conn, err := t.dialTo(ctx, endpoint)
 		if err != nil {
-			// 错误错误处理
 			t.subUsed()
 			return nil, err
 		}
-		conn := new(ThriftConn)
-		conn.Endpoint = t.Endpoint
-		conn.closed = false
-		conn.socket = socket
-		conn.usedTime = time.Now()
+		if atomic.LoadInt32(&t.closed) == 1 {
+			// 拨号期间连接池被关闭，新连接已无法放回池
+			t.discard(conn)
+			t.subUsed()
+			return nil, ErrPoolClosed
+		}
+		t.addEndpointUsed(conn)
 		return conn, nil
 	}
 }
 
+// 处理从队列取出的空闲连接，调用前已计入已用连接数
+func (t *ThriftPool) takeIdle(ctx context.Context, conn *ThriftConn, doNotNew bool, endpoint string) (*ThriftConn, error) {
+	t.subIdle()
+	if t.isStale(conn) {
+		// Rebind 之前的空闲连接，关闭后重新取
+		t.subUsed()
+		t.discard(conn)
+		t.countEvicted(evictStale)
+		t.emit(EventEvicted, nil)
+		return t.getFor(ctx, doNotNew, endpoint)
+	}
+	if t.ValidateOnBorrow && !doNotNew && t.validate(ctx, conn) != nil {
+		// 校验失败，关闭后重新取
+		t.subUsed()
+		t.discard(conn)
+		t.countEvicted(evictBroken)
+		t.emit(EventEvicted, nil)
+		return t.getFor(ctx, doNotNew, endpoint)
+	}
+	t.addEndpointUsed(conn)
+	return conn, nil
+}
+
+// 拨号创建一个新连接，并更新熔断状态
+// 所有拨号都需先预占连接名额，保证已打开的连接数不超过 MaxSize
+func (t *ThriftPool) dial(ctx context.Context) (*ThriftConn, error) {
+	if !t.tryReserveSlot() {
+		return nil, t.poolError(KindFull, t.GetUsed())
+	}
+	conn, err := t.connect(ctx)
+	if err != nil {
+		t.releaseSlot()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// 预占连接名额并拨号 endpoint，为空时同 dial；
+// 连接名额已满时关闭一个其它端点的空闲连接腾出名额，没有空闲连接可关闭时返回 KindFull
+func (t *ThriftPool) dialTo(ctx context.Context, endpoint string) (*ThriftConn, error) {
+	if endpoint == "" {
+		return t.dial(ctx)
+	}
+	for !t.tryReserveSlot() {
+		conn := t.idleConns.popFront()
+		if conn == nil {
+			return nil, t.poolError(KindFull, t.GetUsed())
+		}
+		t.subIdle()
+		t.discard(conn)
+		t.countEvicted(evictOverMax)
+		t.emit(EventEvicted, nil)
+	}
+	conn, err := t.connectTo(ctx, t.loadBinding(), endpoint)
+	if err != nil {
+		t.releaseSlot()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// 建立连接，不预占连接名额，由调用方负责名额的预占和释放
+func (t *ThriftPool) connect(ctx context.Context) (*ThriftConn, error) {
+	bound := t.loadBinding()
+	endpoint, err := t.resolveEndpoint(ctx, bound)
+	if err != nil {
+		return nil, err
+	}
+	return t.connectTo(ctx, bound, endpoint)
+}
+
+// 建立到指定端点的连接，bound 为选取端点时的绑定
+func (t *ThriftPool) connectTo(ctx context.Context, bound binding, endpoint string) (*ThriftConn, error) {
+	if err := t.checkFDs(); err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&t.dialing, 1)
+	defer atomic.AddInt32(&t.dialing, -1)
+	startTime := time.Now()
+	socket, err := t.dialSocket(ctx, endpoint)
+	if err != nil {
+		err = fmt.Errorf("thriftpool dial %s: %w", endpoint, err)
+		t.dialFailed()
+		t.emit(EventDialFailed, err)
+		return nil, err
+	}
+	t.setSocketBuffers(socket)
+	var transport thrift.TTransport = socket
+	if t.TransportWrapper != nil {
+		transport, err = t.TransportWrapper(socket)
+		if err != nil {
+			// 握手失败同样视为拨号失败
+			_ = socket.Close()
+			t.dialFailed()
+			t.emit(EventDialFailed, err)
+			return nil, err
+		}
+	}
+	conn := new(ThriftConn)
+	conn.Endpoint = endpoint
+	conn.generation = bound.generation
+	conn.socket = socket
+	conn.transport = transport
+	conn.slot = 1
+	conn.pool = t
+	t.wrapCounting(conn)
+	if err := t.wrapZlib(conn); err != nil {
+		_ = socket.Close()
+		t.dialFailed()
+		t.emit(EventDialFailed, err)
+		return nil, err
+	}
+	t.wrapBuffered(conn)
+	t.wrapFramed(conn)
+	t.wrapFactory(conn)
+	t.guard(conn)
+	if t.OnConnReady != nil {
+		if err := t.OnConnReady(ctx, conn); err != nil {
+			// 协商失败同样视为拨号失败
+			_ = conn.Close()
+			t.releaseBuffer(conn)
+			t.dialFailed()
+			t.emit(EventDialFailed, err)
+			return nil, err
+		}
+	}
+	t.dialSucceeded()
+	t.dialLatency.record(time.Since(startTime))
+	conn.usedTime = t.now()
+	conn.createdTime = conn.usedTime
+	t.connCreated(conn, OriginDialed)
+	return conn, nil
+}
+
 // 连接用完后归还回池，应和 Get 一对一成对调用
-// 约束：同一 conn 不应同时被多个协程使用
+// 约束：同一 conn 不应同时被多个协程使用，重复 Put 或归还未借出的连接时返回 ErrNotBorrowed，参见 WithDebug
 // 传参：
 // ThriftConn指针
 // 返回值：
 // 2) 错误信息
 func (t *ThriftPool) Put(conn *ThriftConn) error {
-	return t.put(conn, false)
+	if !t.clearInUse(conn) {
+		return t.nameErr(ErrNotBorrowed)
+	}
+	t.recordHold(conn)
+	t.emitTagged(EventReturned, conn.tag, nil)
+	return t.nameErr(t.put(conn, false))
 }
 
+// 放回连接，不能放回队列时关闭连接，按以下顺序判断并按第一个满足的条件统计关闭原因：
+// 连接池已关闭或连接不可用、属于旧端点、超过最长存活时间、超过字节预算、超出限流值、放回时校验失败、空闲连接数已达上限、闲置超时、队列已满；
+// 有 GetPriority 在等待时，可用的连接在放回时校验之后直接交给等待者
 func (t *ThriftPool) put(conn *ThriftConn, doNotNew bool) error {
-	accessTime := time.Now().Unix()
-	atomic.StoreInt64(&t.assessTime, accessTime)
-	defer func() {
-		// 捕获panic，因为channel关闭时，再向关闭的channel写数据时，会导致panic
-		if err := recover(); err != nil {
-			_ = conn.Close()
-			t.subIdle()
+	if conn.pool != nil && conn.pool != t {
+		// 归还到了错误的连接池，即使端点相同（如 Clone 出的连接池）也不接收，
+		// 关闭连接，已用连接数和连接名额交还给创建它的连接池
+		_ = conn.CloseTransport()
+		_ = conn.pool.put(conn, true)
+		if conn.GetEndpoint() != t.GetEndpoint() {
+			return fmt.Errorf("%w: conn endpoint %s, pool endpoint %s", ErrWrongEndpoint, conn.GetEndpoint(), t.GetEndpoint())
 		}
-	}()
+		return ErrForeignConn
+	}
+	if conn.pool == nil && conn.GetEndpoint() != t.GetEndpoint() {
+		// 不属于任何连接池的连接，端点不同时不接收
+		_ = conn.Close()
+		return fmt.Errorf("%w: conn endpoint %s, pool endpoint %s", ErrWrongEndpoint, conn.GetEndpoint(), t.GetEndpoint())
+	}
+	if !doNotNew {
+		t.touch()
+	}
 
-	used := t.subUsed()
+	if !t.stopWatchdog(conn) {
+		// 连接已被看门狗回收，已用连接数也已扣减
+		return ErrConnReclaimed
+	}
+	if conn.probe {
+		// 探测连接用完即关闭，不计入已用连接数；新建的探测连接不占用连接名额，discard 不会释放其缓冲计数
+		t.discard(conn)
+		t.releaseBuffer(conn)
+		t.releaseProbe()
+		return nil
+	}
+	t.subEndpointUsed(conn)
+	if conn.overflow {
+		// 溢出连接用完即关闭，不放回队列
+		t.discard(conn)
+		return nil
+	}
+	// 连接处理完（放回队列或关闭）后再扣减已用连接数，CloseGracefully 据此判断连接都已归还
+	defer t.subUsedOnPut()
 	closed := atomic.LoadInt32(&t.closed)
-	if closed == 1 {
-		if !conn.IsClose() {
-			_ = conn.Close()
-		}
+	if closed == 1 || atomic.LoadInt32(&t.draining) == 1 {
+		t.discard(conn)
+		return nil
+	}
+	if !conn.IsUsable() || conn.hasPendingWrites() {
+		// 如果ThriftConn关闭、不可用（包括写失败）或有未 Flush 的数据时，无需返回队列
+		t.discard(conn)
+		t.countEvicted(evictBroken)
 		return nil
 	}
-	if conn.IsClose() {
-		// 如果ThriftConn关闭时，无需返回队列
+	if t.isStale(conn) {
+		// Rebind 之前借出的连接
+		t.discard(conn)
+		t.countEvicted(evictStale)
+		t.emit(EventEvicted, nil)
+		return nil
+	}
+	if t.isExpired(conn) {
+		// 超过最长存活时间，即使仍在 IdleTimeout 之内也关闭
+		t.discard(conn)
+		t.countEvicted(evictLifetime)
+		t.emit(EventEvicted, nil)
+		return nil
+	}
+	if t.isOverBytes(conn) {
+		// 累计读写超过字节预算，关闭以便重建连接
+		t.discard(conn)
+		t.countEvicted(evictBytes)
+		t.emit(EventEvicted, nil)
+		return nil
+	}
+	if t.isOverThrottle() {
+		// 限流期间逐步关闭超出限流值的连接
+		t.discard(conn)
+		t.countEvicted(evictOverMax)
+		t.emit(EventEvicted, nil)
+		return nil
+	}
+	if t.ValidateOnReturn && !doNotNew && t.validate(t.ctx, conn) != nil {
+		// 放回时校验失败
+		t.discard(conn)
+		t.countEvicted(evictBroken)
+		t.emit(EventEvicted, nil)
+		return nil
+	}
+	if t.handOff(conn) {
+		// 有 GetPriority 在等待，直接交给它而不放回队列
 		return nil
 	}
-	idle := t.addIdle()
 	usedTime := conn.GetUsedTime()
 	var nowTime int64
 	if !doNotNew {
 		nowTime = conn.UpdateUsedTime()
 	} else {
-		nowTime = time.Now().UnixNano()
+		nowTime = t.now().UnixNano()
 	}
 
-	if idle > t.InitSize {
-		if nowTime > usedTime {
-			iTime := nowTime - usedTime
-			if iTime > int64(t.IdleTimeout) {
-				_ = conn.Close()
-				t.subIdle()
-				// 闲置连接，回收连接资源
-				return nil
-			}
-			// 创建的资源大于最大连接数时，关闭连接，回收连接资源
-			if idle > t.MaxSize {
-				_ = conn.Close()
-				t.subIdle()
-				return nil
-			}
+	// 预占队列容量，空闲连接数已达 MaxIdle（未设置时为 MaxSize）时关闭连接，回收连接资源
+	if !t.tryAddIdle() {
+		t.discard(conn)
+		t.countEvicted(evictOverMax)
+		t.emit(EventEvicted, nil)
+		return nil
+	}
+	// 放回后的空闲连接数，已包括刚预占的容量；
+	// 后台协程放回的连接不在此按闲置超时关闭，由回收协程按 EvictionOrder 统一回收
+	idle := t.GetIdle()
+	if !doNotNew && idle > t.InitSize && nowTime > usedTime {
+		iTime := nowTime - usedTime
+		if iTime > int64(t.IdleTimeout) {
+			t.subIdle()
+			t.discard(conn)
+			t.countEvicted(evictIdle)
+			t.emit(EventEvicted, nil)
+			// 闲置连接，回收连接资源
+			return nil
 		}
 	}
-	select {
-	case t.clients <- conn:
-		return nil
-	default:
-		_ = conn.Close()
+	if !t.idleConns.push(conn) {
 		t.subIdle()
-		return errors.New(fmt.Sprintf("use:%d, init:%d, idle:%d", used, t.InitSize, t.GetIdle()))
+		t.discard(conn)
+		if atomic.LoadInt32(&t.closed) == 1 {
+			return nil
+		}
+		// 已预占了队列容量，正常情况下不会走到这里
+		t.countEvicted(evictOverMax)
+		t.emit(EventEvicted, nil)
+		return t.poolError(KindIdleFull, t.GetUsed())
 	}
+	return nil
+}
+
+// 记录调用方的 Get 或 Put，后台协程（心跳、MinIdle 探测等）取放连接不计入，见 IsActive
+func (t *ThriftPool) touch() {
+	atomic.StoreInt64(&t.assessTime, t.now().Unix())
 }
 
 func (t *ThriftPool) GetAssessTime() int64 {
 	return atomic.LoadInt64(&t.assessTime)
 }
-// 关闭连接池（释放资源）
-func (t *ThriftPool) Close() {
+
+// 连接池是否仍在使用：有借出的连接，或最近 maxIdle 内调用方有过 Get 或 Put（精度为秒）
+func (t *ThriftPool) IsActive(maxIdle time.Duration) bool {
+	if t.GetUsed() > 0 {
+		return true
+	}
+	idle := t.now().Sub(time.Unix(t.GetAssessTime(), 0))
+	return idle < maxIdle
+}
+// 关闭连接池（释放资源），可重复调用，也可与 Get 并发调用
+// 关闭后 Get 返回 ErrPoolClosed，正在拨号的 Get 拨号完成后关闭新连接并返回 ErrPoolClosed
+// Close 会等待后台协程（回收、心跳、预热等）退出，因此可能阻塞至多一次拨号的时长
+// 返回值为满足 Pool 接口，总是nil
+func (t *ThriftPool) Close() error {
 	swp := atomic.CompareAndSwapInt32(&t.closed, 0, 1)
 	if !swp {
-		return
+		return nil
 	}
+	t.cancel()
+	t.deregister()
+	t.emit(EventClosed, nil)
 
-	close(t.clients)
-	for conn := range t.clients {
-		if conn == nil {
-			continue
-		}
-		_ = conn.Close()
+	// 后台协程会向队列放回连接，等其退出后再关闭队列
+	t.workers.Wait()
+	for _, conn := range t.idleConns.close() {
+		t.discard(conn)
 	}
-	t.used = 0
-	t.idle = 0
+	atomic.StoreInt32(&t.used, 0)
+	atomic.StoreInt32(&t.idle, 0)
+	return nil
+}
+
+// 返回一个在连接池关闭时被关闭的channel，用于感知连接池的关闭
+func (t *ThriftPool) Done() <-chan struct{} {
+	return t.ctx.Done()
+}
+
+// 立即关闭多余的空闲连接，直到空闲连接数不超过 InitSize，返回关闭的连接数
+// 适合在流量高峰过后手动回收连接，不必等待后台协程逐步回收，可以和 Get、Put 并发调用；关闭的顺序见 WithEvictionOrder
+func (t *ThriftPool) Shrink() int32 {
+	return t.evictIdleConns(t.GetIdle() - t.GetInitSize())
 }
 
 // 回收闲置资源
 func (t *ThriftPool) releaseIdleConn() {
+	ticker := time.NewTicker(time.Duration(1) * time.Second)
+	defer ticker.Stop()
+
 	for {
-		closed := atomic.LoadInt32(&t.closed)
-		if closed == 1 {
-			break
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
 		}
+		if t.expireIdlePool() {
+			return
+		}
+		t.releaseIdle()
+	}
+}
 
-		time.Sleep(time.Duration(1) * time.Second)
-		initSize := t.GetInitSize()
-		idleSize := t.GetIdle()
-		usedSize := t.GetUsed()
-		// 当闲置连接大于在用连接，说明连接池比较空闲
-		if idleSize > initSize && usedSize < idleSize {
-			for i:=0; i<int(idleSize); i++ {
-				conn, _ := t.get(true)
-				if conn == nil {
-					break
-				}
-				err := t.put(conn, true)
-				if err != nil {
-					fmt.Printf("relase idle Conn failed:%s\n", err.Error())
-				}
+// 回收一轮闲置超时的空闲连接，设置了 TrimPolicy 时按策略回收
+func (t *ThriftPool) releaseIdle() {
+	if t.TrimPolicy != nil {
+		t.trimIdle()
+		return
+	}
+	initSize := t.GetInitSize()
+	idleSize := t.GetIdle()
+	usedSize := t.GetUsed()
+	// 当闲置连接大于在用连接，说明连接池比较空闲
+	if idleSize > initSize && usedSize < idleSize {
+		// 闲置超时的连接数即本轮回收数，具体关闭哪些连接按 EvictionOrder 选取
+		n := t.countIdleExpired()
+		if n > idleSize-initSize {
+			n = idleSize - initSize
+		}
+		idleSize -= t.evictIdleConns(n)
+		// 其余连接逐个取出再放回，放回时按最长存活时间、字节预算等检查
+		for i:=0; i<int(idleSize); i++ {
+			conn, _ := t.get(t.ctx, true)
+			if conn == nil {
+				break
+			}
+			err := t.put(conn, true)
+			if err != nil {
+				t.logf("relase idle Conn failed:%s", err.Error())
 			}
 		}
 	}
 }
 
+// 阻塞直到所有后台协程（回收、心跳、预热、上报等）退出
+// 后台协程在连接池关闭后才退出，未关闭时调用会一直阻塞；Close 返回前已等待过，
+// 此方法主要用于并发 Close 时等待另一方完成关闭，或在测试中检查协程泄漏
+func (t *ThriftPool) WaitStopped() {
+	t.workers.Wait()
+}
+
+// 启动后台协程，协程应在 t.ctx 结束后尽快退出
+func (t *ThriftPool) goWorker(f func()) {
+	t.workers.Add(1)
+	atomic.AddInt32(&t.goroutines, 1)
+	go func() {
+		defer t.workers.Done()
+		defer atomic.AddInt32(&t.goroutines, -1)
+		f()
+	}()
+}
+
+// 运行中的后台协程数，Close 返回后为0，可用于检查后台协程是否泄漏
+func (t *ThriftPool) GoroutineCount() int32 {
+	return atomic.LoadInt32(&t.goroutines)
+}
+
 func (t *ThriftPool) addUsed() int32 {
 	return atomic.AddInt32(&t.used, 1)
 }
@@ -291,8 +953,17 @@ func (t *ThriftPool) subUsed() int32 {
 	return atomic.AddInt32(&t.used, -1)
 }
 
-func (t *ThriftPool) addIdle() int32 {
-	return atomic.AddInt32(&t.idle, 1)
+// 预占一个队列容量，空闲连接数已达队列容量时返回 false
+func (t *ThriftPool) tryAddIdle() bool {
+	for {
+		idle := atomic.LoadInt32(&t.idle)
+		if idle >= t.idleCap {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&t.idle, idle, idle+1) {
+			return true
+		}
+	}
 }
 
 func (t *ThriftPool) subIdle() int32 {
@@ -311,12 +982,16 @@ func (t *ThriftPool) GetInitSize() int32 {
 	return t.InitSize
 }
 
+func (t *ThriftPool) GetMaxIdle() int32 {
+	return t.MaxIdle
+}
+
 func (t *ThriftPool) GetMaxSize() int32 {
 	return t.MaxSize
 }
 
 func (t *ThriftPool) GetEndpoint() string {
-	return t.Endpoint
+	return t.loadBinding().endpoint
 }
 
 func (t *ThriftPool) SetIdleTimeout(timeout int32) {
@@ -335,7 +1010,22 @@ func (t *ThriftPool) SetDialTimeout(timeout int32) {
 	}
 }
 
+// 设置socket包装函数，拨号成功后调用，用于在连接可用前完成SASL等握手，
+// 返回的transport可通过 ThriftConn 的 GetTransport 取得。
+// 包装函数返回错误时，socket会被关闭，本次拨号视为失败
+func (t *ThriftPool) SetTransportWrapper(wrapper func(socket *thrift.TSocket) (thrift.TTransport, error)) {
+	t.TransportWrapper = wrapper
+}
+
+// 设置新连接的协商函数，在拨号成功、socket经 TransportWrapper、缓冲和 framed 包装之后调用，
+// 用于在连接用于业务调用前完成版本协商、交换认证令牌等自定义握手，可在 conn 上发起RPC。
+// 每个新连接只调用一次，复用的空闲连接不会再调用。ctx 为触发拨号的 Get 的 ctx，后台拨号时为连接池的 ctx。
+// 返回错误时连接被关闭，本次拨号视为失败，计入连续拨号失败次数
+func (t *ThriftPool) SetOnConnReady(onConnReady func(ctx context.Context, conn *ThriftConn) error) {
+	t.OnConnReady = onConnReady
+}
+
+// 队列中的空闲连接数，不包括已预占容量但尚未放入的连接
 func (t *ThriftPool) GetChanSize() int32 {
-	tmp := len(t.clients)
-	return int32(tmp)
+	return int32(t.idleConns.len())
 }
\ No newline at end of file
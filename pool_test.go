@@ -2,30 +2,635 @@
 package thriftpool
 
 import (
+	"context"
+	"errors"
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/tianxingpan/thriftpool/example/echo"
+	"github.com/tianxingpan/thriftpool/testutil"
+	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewThriftPool(t *testing.T) {
-	pool := NewThriftPool("127.0.0.1:9898", 3, 5, 10, 1)
-	conn1, err := pool.Get()
+	endpoint, stop := testutil.StartEchoServer(t)
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 5, 10, 1, WithFramedTransport(0))
+	conn1, err := pool.Get(context.Background())
 	if err != nil {
 		t.Errorf("The first pool.Get error:%s\n", err.Error())
 		return
 	}
-	conn2, err := pool.Get()
+	conn2, err := pool.Get(context.Background())
 	if err != nil {
 		t.Errorf("The second pool.Get error:%s\n", err.Error())
 		return
 	}
 	t.Logf("pool status, used:%d, idle:%d\n", pool.GetUsed(), pool.GetIdle())
+	for _, conn := range []*ThriftConn{conn1, conn2} {
+		res, err := echo.NewEchoClientFactory(conn.ClientFactory()).Echo(&echo.EchoReq{Msg: "hello"})
+		if err != nil {
+			t.Errorf("Echo error:%s\n", err.Error())
+		} else if res.GetMsg() != "success" {
+			t.Errorf("Echo returned %q\n", res.GetMsg())
+		}
+	}
 	err = pool.Put(conn1)
 	if err != nil {
-		t.Errorf("The second pool.Get error:%s\n", err.Error())
+		t.Errorf("The first pool.Put error:%s\n", err.Error())
 	}
 	err = pool.Put(conn2)
 	if err != nil {
-		t.Errorf("The second pool.Get error:%s\n", err.Error())
+		t.Errorf("The second pool.Put error:%s\n", err.Error())
+	}
+
+	// 归还的连接可以继续使用
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get after Put error:%s\n", err.Error())
+	}
+	if _, err := echo.NewEchoClientFactory(conn.ClientFactory()).Echo(&echo.EchoReq{Msg: "again"}); err != nil {
+		t.Errorf("Echo on reused conn error:%s\n", err.Error())
 	}
+	_ = pool.Put(conn)
 	pool.Close()
 	t.Logf("Test done")
-}
\ No newline at end of file
+}
+
+// 在 endpoint 上启动一个只接受连接、不做任何处理的TCP服务，
+// endpoint 端口为0时随机选择端口，返回实际监听的端点和关闭函数
+func startTestServer(t testing.TB, endpoint string) (string, func()) {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		t.Fatalf("listen on %s error:%s\n", endpoint, err.Error())
+	}
+	var mutex sync.Mutex
+	var conns []net.Conn
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mutex.Lock()
+			conns = append(conns, conn)
+			mutex.Unlock()
+		}
+	}()
+	return listener.Addr().String(), func() {
+		_ = listener.Close()
+		mutex.Lock()
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+		mutex.Unlock()
+	}
+}
+
+func TestConnStates(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1)
+	defer pool.Close()
+
+	// 在池中
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if err = pool.Put(conn); err != nil {
+		t.Fatalf("pool.Put error:%s\n", err.Error())
+	}
+	if pool.GetChanSize() != 1 || pool.GetIdle() != 1 || pool.GetUsed() != 0 {
+		t.Errorf("usable conn should be pooled, chan:%d, idle:%d, used:%d\n",
+			pool.GetChanSize(), pool.GetIdle(), pool.GetUsed())
+	}
+
+	// 不可用：仍计入已用连接数，直到 Put
+	conn, _ = pool.Get(context.Background())
+	if err = conn.CloseTransport(); err != nil {
+		t.Fatalf("conn.CloseTransport error:%s\n", err.Error())
+	}
+	if conn.IsUsable() || conn.IsClose() {
+		t.Errorf("conn should be unusable but not closed\n")
+	}
+	if pool.GetUsed() != 1 {
+		t.Errorf("used is %d, want 1 before Put\n", pool.GetUsed())
+	}
+	_ = pool.Put(conn)
+	if !conn.IsClose() || pool.GetChanSize() != 0 || pool.GetUsed() != 0 {
+		t.Errorf("unusable conn should be discarded, closed:%v, chan:%d, used:%d\n",
+			conn.IsClose(), pool.GetChanSize(), pool.GetUsed())
+	}
+
+	// 已关闭
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = conn.Close()
+	if conn.IsUsable() || !conn.IsClose() {
+		t.Errorf("conn should be closed\n")
+	}
+	_ = pool.Put(conn)
+	if pool.GetChanSize() != 0 || pool.GetIdle() != 0 || pool.GetUsed() != 0 {
+		t.Errorf("closed conn should be discarded, chan:%d, idle:%d, used:%d\n",
+			pool.GetChanSize(), pool.GetIdle(), pool.GetUsed())
+	}
+}
+
+// 配合 -race 运行：并发关闭和读取连接状态，只有一方真正关闭socket，其余调用返回nil
+func TestConnConcurrentClose(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1)
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if err := conn.Close(); err != nil {
+				t.Errorf("conn.Close error:%s\n", err.Error())
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := conn.CloseTransport(); err != nil {
+				t.Errorf("conn.CloseTransport error:%s\n", err.Error())
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			_ = conn.IsClose()
+			_ = conn.IsUsable()
+		}()
+	}
+	wg.Wait()
+	if !conn.IsClose() || conn.IsUsable() {
+		t.Errorf("conn should be closed\n")
+	}
+	_ = pool.Put(conn)
+	if pool.GetUsed() != 0 || pool.GetOpen() != 0 {
+		t.Errorf("closed conn should be discarded, used:%d, open:%d\n", pool.GetUsed(), pool.GetOpen())
+	}
+}
+
+func TestDone(t *testing.T) {
+	pool := NewThriftPool("127.0.0.1:9898", 3, 5, 10, 1)
+	select {
+	case <-pool.Done():
+		t.Fatalf("Done should block before Close\n")
+	default:
+	}
+	pool.Close()
+	select {
+	case <-pool.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Done should be closed after Close\n")
+	}
+}
+
+func TestGetIdleOnly(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1)
+	defer pool.Close()
+
+	conn, err := pool.GetIdleOnly(context.Background())
+	if err != ErrNoIdle || conn != nil {
+		t.Fatalf("GetIdleOnly on empty pool got (%v, %v), want ErrNoIdle\n", conn, err)
+	}
+	if pool.GetUsed() != 0 {
+		t.Errorf("used is %d, want 0 after ErrNoIdle\n", pool.GetUsed())
+	}
+
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+	idleConn, err := pool.GetIdleOnly(context.Background())
+	if err != nil {
+		t.Fatalf("GetIdleOnly error:%s\n", err.Error())
+	}
+	if idleConn != conn {
+		t.Errorf("GetIdleOnly should reuse the idle conn\n")
+	}
+	if pool.GetUsed() != 1 || pool.GetIdle() != 0 {
+		t.Errorf("used:%d, idle:%d, want used:1, idle:0\n", pool.GetUsed(), pool.GetIdle())
+	}
+	_ = pool.Put(idleConn)
+}
+
+func TestAgeHistogram(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1)
+	defer pool.Close()
+
+	conn1, _ := pool.Get(context.Background())
+	conn2, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	conn1.createdTime = time.Now().Add(-30 * time.Second)
+	_ = pool.Put(conn1)
+	_ = pool.Put(conn2)
+
+	histogram := pool.GetAgeHistogram()
+	if histogram.Under1s != 1 || histogram.Under1m != 1 {
+		t.Errorf("unexpected age histogram:%+v\n", histogram)
+	}
+	if pool.GetIdle() != 2 || pool.GetChanSize() != 2 {
+		t.Errorf("idle conns should be restored, idle:%d, chan:%d\n", pool.GetIdle(), pool.GetChanSize())
+	}
+}
+
+func TestAgeHistogramConcurrentGet(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	// 连接都已打开且空闲，统计期间 Get 必须取到空闲连接，不能因队列被取空而报满
+	pool := NewThriftPool(endpoint, 100, 60000, 2, 0)
+	defer pool.Close()
+	conn1, _ := pool.Get(context.Background())
+	conn2, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn1)
+	_ = pool.Put(conn2)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_ = pool.GetAgeHistogram()
+			}
+		}
+	}()
+	for i := 0; i < 500; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Errorf("pool.Get error during histogram:%s\n", err.Error())
+			break
+		}
+		_ = pool.Put(conn)
+	}
+	close(done)
+	wg.Wait()
+	if pool.GetOpen() != 2 || pool.GetIdle() != 2 {
+		t.Errorf("open:%d, idle:%d, want 2, 2\n", pool.GetOpen(), pool.GetIdle())
+	}
+}
+
+func TestTransportWrapper(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1)
+	defer pool.Close()
+
+	handshakeErr := errors.New("handshake failed")
+	var wrappedSocket *thrift.TSocket
+	pool.SetTransportWrapper(func(socket *thrift.TSocket) (thrift.TTransport, error) {
+		wrappedSocket = socket
+		return nil, handshakeErr
+	})
+	if _, err := pool.Get(context.Background()); err != handshakeErr {
+		t.Fatalf("pool.Get error is %v, want handshake error\n", err)
+	}
+	if wrappedSocket.IsOpen() {
+		t.Errorf("socket should be closed after failed handshake\n")
+	}
+	if pool.GetUsed() != 0 || pool.GetDialFailures() != 1 {
+		t.Errorf("used:%d, dial failures:%d, want 0 and 1\n", pool.GetUsed(), pool.GetDialFailures())
+	}
+
+	transport := thrift.NewTFramedTransport(thrift.NewTMemoryBuffer())
+	pool.SetTransportWrapper(func(socket *thrift.TSocket) (thrift.TTransport, error) {
+		return transport, nil
+	})
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if conn.GetTransport() != transport {
+		t.Errorf("conn should carry the wrapped transport\n")
+	}
+	_ = pool.Put(conn)
+}
+
+func TestPutWrongEndpoint(t *testing.T) {
+	endpoint1, stop1 := startTestServer(t, "127.0.0.1:0")
+	defer stop1()
+	endpoint2, stop2 := startTestServer(t, "127.0.0.1:0")
+	defer stop2()
+	pool1 := NewThriftPool(endpoint1, 100, 60000, 10, 1)
+	defer pool1.Close()
+	pool2 := NewThriftPool(endpoint2, 100, 60000, 10, 1)
+	defer pool2.Close()
+
+	conn, err := pool1.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	err = pool2.Put(conn)
+	if !errors.Is(err, ErrWrongEndpoint) {
+		t.Fatalf("pool.Put error is %v, want ErrWrongEndpoint\n", err)
+	}
+	if !conn.IsClose() {
+		t.Errorf("foreign conn should be closed\n")
+	}
+	if pool2.GetIdle() != 0 || pool2.GetUsed() != 0 || pool2.GetOpen() != 0 {
+		t.Errorf("foreign conn should not touch pool2, idle:%d, used:%d, open:%d\n",
+			pool2.GetIdle(), pool2.GetUsed(), pool2.GetOpen())
+	}
+	if pool1.GetUsed() != 0 || pool1.GetOpen() != 0 {
+		t.Errorf("pool1 should get its counters back, used:%d, open:%d\n", pool1.GetUsed(), pool1.GetOpen())
+	}
+}
+
+func TestPutForeignConnSameEndpoint(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool1 := NewThriftPool(endpoint, 100, 60000, 10, 0)
+	defer pool1.Close()
+	pool2 := pool1.Clone()
+	defer pool2.Close()
+
+	conn, err := pool1.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	err = pool2.Put(conn)
+	if !errors.Is(err, ErrForeignConn) {
+		t.Fatalf("pool.Put error is %v, want ErrForeignConn\n", err)
+	}
+	if !conn.IsClose() {
+		t.Errorf("foreign conn should be closed\n")
+	}
+	if pool2.GetIdle() != 0 || pool2.GetUsed() != 0 || pool2.GetOpen() != 0 {
+		t.Errorf("foreign conn should not touch pool2, idle:%d, used:%d, open:%d\n",
+			pool2.GetIdle(), pool2.GetUsed(), pool2.GetOpen())
+	}
+	if pool1.GetUsed() != 0 || pool1.GetOpen() != 0 || pool1.GetIdle() != 0 {
+		t.Errorf("pool1 should get its counters back, used:%d, open:%d, idle:%d\n",
+			pool1.GetUsed(), pool1.GetOpen(), pool1.GetIdle())
+	}
+}
+
+func TestShrink(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 2)
+	defer pool.Close()
+
+	conns := make([]*ThriftConn, 0, 5)
+	for i := 0; i < 5; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		_ = pool.Put(conn)
+	}
+	if closed := pool.Shrink(); closed != 3 {
+		t.Errorf("Shrink closed %d conns, want 3\n", closed)
+	}
+	if pool.GetIdle() != 2 || pool.GetChanSize() != 2 || pool.GetOpen() != 2 {
+		t.Errorf("idle:%d, chan:%d, open:%d, want 2 after Shrink\n",
+			pool.GetIdle(), pool.GetChanSize(), pool.GetOpen())
+	}
+	if closed := pool.Shrink(); closed != 0 {
+		t.Errorf("second Shrink closed %d conns, want 0\n", closed)
+	}
+}
+
+func TestNetConn(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1)
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	netConn, ok := conn.NetConn()
+	if !ok || netConn.RemoteAddr().String() != endpoint {
+		t.Errorf("NetConn should expose the peer address %s\n", endpoint)
+	}
+	_ = conn.Close()
+	if _, ok = conn.NetConn(); ok {
+		t.Errorf("NetConn of a closed conn should not be available\n")
+	}
+	_ = pool.Put(conn)
+}
+
+func TestPutIdleFull(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1, WithMaxIdle(2))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	// 绕过空闲连接计数直接塞满队列，模拟计数和队列不一致的竞争
+	for i := 0; i < 2; i++ {
+		other, err := pool.dial(context.Background())
+		if err != nil {
+			t.Fatalf("pool.dial error:%s\n", err.Error())
+		}
+		pool.idleConns.push(other)
+	}
+
+	err = pool.Put(conn)
+	if !errors.Is(err, ErrIdleFull) {
+		t.Fatalf("pool.Put error is %v, want ErrIdleFull\n", err)
+	}
+	if !conn.IsClose() {
+		t.Errorf("conn should be closed when the idle queue is full\n")
+	}
+	if pool.GetIdle() != 0 || pool.GetUsed() != 0 || pool.GetOpen() != 2 {
+		t.Errorf("idle:%d, used:%d, open:%d, want 0, 0, 2\n", pool.GetIdle(), pool.GetUsed(), pool.GetOpen())
+	}
+}
+
+func TestCloseWhileGet(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 50, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := pool.Get(context.Background())
+			if err != nil {
+				if !errors.Is(err, ErrPoolClosed) {
+					t.Errorf("unexpected Get error:%s\n", err)
+				}
+				return
+			}
+			if conn == nil {
+				t.Errorf("Get returned nil conn without error\n")
+				return
+			}
+			_ = pool.Put(conn)
+		}()
+	}
+	pool.Close()
+	pool.Close()
+	wg.Wait()
+
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Get after Close should return ErrPoolClosed, got %v\n", err)
+	}
+}
+
+func TestZeroInitSize(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 100, 10, 0)
+	defer pool.Close()
+	if pool.GetInitSize() != 0 {
+		t.Fatalf("InitSize should be 0, got %d\n", pool.GetInitSize())
+	}
+
+	conns := make([]*ThriftConn, 0, 3)
+	for i := 0; i < 3; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		_ = pool.Put(conn)
+	}
+	if pool.GetIdle() != 3 {
+		t.Errorf("recently returned conns should stay idle, got %d\n", pool.GetIdle())
+	}
+
+	// 闲置超时后由回收协程全部回收
+	deadline := time.Now().Add(3 * time.Second)
+	for pool.GetOpen() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if pool.GetIdle() != 0 || pool.GetOpen() != 0 {
+		t.Errorf("idle InitSize=0 pool should reap to zero, idle:%d, open:%d\n", pool.GetIdle(), pool.GetOpen())
+	}
+}
+
+func TestErrPoolExhausted(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	for _, detailed := range []bool{false, true} {
+		pool := NewThriftPool(endpoint, 1000, 60000, 1, 0, WithDetailedErrors(detailed))
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		_, err = pool.Get(context.Background())
+		if !errors.Is(err, ErrPoolExhausted) {
+			t.Errorf("exhausted pool should return ErrPoolExhausted, got %v\n", err)
+		}
+		if detailed != (err != ErrPoolExhausted) {
+			t.Errorf("detailed:%v, unexpected error:%v\n", detailed, err)
+		}
+		_ = pool.Put(conn)
+		pool.Close()
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	// 拨号失败：包装了原始错误并带上端点
+	endpoint := refusedEndpoint(t)
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 0)
+	_, err := pool.Get(context.Background())
+	var transportErr thrift.TTransportException
+	if !errors.As(err, &transportErr) || !IsConnectionError(err) {
+		t.Errorf("dial error should wrap the transport error, got %v\n", err)
+	}
+	if err == nil || !strings.HasPrefix(err.Error(), "thriftpool dial "+endpoint+": ") {
+		t.Errorf("dial error should name the endpoint, got %v\n", err)
+	}
+
+	// 连接池已关闭
+	pool.Close()
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("closed pool should return ErrPoolClosed, got %v\n", err)
+	}
+
+	// 等待拨号名额超时
+	blockDial(t)
+	pool = NewThriftPool("127.0.0.1:9898", 50, 60000, 10, 0, WithMaxConcurrentDials(1))
+	defer pool.Close()
+	holdCtx, release := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = pool.Get(holdCtx)
+	}()
+	for pool.GetDialing() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrTooManyDials) {
+		t.Errorf("Get waiting too long for a dial slot should return ErrTooManyDials, got %v\n", err)
+	}
+	release()
+	<-done
+}
+
+func TestWaitStopped(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	heartbeat := func(ctx context.Context, conn *ThriftConn) error {
+		return nil
+	}
+	pool := NewThriftPool(endpoint, 2000, 60000, 5, 1, WithHeartbeat(heartbeat, 10, 100))
+
+	stopped := make(chan struct{})
+	go func() {
+		pool.WaitStopped()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatalf("WaitStopped should block before Close\n")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if pool.Stats().Goroutines != 2 {
+		t.Errorf("reaper and heartbeat should be running, goroutines:%d\n", pool.GoroutineCount())
+	}
+	pool.Close()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Errorf("WaitStopped should return after Close\n")
+	}
+	if pool.GoroutineCount() != 0 {
+		t.Errorf("goroutines should stop after Close, got %d\n", pool.GoroutineCount())
+	}
+}
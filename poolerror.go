@@ -0,0 +1,80 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 已打开的连接数已达 MaxSize，无法再拨号
+var ErrPoolFull = errors.New("thriftpool full")
+
+// PoolError 的失败类型
+type PoolErrorKind int
+
+const (
+	KindExhausted	PoolErrorKind = iota	// 已用连接数超过 MaxSize，对应 ErrPoolExhausted
+	KindFull								// 已打开的连接数已达 MaxSize，对应 ErrPoolFull
+	KindIdleFull							// 放回时空闲队列已满，对应 ErrIdleFull
+)
+
+func (k PoolErrorKind) String() string {
+	switch k {
+	case KindExhausted:
+		return "exhausted"
+	case KindFull:
+		return "full"
+	case KindIdleFull:
+		return "idle_full"
+	default:
+		return "unknown"
+	}
+}
+
+// 带失败时连接池状态的错误，可用 errors.As 取出各项计数，用 errors.Is 判断对应的哨兵错误
+type PoolError struct {
+	Kind		PoolErrorKind	// 失败类型
+	Endpoint	string			// 服务端的端点
+	Used		int32			// 失败时的已用连接数
+	Idle		int32			// 失败时的空闲连接数
+	Open		int32			// 失败时已打开的连接数
+	InitSize	int32			// 初始连接数
+	MaxSize		int32			// 最大连接数
+}
+
+// 记录连接池当前的状态，used 为失败时的已用连接数
+func (t *ThriftPool) poolError(kind PoolErrorKind, used int32) *PoolError {
+	return &PoolError{
+		Kind:     kind,
+		Endpoint: t.GetEndpoint(),
+		Used:     used,
+		Idle:     t.GetIdle(),
+		Open:     t.GetOpen(),
+		InitSize: t.InitSize,
+		MaxSize:  t.MaxSize,
+	}
+}
+
+func (e *PoolError) Error() string {
+	switch e.Kind {
+	case KindFull:
+		return fmt.Sprintf("%s, open:%d, max:%d", ErrPoolFull.Error(), e.Open, e.MaxSize)
+	case KindIdleFull:
+		return fmt.Sprintf("%s: used:%d, init:%d, idle:%d", ErrIdleFull.Error(), e.Used, e.InitSize, e.Idle)
+	default:
+		return fmt.Sprintf("%s, used:%d, idle:%d, init:%d, max:%d",
+			ErrPoolExhausted.Error(), e.Used, e.Idle, e.InitSize, e.MaxSize)
+	}
+}
+
+// 返回失败类型对应的哨兵错误
+func (e *PoolError) Unwrap() error {
+	switch e.Kind {
+	case KindFull:
+		return ErrPoolFull
+	case KindIdleFull:
+		return ErrIdleFull
+	default:
+		return ErrPoolExhausted
+	}
+}
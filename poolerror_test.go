@@ -0,0 +1,83 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPoolErrorExhausted(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 1, 0, WithDetailedErrors(true), WithName("billing"))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	defer pool.Put(conn)
+	_, err = pool.Get(context.Background())
+	var perr *PoolError
+	if !errors.As(err, &perr) {
+		t.Fatalf("exhausted pool should return *PoolError, got %v\n", err)
+	}
+	if perr.Kind != KindExhausted || perr.Endpoint != endpoint || perr.Used != 2 || perr.Idle != 0 || perr.MaxSize != 1 {
+		t.Errorf("unexpected pool error:%+v\n", *perr)
+	}
+	if !errors.Is(err, ErrPoolExhausted) || errors.Is(err, ErrIdleFull) {
+		t.Errorf("pool error should unwrap to ErrPoolExhausted only:%s\n", err.Error())
+	}
+	if !strings.HasPrefix(perr.Error(), "thriftpool empty, used:2") {
+		t.Errorf("unexpected message:%s\n", perr.Error())
+	}
+}
+
+func TestPoolErrorIdleFull(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1, WithMaxIdle(1))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	other, err := pool.dial(context.Background())
+	if err != nil {
+		t.Fatalf("pool.dial error:%s\n", err.Error())
+	}
+	pool.idleConns.push(other)
+
+	err = pool.Put(conn)
+	var perr *PoolError
+	if !errors.As(err, &perr) || perr.Kind != KindIdleFull || !errors.Is(err, ErrIdleFull) {
+		t.Fatalf("full idle queue should return an idle_full *PoolError, got %v\n", err)
+	}
+	if perr.InitSize != 1 || perr.MaxSize != 10 || perr.Kind.String() != "idle_full" {
+		t.Errorf("unexpected pool error:%+v\n", *perr)
+	}
+}
+
+func TestPoolErrorFull(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 1, 0)
+	defer pool.Close()
+
+	conn, err := pool.dial(context.Background())
+	if err != nil {
+		t.Fatalf("pool.dial error:%s\n", err.Error())
+	}
+	defer conn.Close()
+	_, err = pool.dial(context.Background())
+	var perr *PoolError
+	if !errors.As(err, &perr) || perr.Kind != KindFull || !errors.Is(err, ErrPoolFull) {
+		t.Fatalf("dial beyond max should return a full *PoolError, got %v\n", err)
+	}
+	if perr.Error() != "thriftpool full, open:1, max:1" {
+		t.Errorf("unexpected message:%s\n", perr.Error())
+	}
+}
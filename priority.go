@@ -0,0 +1,163 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// GetPriority 的优先级
+type Priority int
+
+const (
+	PriorityLow		Priority = iota	// 批量等对延迟不敏感的请求
+	PriorityHigh					// 交互等对延迟敏感的请求
+	priorities
+)
+
+// 连接池耗尽时等待连接的 GetPriority，按优先级分为两个先进先出的队列
+type waitQueue struct {
+	mutex	sync.Mutex
+	queues	[priorities][]chan *ThriftConn
+	waiting	int32	// 等待者总数，为0时 Put 无需加锁
+}
+
+// 登记一个等待者，返回用于唤醒它的channel
+func (q *waitQueue) add(priority Priority) chan *ThriftConn {
+	ch := make(chan *ThriftConn, 1)
+	q.mutex.Lock()
+	q.queues[priority] = append(q.queues[priority], ch)
+	atomic.AddInt32(&q.waiting, 1)
+	q.mutex.Unlock()
+	return ch
+}
+
+// 移除尚未被唤醒的等待者，已被唤醒时返回 false，此时唤醒它的连接或nil已经或即将写入 ch
+func (q *waitQueue) remove(priority Priority, ch chan *ThriftConn) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	queue := q.queues[priority]
+	for i, waiter := range queue {
+		if waiter == ch {
+			q.queues[priority] = append(queue[:i], queue[i+1:]...)
+			atomic.AddInt32(&q.waiting, -1)
+			return true
+		}
+	}
+	return false
+}
+
+// 取出优先级最高、等待最久的等待者，没有等待者时返回nil
+func (q *waitQueue) pop() chan *ThriftConn {
+	if atomic.LoadInt32(&q.waiting) == 0 {
+		return nil
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for priority := priorities - 1; priority >= 0; priority-- {
+		queue := q.queues[priority]
+		if len(queue) > 0 {
+			ch := queue[0]
+			queue[0] = nil
+			q.queues[priority] = queue[1:]
+			atomic.AddInt32(&q.waiting, -1)
+			return ch
+		}
+	}
+	return nil
+}
+
+// 等待连接的 GetPriority 数
+func (t *ThriftPool) GetWaiting() int32 {
+	return atomic.LoadInt32(&t.waiters.waiting)
+}
+
+// 按优先级借出连接。有空闲连接或可以拨号时同 Get；连接池耗尽（Get 返回 ErrPoolExhausted）时不立即失败，
+// 而是排队等待其它协程归还连接或释放连接名额，直到 ctx 结束或连接池关闭。
+// ctx 结束时返回包装了 ctx.Err() 的错误，可用 errors.Is 区分调用方取消（context.Canceled）和超时（context.DeadlineExceeded）。
+// 公平性保证：
+// 1) 归还的连接直接交给等待者，高优先级的等待者总是先于低优先级的等待者，同一优先级内先到先得；
+// 2) 连接被关闭而释放名额时，同样按上述顺序唤醒一个等待者去拨号，因此批量请求不会抢在交互请求之前拨号；
+// 3) 不排队的 Get 和新到的 GetPriority 在有空闲连接或名额时直接取用，不会让给等待者；
+// 4) 高优先级的请求持续耗尽连接池时，低优先级的等待者可能一直等到 ctx 结束；
+// 5) 被唤醒去拨号而再次遇到耗尽的等待者重新排到队尾。
+func (t *ThriftPool) GetPriority(ctx context.Context, priority Priority) (*ThriftConn, error) {
+	if priority < PriorityLow || priority >= priorities {
+		priority = PriorityLow
+	}
+	ctx, cancel := t.withPoolContext(ctx)
+	defer cancel()
+	for {
+		conn, err := t.Get(ctx)
+		if !errors.Is(err, ErrPoolExhausted) {
+			return conn, err
+		}
+		ch := t.waiters.add(priority)
+		// 登记之前归还的连接或释放的名额不会唤醒本等待者，登记后再检查一次
+		if t.GetIdle() > 0 || t.GetOpen() < t.GetEffectiveMaxSize() {
+			if t.waiters.remove(priority, ch) {
+				continue
+			}
+		}
+		select {
+		case conn := <-ch:
+			if conn == nil {
+				// 有连接名额被释放，重新尝试拨号
+				continue
+			}
+			return t.borrowHandedOff(conn), nil
+		case <-ctx.Done():
+			t.leaveWaiters(priority, ch)
+			return nil, t.nameErr(fmt.Errorf("%w: wait for connection, used:%d, max:%d, waiting:%d",
+				ctx.Err(), t.GetUsed(), t.MaxSize, t.GetWaiting()))
+		case <-t.ctx.Done():
+			t.leaveWaiters(priority, ch)
+			return nil, t.nameErr(ErrPoolClosed)
+		}
+	}
+}
+
+// 把 Put 直接交给等待者的连接作为借出的连接
+func (t *ThriftPool) borrowHandedOff(conn *ThriftConn) *ThriftConn {
+	t.addUsed()
+	t.addEndpointUsed(conn)
+	conn.tag = ""
+	t.markInUse(conn)
+	t.startWatchdog(conn)
+	t.emit(EventBorrowed, nil)
+	return conn
+}
+
+// 放弃等待，已被唤醒时把收到的连接放回池或把唤醒转给下一个等待者
+func (t *ThriftPool) leaveWaiters(priority Priority, ch chan *ThriftConn) {
+	if t.waiters.remove(priority, ch) {
+		return
+	}
+	if conn := <-ch; conn != nil {
+		t.addUsed()
+		t.addEndpointUsed(conn)
+		_ = t.put(conn, false)
+	} else {
+		t.wakeWaiter()
+	}
+}
+
+// 把归还的连接直接交给等待者，没有等待者时返回 false
+func (t *ThriftPool) handOff(conn *ThriftConn) bool {
+	ch := t.waiters.pop()
+	if ch == nil {
+		return false
+	}
+	ch <- conn
+	return true
+}
+
+// 连接名额被释放后唤醒一个等待者去拨号
+func (t *ThriftPool) wakeWaiter() {
+	if ch := t.waiters.pop(); ch != nil {
+		ch <- nil
+	}
+}
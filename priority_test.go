@@ -0,0 +1,117 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 等待直到有 n 个 GetPriority 在排队
+func waitWaiting(t *testing.T, pool *ThriftPool, n int32) {
+	deadline := time.Now().Add(time.Second)
+	for pool.GetWaiting() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiters, got %d\n", n, pool.GetWaiting())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type priorityResult struct {
+	conn	*ThriftConn
+	err		error
+}
+
+func getPriorityAsync(pool *ThriftPool, ctx context.Context, priority Priority) chan priorityResult {
+	result := make(chan priorityResult, 1)
+	go func() {
+		conn, err := pool.GetPriority(ctx, priority)
+		result <- priorityResult{conn, err}
+	}()
+	return result
+}
+
+func TestGetPriority(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 1, 0)
+	defer pool.Close()
+
+	ctx := context.Background()
+	held, err := pool.GetPriority(ctx, PriorityHigh)
+	if err != nil {
+		t.Fatalf("GetPriority failed:%s\n", err)
+	}
+
+	// 低优先级先到，高优先级后到，归还的连接先交给高优先级
+	low := getPriorityAsync(pool, ctx, PriorityLow)
+	waitWaiting(t, pool, 1)
+	high := getPriorityAsync(pool, ctx, PriorityHigh)
+	waitWaiting(t, pool, 2)
+
+	_ = pool.Put(held)
+	r := <-high
+	if r.err != nil || r.conn != held {
+		t.Fatalf("high priority waiter should get the returned conn, got %v\n", r.err)
+	}
+	if pool.GetUsed() != 1 || pool.GetIdle() != 0 || pool.GetWaiting() != 1 {
+		t.Errorf("handed off conn should stay in use, used:%d idle:%d waiting:%d\n",
+			pool.GetUsed(), pool.GetIdle(), pool.GetWaiting())
+	}
+
+	// 连接被关闭释放名额时，唤醒的等待者重新拨号
+	_ = r.conn.CloseTransport()
+	_ = pool.Put(r.conn)
+	r = <-low
+	if r.err != nil || r.conn == nil || r.conn == held {
+		t.Fatalf("low priority waiter should dial a new conn, got %v\n", r.err)
+	}
+
+	// 等待超时
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.GetPriority(timeoutCtx, PriorityHigh); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiter should give up when ctx expires, got %v\n", err)
+	}
+	if pool.GetWaiting() != 0 {
+		t.Errorf("expired waiter should leave the queue, waiting:%d\n", pool.GetWaiting())
+	}
+	canceledCtx, cancelWait := context.WithCancel(ctx)
+	time.AfterFunc(20*time.Millisecond, cancelWait)
+	if _, err := pool.GetPriority(canceledCtx, PriorityLow); !errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("cancelled waiter should return context.Canceled, got %v\n", err)
+	}
+	if pool.GetWaiting() != 0 {
+		t.Errorf("cancelled waiter should leave the queue, waiting:%d\n", pool.GetWaiting())
+	}
+
+	// 不排队的 Get 仍然立即失败
+	if _, err := pool.Get(ctx); !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("Get should not wait, got %v\n", err)
+	}
+	_ = pool.Put(r.conn)
+	if pool.GetIdle() != 1 || pool.GetUsed() != 0 {
+		t.Errorf("conn should go back to idle without waiters, idle:%d used:%d\n", pool.GetIdle(), pool.GetUsed())
+	}
+}
+
+func TestGetPriorityClose(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 1, 0)
+
+	held, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get failed:%s\n", err)
+	}
+	waiter := getPriorityAsync(pool, context.Background(), PriorityLow)
+	waitWaiting(t, pool, 1)
+	pool.Close()
+	if r := <-waiter; !errors.Is(r.err, ErrPoolClosed) {
+		t.Errorf("waiter should fail when the pool closes, got %v\n", r.err)
+	}
+	_ = pool.Put(held)
+}
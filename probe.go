@@ -0,0 +1,93 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// 未设置 MaxProbes 时同时借出的探测连接的最大数
+const DefaultMaxProbes = 1
+
+// 同时借出的探测连接已达 MaxProbes
+var ErrTooManyProbes = errors.New("thriftpool too many probes")
+
+// 设置同时借出的探测连接的最大数，小于1时为 DefaultMaxProbes，参见 GetProbe
+func WithMaxProbes(maxProbes int32) Option {
+	return func(t *ThriftPool) {
+		t.MaxProbes = maxProbes
+	}
+}
+
+// 借出一个探测连接，用于健康检查等试探连接是否可用的场景，不占用业务的连接容量
+// 有空闲连接时取一个空闲连接，否则新建一个不占用连接名额的连接；探测连接不计入已用连接数，
+// 不受 MaxSize 限制，也不启用看门狗，Put 时总是关闭而不放回队列。
+// 同时借出的探测连接不超过 MaxProbes，超出时返回 ErrTooManyProbes
+func (t *ThriftPool) GetProbe(ctx context.Context) (*ThriftConn, error) {
+	if atomic.LoadInt32(&t.closed) == 1 || atomic.LoadInt32(&t.draining) == 1 {
+		return nil, t.nameErr(ErrPoolClosed)
+	}
+	if !t.tryReserveProbe() {
+		return nil, t.nameErr(ErrTooManyProbes)
+	}
+	if conn := t.idleConns.popFront(); conn != nil {
+		t.subIdle()
+		if !t.isStale(conn) {
+			conn.probe = true
+			t.markInUse(conn)
+			return conn, nil
+		}
+		t.discard(conn)
+		t.countEvicted(evictStale)
+		t.emit(EventEvicted, nil)
+	}
+	if err := ctx.Err(); err != nil {
+		t.releaseProbe()
+		return nil, t.nameErr(err)
+	}
+	poolCtx, cancel := t.withPoolContext(ctx)
+	conn, err := t.connect(poolCtx)
+	cancel()
+	if err != nil {
+		t.releaseProbe()
+		return nil, t.nameErr(err)
+	}
+	// 新建的探测连接不占用连接名额
+	conn.slot = 0
+	conn.probe = true
+	t.markInUse(conn)
+	return conn, nil
+}
+
+// 借出中的探测连接数
+func (t *ThriftPool) GetProbes() int32 {
+	return atomic.LoadInt32(&t.probes)
+}
+
+// 同时借出的探测连接的最大数
+func (t *ThriftPool) GetMaxProbes() int32 {
+	if t.MaxProbes < 1 {
+		return DefaultMaxProbes
+	}
+	return t.MaxProbes
+}
+
+// 预占一个探测名额，已达 MaxProbes 时返回 false
+func (t *ThriftPool) tryReserveProbe() bool {
+	maxProbes := t.GetMaxProbes()
+	for {
+		probes := atomic.LoadInt32(&t.probes)
+		if probes >= maxProbes {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&t.probes, probes, probes+1) {
+			return true
+		}
+	}
+}
+
+// 释放一个探测名额
+func (t *ThriftPool) releaseProbe() {
+	atomic.AddInt32(&t.probes, -1)
+}
@@ -0,0 +1,60 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetProbe(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 2, 0)
+	defer pool.Close()
+
+	ctx := context.Background()
+	conn1, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed:%s\n", err)
+	}
+	conn2, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed:%s\n", err)
+	}
+
+	// 连接池已满时探测连接仍可借出
+	probe, err := pool.GetProbe(ctx)
+	if err != nil {
+		t.Fatalf("GetProbe on an exhausted pool failed:%s\n", err)
+	}
+	if pool.GetUsed() != 2 || pool.GetOpen() != 2 || pool.GetProbes() != 1 {
+		t.Errorf("probe should not count as used, got used:%d open:%d probes:%d\n",
+			pool.GetUsed(), pool.GetOpen(), pool.GetProbes())
+	}
+	if _, err := pool.GetProbe(ctx); !errors.Is(err, ErrTooManyProbes) {
+		t.Errorf("second probe should fail with ErrTooManyProbes, got %v\n", err)
+	}
+	if err := pool.Put(probe); err != nil {
+		t.Errorf("Put probe failed:%s\n", err)
+	}
+	if !probe.IsClose() || pool.GetProbes() != 0 || pool.GetIdle() != 0 || pool.GetOpen() != 2 {
+		t.Errorf("probe should be closed on Put, got probes:%d idle:%d open:%d\n",
+			pool.GetProbes(), pool.GetIdle(), pool.GetOpen())
+	}
+
+	// 有空闲连接时探测连接取自空闲队列，归还时关闭并释放连接名额
+	_ = pool.Put(conn1)
+	probe, err = pool.GetProbe(ctx)
+	if err != nil {
+		t.Fatalf("GetProbe failed:%s\n", err)
+	}
+	if probe != conn1 || pool.GetIdle() != 0 || pool.GetUsed() != 1 {
+		t.Errorf("probe should take the idle connection, got idle:%d used:%d\n", pool.GetIdle(), pool.GetUsed())
+	}
+	_ = pool.Put(probe)
+	if pool.GetOpen() != 1 {
+		t.Errorf("probe taken from idle should release its slot, got open:%d\n", pool.GetOpen())
+	}
+	_ = pool.Put(conn2)
+}
@@ -0,0 +1,61 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 新连接的 transport 工厂，参见 SetTransportFactory
+func WithTransportFactory(factory thrift.TTransportFactory) Option {
+	return func(t *ThriftPool) {
+		t.SetTransportFactory(factory)
+	}
+}
+
+// 连接的 protocol 工厂，参见 SetProtocolFactory
+func WithProtocolFactory(factory thrift.TProtocolFactory) Option {
+	return func(t *ThriftPool) {
+		t.SetProtocolFactory(factory)
+	}
+}
+
+// 设置 transport 工厂，拨号时在 TransportWrapper、缓冲和 framed 等包装之外再包一层，
+// 每个连接只包装一次，结果可通过 GetTransport 取得；为nil时不包装（默认）。
+// framed 应优先使用 WithFramedTransport，该工厂用于连接池未内置的 transport。只影响之后拨号的连接
+func (t *ThriftPool) SetTransportFactory(factory thrift.TTransportFactory) {
+	t.TransportFactory = factory
+}
+
+// 设置 protocol 工厂，Protocols 和 ClientFactory 使用它创建 protocol；为nil时使用 binary protocol（默认）。
+// 已缓存了 protocol 的连接不受影响
+func (t *ThriftPool) SetProtocolFactory(factory thrift.TProtocolFactory) {
+	t.ProtocolFactory = factory
+}
+
+// 连接池的 protocol 工厂，未设置时为默认的 binary protocol
+func (t *ThriftPool) protocolFactory() thrift.TProtocolFactory {
+	if t == nil || t.ProtocolFactory == nil {
+		return thrift.NewTBinaryProtocolFactoryDefault()
+	}
+	return t.ProtocolFactory
+}
+
+// 按 TransportFactory 包装新连接的 transport
+func (t *ThriftPool) wrapFactory(conn *ThriftConn) {
+	if t.TransportFactory == nil {
+		return
+	}
+	conn.transport = t.TransportFactory.GetTransport(conn.transport)
+}
+
+// 返回在 GuardedTransport 上按连接池的 protocol 工厂创建的 protocol，首次调用时创建并缓存在连接上，
+// 之后同一连接上的调用（包括之后的借出）复用同一个 protocol，in 和 out 为同一个实例，可直接传给生成代码：
+//   in, out := conn.Protocols()
+//   client := echo.NewEchoClientProtocol(conn.GuardedTransport(), in, out)
+// protocol 不保存 SeqId，可以跨借出复用；客户端仍应每次借出新建，参见 ClientFactory
+func (t *ThriftConn) Protocols() (in, out thrift.TProtocol) {
+	if t.protocol == nil {
+		t.protocol = t.pool.protocolFactory().GetProtocol(t.GuardedTransport())
+	}
+	return t.protocol, t.protocol
+}
@@ -0,0 +1,78 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/tianxingpan/thriftpool/example/echo"
+	"github.com/tianxingpan/thriftpool/testutil"
+)
+
+// 记录创建 protocol 的次数
+type countingProtocolFactory struct {
+	thrift.TProtocolFactory
+	created	int32
+}
+
+func (f *countingProtocolFactory) GetProtocol(trans thrift.TTransport) thrift.TProtocol {
+	atomic.AddInt32(&f.created, 1)
+	return f.TProtocolFactory.GetProtocol(trans)
+}
+
+func TestProtocols(t *testing.T) {
+	endpoint, stop := testutil.StartEchoServer(t)
+	defer stop()
+
+	protocolFactory := &countingProtocolFactory{TProtocolFactory: thrift.NewTBinaryProtocolFactoryDefault()}
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0,
+		WithTransportFactory(thrift.NewTFramedTransportFactory(thrift.NewTTransportFactory())),
+		WithProtocolFactory(protocolFactory))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	// 同一个连接上的两次调用复用 protocol
+	for _, msg := range []string{"hello", "again"} {
+		in, out := conn.Protocols()
+		client := echo.NewEchoClientProtocol(conn.GuardedTransport(), in, out)
+		res, err := client.Echo(&echo.EchoReq{Msg: msg})
+		if err != nil {
+			t.Fatalf("Echo %s error:%s\n", msg, err.Error())
+		}
+		if res.GetMsg() != "success" {
+			t.Errorf("Echo %s returned %q\n", msg, res.GetMsg())
+		}
+	}
+	in, out := conn.Protocols()
+	if in != out {
+		t.Errorf("in and out should be the same protocol\n")
+	}
+	if n := atomic.LoadInt32(&protocolFactory.created); n != 1 {
+		t.Errorf("protocol should be created once per conn, got %d\n", n)
+	}
+	if err := pool.Put(conn); err != nil {
+		t.Fatalf("pool.Put error:%s\n", err.Error())
+	}
+
+	// 之后的借出继续复用，ClientFactory 同样使用设置的 protocol 工厂
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if again, _ := conn.Protocols(); again != in {
+		t.Errorf("protocol should be cached on the conn across borrows\n")
+	}
+	if _, err := ClientFor(conn, echo.NewEchoClientFactory).Echo(&echo.EchoReq{Msg: "factory"}); err != nil {
+		t.Errorf("Echo through ClientFactory error:%s\n", err.Error())
+	}
+	// 生成代码的 NewEchoClientFactory 分别创建 in 和 out 两个 protocol
+	if n := atomic.LoadInt32(&protocolFactory.created); n != 3 {
+		t.Errorf("ClientFactory should use the configured protocol factory, created:%d\n", n)
+	}
+	_ = pool.Put(conn)
+}
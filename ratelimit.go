@@ -0,0 +1,107 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 限制每秒拨号的新连接数，小于1时不限制（默认），burst 为允许的突发拨号数，小于1时为1
+// 拨号前需取得令牌，令牌不足时 Get 等待（受 ctx 限制），取空闲连接不受限制；
+// 用于冷启动或 Rebind 后避免瞬间大量建连冲击服务端，与 WithMaxConcurrentDials 限制的同时拨号数互相独立
+func WithDialRateLimit(perSecond, burst int32) Option {
+	return func(t *ThriftPool) {
+		if perSecond < 1 {
+			t.DialRateLimit = 0
+			t.DialBurst = 0
+			return
+		}
+		t.DialRateLimit = perSecond
+		if burst < 1 {
+			t.DialBurst = 1
+		} else {
+			t.DialBurst = burst
+		}
+	}
+}
+
+// 令牌桶，令牌按 rate 每秒匀速补充，最多积累 burst 个
+type tokenBucket struct {
+	mutex	sync.Mutex
+	rate	float64		// 每秒补充的令牌数
+	burst	float64		// 令牌上限
+	tokens	float64		// 当前令牌数，预支时为负
+	last	time.Time	// 最近一次补充的时间
+}
+
+func newTokenBucket(rate, burst int32) *tokenBucket {
+	return &tokenBucket{
+		rate:   float64(rate),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// 取一个令牌，令牌不足时预支，返回需要等待的时长
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// 放弃等待时归还预支的令牌
+func (b *tokenBucket) cancel() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// 拨号前等待令牌，未限制拨号速率时立即返回
+// ctx 结束时返回包装了 ctx.Err() 的错误，可用 errors.Is 区分 context.Canceled 和 context.DeadlineExceeded；
+// 连接池关闭时返回 ErrPoolClosed
+func (t *ThriftPool) waitDialToken(ctx context.Context) error {
+	if t.dialLimiter == nil {
+		return nil
+	}
+	wait := t.dialLimiter.reserve(time.Now())
+	if wait <= 0 {
+		return nil
+	}
+	atomic.AddInt64(&t.throttledDials, 1)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+	case <-t.ctx.Done():
+	}
+	t.dialLimiter.cancel()
+	if t.ctx.Err() != nil {
+		return ErrPoolClosed
+	}
+	return fmt.Errorf("%w: wait for dial token, throttled:%d", ctx.Err(), t.GetThrottledDials())
+}
+
+// 因拨号速率限制而等待过的拨号数
+func (t *ThriftPool) GetThrottledDials() int64 {
+	return atomic.LoadInt64(&t.throttledDials)
+}
@@ -0,0 +1,80 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	bucket := newTokenBucket(10, 2)
+	now := bucket.last
+	if bucket.reserve(now) != 0 || bucket.reserve(now) != 0 {
+		t.Errorf("burst tokens should be available immediately\n")
+	}
+	if wait := bucket.reserve(now); wait != 100*time.Millisecond {
+		t.Errorf("third token should wait 100ms, got %s\n", wait)
+	}
+	bucket.cancel()
+	if wait := bucket.reserve(now.Add(50 * time.Millisecond)); wait != 50*time.Millisecond {
+		t.Errorf("cancelled reservation should be returned, got wait %s\n", wait)
+	}
+	if wait := bucket.reserve(now.Add(time.Hour)); wait != 0 {
+		t.Errorf("tokens should refill over time, got wait %s\n", wait)
+	}
+}
+
+func TestDialRateLimit(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithDialRateLimit(20, 1))
+	defer pool.Close()
+
+	startTime := time.Now()
+	var conns []*ThriftConn
+	for i := 0; i < 3; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("get error:%s\n", err.Error())
+		}
+		conns = append(conns, conn)
+	}
+	// 第一个拨号使用突发令牌，之后每个等待约50ms
+	if elapsed := time.Since(startTime); elapsed < 90*time.Millisecond {
+		t.Errorf("dials should be throttled, took %s\n", elapsed)
+	}
+	if pool.Stats().ThrottledDials != 2 {
+		t.Errorf("throttled dials should be 2, got %d\n", pool.Stats().ThrottledDials)
+	}
+
+	// 空闲连接不受限制
+	_ = pool.Put(conns[0])
+	startTime = time.Now()
+	conn, err := pool.Get(context.Background())
+	if err != nil || time.Since(startTime) > 20*time.Millisecond {
+		t.Errorf("idle reuse should not wait for a token, err:%v\n", err)
+	}
+	conns[0] = conn
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiting for a token should be bounded by ctx, got %v\n", err)
+	}
+
+	// 调用方取消与超时可以区分
+	canceledCtx, cancelWait := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancelWait)
+	if _, err := pool.Get(canceledCtx); !errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("cancelled wait should return context.Canceled, got %v\n", err)
+	}
+	if pool.GetUsed() != 3 {
+		t.Errorf("used should not leak, got %d\n", pool.GetUsed())
+	}
+	for _, conn := range conns {
+		_ = pool.Put(conn)
+	}
+}
@@ -0,0 +1,73 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tianxingpan/thriftpool/example/echo"
+	"github.com/tianxingpan/thriftpool/testutil"
+)
+
+// 用于确认协商函数收到的是 Get 的 ctx
+type callerKey struct{}
+
+func TestOnConnReady(t *testing.T) {
+	endpoint, stop := testutil.StartEchoServer(t)
+	defer stop()
+
+	// 协商函数在新连接上发起一次RPC
+	var ready int32
+	onReady := func(ctx context.Context, conn *ThriftConn) error {
+		if ctx.Value(callerKey{}) == nil {
+			return errors.New("ctx of the Get should be passed through")
+		}
+		if _, err := echo.NewEchoClientFactory(conn.ClientFactory()).Echo(&echo.EchoReq{Msg: "version:1"}); err != nil {
+			return err
+		}
+		atomic.AddInt32(&ready, 1)
+		return nil
+	}
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithFramedTransport(0), WithOnConnReady(onReady))
+	defer pool.Close()
+
+	ctx := context.WithValue(context.Background(), callerKey{}, "test")
+	conn, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if _, err := echo.NewEchoClientFactory(conn.ClientFactory()).Echo(&echo.EchoReq{Msg: "hello"}); err != nil {
+		t.Errorf("Echo after the handshake error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+
+	// 复用的空闲连接不再协商
+	conn, err = pool.Get(ctx)
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(conn)
+	if atomic.LoadInt32(&ready) != 1 {
+		t.Errorf("OnConnReady should run once per new conn, ran %d times\n", ready)
+	}
+}
+
+func TestOnConnReadyFailed(t *testing.T) {
+	endpoint, stop := testutil.StartEchoServer(t)
+	defer stop()
+	rejected := errors.New("version rejected")
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithOnConnReady(func(ctx context.Context, conn *ThriftConn) error {
+		return rejected
+	}))
+	defer pool.Close()
+
+	if _, err := pool.Get(context.Background()); !errors.Is(err, rejected) {
+		t.Errorf("failed handshake should fail Get, got %v\n", err)
+	}
+	if pool.GetOpen() != 0 || pool.GetUsed() != 0 || pool.GetDialFailures() != 1 {
+		t.Errorf("failed handshake should count as a dial failure, open:%d used:%d failures:%d\n",
+			pool.GetOpen(), pool.GetUsed(), pool.GetDialFailures())
+	}
+}
@@ -0,0 +1,59 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+// 连接池当前绑定的端点，Rebind 时整体替换，保证拨号读到的端点和代数一致
+type binding struct {
+	endpoint	string		// 服务端的端点
+	generation	int32		// 每次 Rebind 加1，连接的代数与之不同时表示连接属于旧端点
+	targets		*targetSet	// 多个目标时按权重轮流拨号，为nil时拨号 endpoint
+}
+
+// 本次拨号的端点
+func (b binding) dialEndpoint(cursor *uint32) string {
+	if b.targets == nil {
+		return b.endpoint
+	}
+	return b.targets.pick(cursor)
+}
+
+// endpoint 是否为当前的端点或目标之一
+func (b binding) hasEndpoint(endpoint string) bool {
+	if b.targets == nil {
+		return endpoint == b.endpoint
+	}
+	return b.targets.members[endpoint]
+}
+
+// 把连接池切换到新的端点，无需重建连接池，计数和配置均保留。
+// 切换后：
+// 1) 新拨号的连接都连到新端点；
+// 2) 当前的空闲连接在被 Get 取出或空闲回收时关闭，Get 随后改用其它空闲连接或拨号新端点；
+// 3) 使用中的连接继续使用旧端点直到 Put，Put 时关闭而不放回池。
+// 因此切换后的一段时间内（直到所有旧连接被归还），新旧端点的连接会同时存在，
+// 在此期间旧连接仍占用连接名额。切换会清零连续拨号失败次数，即关闭熔断。
+// 切换会清除 SetTargets 设置的多个目标。
+// 注意 Endpoint 字段保留创建时的端点，切换后应使用 GetEndpoint 读取当前端点
+func (t *ThriftPool) Rebind(endpoint string) {
+	t.rebindMutex.Lock()
+	defer t.rebindMutex.Unlock()
+	current := t.loadBinding()
+	if current.endpoint == endpoint && current.targets == nil {
+		return
+	}
+	t.binding.Store(binding{endpoint: endpoint, generation: current.generation + 1})
+	t.dialSucceeded()
+	t.logf("rebind endpoint %s to %s", current.endpoint, endpoint)
+}
+
+func (t *ThriftPool) loadBinding() binding {
+	return t.binding.Load().(binding)
+}
+
+// 连接是否属于 Rebind 之前的端点，或已不在 SetTargets 设置的目标中
+func (t *ThriftPool) isStale(conn *ThriftConn) bool {
+	bound := t.loadBinding()
+	if conn.generation != bound.generation {
+		return true
+	}
+	return bound.targets != nil && !bound.targets.members[conn.Endpoint]
+}
@@ -0,0 +1,53 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	endpoint1, stop1 := startTestServer(t, "127.0.0.1:0")
+	defer stop1()
+	endpoint2, stop2 := startTestServer(t, "127.0.0.1:0")
+	defer stop2()
+	pool := NewThriftPool(endpoint1, 100, 60000, 10, 2)
+	defer pool.Close()
+
+	inUse, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	idle, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	_ = pool.Put(idle)
+
+	pool.Rebind(endpoint2)
+	if pool.GetEndpoint() != endpoint2 {
+		t.Errorf("GetEndpoint should return %s, got %s\n", endpoint2, pool.GetEndpoint())
+	}
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if conn.GetEndpoint() != endpoint2 {
+		t.Errorf("new conn should dial %s, got %s\n", endpoint2, conn.GetEndpoint())
+	}
+	if !idle.IsClose() {
+		t.Errorf("idle conn of the old endpoint should be evicted on next use\n")
+	}
+
+	if err := pool.Put(inUse); err != nil {
+		t.Errorf("Put of an old endpoint conn should not fail:%s\n", err)
+	}
+	if !inUse.IsClose() {
+		t.Errorf("in-use conn of the old endpoint should be discarded on Put\n")
+	}
+	_ = pool.Put(conn)
+	if pool.GetUsed() != 0 || pool.GetIdle() != 1 || pool.GetOpen() != 1 {
+		t.Errorf("unexpected counters, used:%d, idle:%d, open:%d\n", pool.GetUsed(), pool.GetIdle(), pool.GetOpen())
+	}
+}
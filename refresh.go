@@ -0,0 +1,45 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+// 替换连到 endpoint 的空闲连接：关闭这些连接，再向同一端点拨号同样数量的新连接放回队列，返回替换的连接数。
+// 用于已知某个后端实例重启后主动重建到它的连接，而不影响其它端点和使用中的连接；
+// 单端点的连接池中 endpoint 为连接池的端点时替换全部空闲连接。
+// endpoint 已不是当前的端点或目标时只关闭不重新拨号；拨号失败时停止替换，
+// 关闭的连接留待 Get 按需拨号，返回值只计入成功拨号的连接
+func (t *ThriftPool) RefreshEndpoint(endpoint string) int {
+	var evicted int
+	conns := t.idleConns.removeIf(func(conn *ThriftConn) bool {
+		return conn.GetEndpoint() == endpoint
+	})
+	for _, conn := range conns {
+		t.subIdle()
+		t.discard(conn)
+		t.countEvicted(evictStale)
+		t.emit(EventEvicted, nil)
+		evicted++
+	}
+
+	bound := t.loadBinding()
+	if !bound.hasEndpoint(endpoint) {
+		return 0
+	}
+	var replaced int
+	for replaced < evicted {
+		if t.ctx.Err() != nil || t.IsPaused() || !t.allowDial() || !t.tryReserveSlot() {
+			break
+		}
+		conn, err := t.connectTo(t.ctx, bound, endpoint)
+		if err != nil {
+			t.releaseSlot()
+			t.logf("refresh endpoint %s failed:%s", endpoint, err.Error())
+			break
+		}
+		if !t.tryAddIdle() {
+			t.discard(conn)
+			break
+		}
+		t.restoreIdle(conn)
+		replaced++
+	}
+	return replaced
+}
@@ -0,0 +1,59 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRefreshEndpoint(t *testing.T) {
+	endpoint1, stop1 := startTestServer(t, "127.0.0.1:0")
+	defer stop1()
+	endpoint2, stop2 := startTestServer(t, "127.0.0.1:0")
+	defer stop2()
+
+	pool := NewThriftPool(endpoint1, 1000, 60000, 10, 0)
+	defer pool.Close()
+	pool.SetTargets([]Target{{Endpoint: endpoint1, Weight: 1}, {Endpoint: endpoint2, Weight: 1}})
+
+	conns := make([]*ThriftConn, 0, 4)
+	for i := 0; i < 4; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns = append(conns, conn)
+	}
+	old := make(map[*ThriftConn]bool)
+	for _, conn := range conns {
+		old[conn] = true
+		_ = pool.Put(conn)
+	}
+
+	if n := pool.RefreshEndpoint(endpoint1); n != 2 {
+		t.Errorf("RefreshEndpoint should replace 2 conns, got %d\n", n)
+	}
+	if pool.GetIdle() != 4 || pool.GetOpen() != 4 {
+		t.Errorf("refreshed pool should keep 4 idle conns, idle:%d open:%d\n", pool.GetIdle(), pool.GetOpen())
+	}
+	pool.rangeIdle(func(conn *ThriftConn) {
+		if conn.GetEndpoint() == endpoint1 && old[conn] {
+			t.Errorf("idle conn to %s should have been replaced\n", endpoint1)
+		}
+		if conn.GetEndpoint() == endpoint2 && !old[conn] {
+			t.Errorf("idle conn to %s should be kept\n", endpoint2)
+		}
+	})
+	if pool.Stats().EvictedStale != 2 {
+		t.Errorf("replaced conns should be counted as stale, got %d\n", pool.Stats().EvictedStale)
+	}
+
+	// 不再属于目标的端点只关闭不重新拨号
+	pool.SetTargets([]Target{{Endpoint: endpoint2, Weight: 1}})
+	if n := pool.RefreshEndpoint(endpoint1); n != 0 {
+		t.Errorf("removed endpoint should not be redialed, got %d\n", n)
+	}
+	if pool.GetIdle() != 2 || pool.GetOpen() != 2 {
+		t.Errorf("conns to the removed endpoint should be closed, idle:%d open:%d\n", pool.GetIdle(), pool.GetOpen())
+	}
+}
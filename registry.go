@@ -0,0 +1,93 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// 进程内登记的连接池，供 CloseAll 统一关闭；连接池 Close 时自动注销，不会持有已关闭的连接池
+var registry = struct {
+	mutex	sync.Mutex
+	all		bool							// 是否登记所有新建的连接池，见 RegisterAll
+	pools	map[*ThriftPool]struct{}
+}{pools: make(map[*ThriftPool]struct{})}
+
+// 把连接池登记到全局注册表，进程退出前可用 CloseAll 统一关闭。
+// 默认不登记，避免意外的全局状态；也可用 RegisterAll 登记之后新建的所有连接池
+func WithRegister() Option {
+	return func(t *ThriftPool) {
+		t.Registered = true
+	}
+}
+
+// 设置是否把之后新建的所有连接池都登记到全局注册表，不影响已创建的连接池
+func RegisterAll(all bool) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.all = all
+}
+
+// 新建连接池时按需登记
+func (t *ThriftPool) register() {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if !t.Registered && !registry.all {
+		return
+	}
+	t.Registered = true
+	registry.pools[t] = struct{}{}
+}
+
+// 连接池关闭时注销
+func (t *ThriftPool) deregister() {
+	if !t.Registered {
+		return
+	}
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	delete(registry.pools, t)
+}
+
+// 返回已登记且尚未关闭的连接池
+func RegisteredPools() []*ThriftPool {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	pools := make([]*ThriftPool, 0, len(registry.pools))
+	for pool := range registry.pools {
+		pools = append(pools, pool)
+	}
+	return pools
+}
+
+// 并发地优雅关闭（见 CloseGracefully）所有已登记的连接池，全部关闭后返回，可作为进程统一的退出钩子。
+// ctx 结束时仍有连接未归还的连接池被直接关闭，此时返回的错误包装第一个连接池的错误，并带上出错的连接池数
+func CloseAll(ctx context.Context) error {
+	pools := RegisteredPools()
+	errs := make([]error, len(pools))
+	var wg sync.WaitGroup
+	for i, pool := range pools {
+		wg.Add(1)
+		go func(i int, pool *ThriftPool) {
+			defer wg.Done()
+			errs[i] = pool.CloseGracefully(ctx)
+		}(i, pool)
+	}
+	wg.Wait()
+
+	var first error
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %d of %d pools not drained", first, failed, len(pools))
+}
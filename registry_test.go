@@ -0,0 +1,92 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 是否已登记到全局注册表
+func isRegistered(pool *ThriftPool) bool {
+	for _, registered := range RegisteredPools() {
+		if registered == pool {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRegistry(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	plain := NewThriftPool(endpoint, 1000, 60000, 10, 0)
+	defer plain.Close()
+	registered := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithRegister())
+	if isRegistered(plain) || !isRegistered(registered) {
+		t.Fatalf("only pools created with WithRegister should be registered\n")
+	}
+
+	// Close 后自动注销
+	closed := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithRegister())
+	closed.Close()
+	if isRegistered(closed) {
+		t.Errorf("closed pool should be deregistered\n")
+	}
+
+	RegisterAll(true)
+	all := NewThriftPool(endpoint, 1000, 60000, 10, 0)
+	RegisterAll(false)
+	if !isRegistered(all) {
+		t.Errorf("pool created under RegisterAll should be registered\n")
+	}
+
+	conn, err := registered.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = registered.Put(conn)
+	}()
+	if err := CloseAll(context.Background()); err != nil {
+		t.Errorf("CloseAll error:%s\n", err.Error())
+	}
+	if !conn.IsClose() || registered.GetOpen() != 0 {
+		t.Errorf("CloseAll should wait for the borrowed conn and close it\n")
+	}
+	for _, pool := range []*ThriftPool{registered, all} {
+		if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolClosed) {
+			t.Errorf("registered pool should be closed, got %v\n", err)
+		}
+	}
+	if len(RegisteredPools()) != 0 {
+		t.Errorf("registry should be empty after CloseAll, got %d\n", len(RegisteredPools()))
+	}
+	if _, err := plain.Get(context.Background()); err != nil {
+		t.Errorf("unregistered pool should stay open:%s\n", err.Error())
+	}
+}
+
+func TestCloseAllTimeout(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithRegister())
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	defer pool.Put(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = CloseAll(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CloseAll should report the undrained pool, got %v\n", err)
+	}
+	if isRegistered(pool) {
+		t.Errorf("pool should be closed and deregistered after timeout\n")
+	}
+}
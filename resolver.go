@@ -0,0 +1,53 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"fmt"
+)
+
+// 按需解析本次拨号的端点，ctx 为触发拨号的 Get 的 ctx（后台预热、补连时为连接池的 ctx），
+// 可据此结合特性开关、动态配置等选取机房或实例
+type EndpointResolver func(ctx context.Context) (string, error)
+
+// 设置端点解析函数，代替固定的端点或 SetTargets 的轮询决定连接到哪个端点；为nil时不使用（默认）。
+// Get 每次都先调用 resolver，只复用连到解析出的端点的空闲连接，没有时拨号该端点（同 GetForKey 取指定端点的方式），
+// 拨号和借出同样受 MaxConcurrentDials、限流、AllowOverflow、ValidateOnBorrow 等设置的约束；
+// GetPriority、GetProbe 及后台预热、补足 MinIdle 只在需要拨号时调用 resolver。
+// resolver 返回错误时本次拨号失败，计入熔断的连续失败次数。
+// 各连接记录解析出的端点，见 ThriftConn.GetEndpoint；GetEndpoint 仍返回连接池的端点。
+// 与其它端点功能的关系：
+// 1) Rebind 后旧连接照常按代数关闭，新连接仍由 resolver 决定端点；
+// 2) 设置了 SetTargets 或 NewThriftPoolSRV 的目标时，不在目标中的连接视为旧连接而被关闭，
+//    因此 resolver 应从 GetTargets 中选取，否则每个连接都只能用一次；GetForKey 仍按 key 选取目标，不调用 resolver；
+// 3) RefreshEndpoint 重新拨号指定的端点，不调用 resolver
+func WithEndpointResolver(resolver EndpointResolver) Option {
+	return func(t *ThriftPool) {
+		t.EndpointResolver = resolver
+	}
+}
+
+// 本次拨号的端点，设置了 EndpointResolver 时由它解析
+func (t *ThriftPool) resolveEndpoint(ctx context.Context, bound binding) (string, error) {
+	if t.EndpointResolver == nil {
+		return bound.dialEndpoint(&t.targetCursor), nil
+	}
+	endpoint, err := t.EndpointResolver(ctx)
+	if err != nil {
+		t.dialFailed()
+		err = fmt.Errorf("thriftpool resolve endpoint: %w", err)
+		t.emit(EventDialFailed, err)
+		return "", err
+	}
+	return endpoint, nil
+}
+
+// 按 EndpointResolver 解析出的端点取连接，调用方已确认设置了 EndpointResolver
+func (t *ThriftPool) getResolved(ctx context.Context) (*ThriftConn, error) {
+	bound := t.loadBinding()
+	endpoint, err := t.resolveEndpoint(ctx, bound)
+	if err != nil {
+		return nil, err
+	}
+	return t.getFor(ctx, false, endpoint)
+}
@@ -0,0 +1,177 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type regionKey struct{}
+
+func TestEndpointResolver(t *testing.T) {
+	east, stopEast := startTestServer(t, "127.0.0.1:0")
+	defer stopEast()
+	west, stopWest := startTestServer(t, "127.0.0.1:0")
+	defer stopWest()
+
+	// 按 ctx 中的机房选取端点
+	errNoRegion := errors.New("no region")
+	resolver := func(ctx context.Context) (string, error) {
+		switch ctx.Value(regionKey{}) {
+		case "east":
+			return east, nil
+		case "west":
+			return west, nil
+		}
+		return "", errNoRegion
+	}
+	pool := NewThriftPool("echo", 100, 60000, 10, 0, WithEndpointResolver(resolver))
+	defer pool.Close()
+
+	for _, c := range []struct {
+		region		string
+		endpoint	string
+	}{{"east", east}, {"west", west}} {
+		ctx := context.WithValue(context.Background(), regionKey{}, c.region)
+		conn, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatalf("pool.Get(%s) error:%s\n", c.region, err.Error())
+		}
+		if conn.GetEndpoint() != c.endpoint {
+			t.Errorf("%s conn should dial %s, got %s\n", c.region, c.endpoint, conn.GetEndpoint())
+		}
+		if err := pool.Put(conn); err != nil {
+			t.Errorf("pool.Put error:%s\n", err.Error())
+		}
+	}
+	if pool.GetIdle() != 2 {
+		t.Errorf("resolved conns should be reused, idle:%d\n", pool.GetIdle())
+	}
+	// 只复用连到解析出的端点的空闲连接
+	conn, err := pool.Get(context.WithValue(context.Background(), regionKey{}, "west"))
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if conn.GetEndpoint() != west || pool.GetOpen() != 2 {
+		t.Errorf("west conn should be reused, endpoint:%s open:%d\n", conn.GetEndpoint(), pool.GetOpen())
+	}
+	_ = pool.Put(conn)
+	if pool.GetEndpoint() != "echo" {
+		t.Errorf("pool endpoint should stay echo, got %s\n", pool.GetEndpoint())
+	}
+}
+
+func TestEndpointResolverError(t *testing.T) {
+	errNoRoute := errors.New("no route")
+	pool := NewThriftPool("echo", 100, 60000, 10, 0, WithEndpointResolver(func(ctx context.Context) (string, error) {
+		return "", errNoRoute
+	}))
+	defer pool.Close()
+
+	_, err := pool.Get(context.Background())
+	if !errors.Is(err, errNoRoute) {
+		t.Fatalf("resolver error should abort the dial, got %v\n", err)
+	}
+	if pool.GetDialFailures() != 1 {
+		t.Errorf("resolver error should count as a dial failure, got %d\n", pool.GetDialFailures())
+	}
+	if pool.GetOpen() != 0 || pool.GetUsed() != 0 {
+		t.Errorf("failed dial should release its slot, open:%d used:%d\n", pool.GetOpen(), pool.GetUsed())
+	}
+}
+
+func TestEndpointResolverTargets(t *testing.T) {
+	first, stopFirst := startTestServer(t, "127.0.0.1:0")
+	defer stopFirst()
+	second, stopSecond := startTestServer(t, "127.0.0.1:0")
+	defer stopSecond()
+
+	pool := NewThriftPool("echo", 100, 60000, 10, 0)
+	defer pool.Close()
+	pool.SetTargets([]Target{{Endpoint: first}, {Endpoint: second}})
+	// 总是选取最后一个目标
+	pool.EndpointResolver = func(ctx context.Context) (string, error) {
+		targets := pool.GetTargets()
+		return targets[len(targets)-1], nil
+	}
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if conn.GetEndpoint() != second {
+		t.Errorf("conn should dial the resolved target %s, got %s\n", second, conn.GetEndpoint())
+	}
+	_ = pool.Put(conn)
+	if pool.GetIdle() != 1 {
+		t.Errorf("conn to a current target should go back to the pool, idle:%d\n", pool.GetIdle())
+	}
+}
+
+func TestEndpointResolverMaxConcurrentDials(t *testing.T) {
+	blockDial(t)
+	pool := NewThriftPool("echo", 50, 60000, 10, 0, WithMaxConcurrentDials(1),
+		WithEndpointResolver(func(ctx context.Context) (string, error) {
+			return "127.0.0.1:9898", nil
+		}))
+	defer pool.Close()
+
+	holdCtx, release := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = pool.Get(holdCtx)
+	}()
+	for pool.GetDialing() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrTooManyDials) {
+		t.Errorf("resolved Get waiting too long for a dial slot should return ErrTooManyDials, got %v\n", err)
+	}
+	if pool.GetDialing() != 1 {
+		t.Errorf("only one resolved dial should be in flight, dialing:%d\n", pool.GetDialing())
+	}
+	release()
+	<-done
+	if pool.GetUsed() != 0 || pool.GetOpen() != 0 {
+		t.Errorf("failed dials should release used and open, used:%d, open:%d\n", pool.GetUsed(), pool.GetOpen())
+	}
+}
+
+func TestEndpointResolverValidateOnBorrow(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	v := &testValidator{broken: make(map[*ThriftConn]bool)}
+	pool := NewThriftPool("echo", 100, 60000, 10, 0,
+		WithValidator(v.validate, 100), WithValidateOnBorrow(true),
+		WithEndpointResolver(func(ctx context.Context) (string, error) {
+			return endpoint, nil
+		}))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	v.broken[conn] = true
+	_ = pool.Put(conn)
+
+	// 连到解析出的端点的空闲连接校验失败被关闭，改为拨号新连接
+	conn2, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if conn2 == conn || !conn.IsClose() {
+		t.Errorf("resolved conn failing validation should be closed and replaced\n")
+	}
+	if v.calls != 1 || pool.Stats().EvictedBroken != 1 {
+		t.Errorf("unexpected validation, calls:%d, evicted:%d\n", v.calls, pool.Stats().EvictedBroken)
+	}
+	if conn2.GetEndpoint() != endpoint || pool.GetUsed() != 1 || pool.GetOpen() != 1 {
+		t.Errorf("unexpected replacement, endpoint:%s, used:%d, open:%d\n", conn2.GetEndpoint(), pool.GetUsed(), pool.GetOpen())
+	}
+	_ = pool.Put(conn2)
+}
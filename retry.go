@@ -0,0 +1,99 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 判断错误是否可重试
+type RetryClassifier func(err error) bool
+
+// 判断出错后连接是否已不能复用，Do 据此决定关闭还是放回连接，也是 DoWithRetry 默认的可重试判断：
+// 1) 连接层面的错误：传输异常、网络错误、连接被对端关闭；
+// 2) 协议错误（TProtocolException），读写的字节流已经错位；
+// 3) 表明响应与请求错位的应用异常：消息类型、方法名或序号不匹配，以及 PROTOCOL_ERROR；
+// 4) context.Canceled，调用可能在等待回复时被放弃，见 ThriftConn.MarkAbandoned。
+// 服务端返回的其它应用异常（如 UNKNOWN_METHOD、INTERNAL_ERROR）和 IDL 中定义的异常是完整的响应，连接仍可复用
+func IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var transportErr thrift.TTransportException
+	if errors.As(err, &transportErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.Canceled) {
+		return true
+	}
+	var protocolErr thrift.TProtocolException
+	if errors.As(err, &protocolErr) {
+		return true
+	}
+	var appErr thrift.TApplicationException
+	if errors.As(err, &appErr) {
+		switch appErr.TypeId() {
+		case thrift.INVALID_MESSAGE_TYPE_EXCEPTION, thrift.WRONG_METHOD_NAME, thrift.BAD_SEQUENCE_ID, thrift.PROTOCOL_ERROR:
+			return true
+		}
+	}
+	return false
+}
+
+// 设置 DoWithRetry 的可重试判断和首次重试前的等待时长（单位毫秒，之后每次翻倍）
+// classifier 为nil时使用 IsConnectionError，backoff 小于1时不等待
+func WithRetry(classifier RetryClassifier, backoff int32) Option {
+	return func(t *ThriftPool) {
+		t.RetryClassifier = classifier
+		if backoff < 1 {
+			t.RetryBackoff = 0
+		} else {
+			t.RetryBackoff = time.Duration(backoff) * time.Millisecond
+		}
+	}
+}
+
+// 同 Do，但 fn 或取连接返回可重试的错误时，关闭出错的连接并换一个连接重试，最多执行 attempts 次
+// 重试前按 RetryBackoff 指数退避等待，每次取连接受 ctx 控制，ctx 结束时不再重试，返回最后一次的错误
+func (t *ThriftPool) DoWithRetry(ctx context.Context, attempts int, fn func(conn *ThriftConn) error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	retryable := t.RetryClassifier
+	if retryable == nil {
+		retryable = IsConnectionError
+	}
+	backoff := t.RetryBackoff
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			if backoff > 0 {
+				timer := time.NewTimer(backoff)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return err
+				case <-timer.C:
+				}
+				backoff *= 2
+			} else if ctx.Err() != nil {
+				return err
+			}
+		}
+		err = t.doCtx(ctx, fn)
+		if err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
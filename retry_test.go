@@ -0,0 +1,153 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+func TestDoWithRetry(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 0, WithRetry(nil, 1))
+	defer pool.Close()
+
+	// 第一次连接层面出错，第二次成功
+	var conns []*ThriftConn
+	err := pool.DoWithRetry(context.Background(), 3, func(conn *ThriftConn) error {
+		conns = append(conns, conn)
+		if len(conns) == 1 {
+			return thrift.NewTTransportException(thrift.UNKNOWN_TRANSPORT_EXCEPTION, "broken pipe")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("DoWithRetry should succeed on the second try:%s\n", err)
+	}
+	if len(conns) != 2 || conns[0] == conns[1] {
+		t.Errorf("expected a fresh connection on retry, got %d calls\n", len(conns))
+	}
+	if conns[0].IsUsable() {
+		t.Errorf("broken connection should be discarded\n")
+	}
+
+	// 重试次数用尽
+	calls := 0
+	err = pool.DoWithRetry(context.Background(), 3, func(conn *ThriftConn) error {
+		calls++
+		return io.EOF
+	})
+	if !errors.Is(err, io.EOF) || calls != 3 {
+		t.Errorf("expected io.EOF after 3 calls, got %v after %d\n", err, calls)
+	}
+
+	// 协议错误同样按连接不可复用重试
+	calls = 0
+	err = pool.DoWithRetry(context.Background(), 3, func(conn *ThriftConn) error {
+		calls++
+		if calls == 1 {
+			return thrift.NewTProtocolExceptionWithType(thrift.INVALID_DATA, errors.New("bad data"))
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("protocol errors should be retried, got %v after %d\n", err, calls)
+	}
+
+	// 应用层错误不重试
+	appErr := errors.New("application error")
+	calls = 0
+	err = pool.DoWithRetry(context.Background(), 3, func(conn *ThriftConn) error {
+		calls++
+		return appErr
+	})
+	if err != appErr || calls != 1 {
+		t.Errorf("application errors should not be retried, got %v after %d\n", err, calls)
+	}
+
+	if pool.GetUsed() != 0 || pool.GetOpen() != pool.GetIdle() {
+		t.Errorf("counters leaked, used:%d, open:%d, idle:%d\n", pool.GetUsed(), pool.GetOpen(), pool.GetIdle())
+	}
+}
+
+func TestDoWithRetryCancelDuringDial(t *testing.T) {
+	blockDial(t)
+	pool := NewThriftPool("127.0.0.1:9898", 5000, 60000, 10, 0)
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	calls := 0
+	startTime := time.Now()
+	err := pool.DoWithRetry(ctx, 3, func(conn *ThriftConn) error {
+		calls++
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("DoWithRetry should stop at the ctx deadline, got %v\n", err)
+	}
+	if elapsed := time.Since(startTime); elapsed > time.Second {
+		t.Errorf("ctx deadline should bound each dial, elapsed:%s\n", elapsed)
+	}
+	if calls != 0 || pool.GetUsed() != 0 || pool.GetOpen() != 0 {
+		t.Errorf("unexpected state, calls:%d, used:%d, open:%d\n", calls, pool.GetUsed(), pool.GetOpen())
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	cases := []struct {
+		err		error
+		want	bool
+	}{
+		{nil, false},
+		{errors.New("application error"), false},
+		{thrift.NewTTransportException(thrift.END_OF_FILE, "EOF"), true},
+		{fmt.Errorf("call: %w", io.ErrUnexpectedEOF), true},
+		{thrift.NewTProtocolExceptionWithType(thrift.INVALID_DATA, errors.New("bad data")), true},
+		{thrift.NewTApplicationException(thrift.BAD_SEQUENCE_ID, "out of sequence response"), true},
+		{thrift.NewTApplicationException(thrift.WRONG_METHOD_NAME, "wrong method name"), true},
+		{thrift.NewTApplicationException(thrift.UNKNOWN_METHOD, "unknown method"), false},
+		{thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "internal error"), false},
+		{context.Canceled, true},
+	}
+	for _, c := range cases {
+		if got := IsConnectionError(c.err); got != c.want {
+			t.Errorf("IsConnectionError(%v) = %v, want %v\n", c.err, got, c.want)
+		}
+	}
+}
+
+func TestDoKeepsConnOnAppException(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 0)
+	defer pool.Close()
+
+	var first *ThriftConn
+	err := pool.Do(func(conn *ThriftConn) error {
+		first = conn
+		return thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "internal error")
+	})
+	if err == nil {
+		t.Errorf("Do should return the application exception\n")
+	}
+	if !first.IsUsable() || pool.GetIdle() != 1 {
+		t.Errorf("conn should be pooled after an application exception, idle:%d\n", pool.GetIdle())
+	}
+
+	_ = pool.Do(func(conn *ThriftConn) error {
+		if conn != first {
+			t.Errorf("pooled conn should be reused\n")
+		}
+		return thrift.NewTApplicationException(thrift.BAD_SEQUENCE_ID, "out of sequence response")
+	})
+	if first.IsUsable() || pool.GetIdle() != 0 {
+		t.Errorf("desynced conn should be discarded, idle:%d\n", pool.GetIdle())
+	}
+}
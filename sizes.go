@@ -0,0 +1,33 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 连接数参数不一致，需要调整才能使用，由 NewThriftPoolChecked 返回
+var ErrBadSize = errors.New("thriftpool bad size")
+
+// NewThriftPoolChecked 是否沿用 NewThriftPool 的宽松处理：连接数参数不一致时调整后继续创建，只记录日志。
+// 默认为 false，此时 NewThriftPoolChecked 返回包装了 ErrBadSize 的错误，说明哪些参数需要调整；
+// NewThriftPool 总是宽松处理，调整后的实际值可通过 GetMaxSize、GetInitSize 和 GetMaxIdle 读取
+func WithLenientSizes(lenient bool) Option {
+	return func(t *ThriftPool) {
+		t.LenientSizes = lenient
+	}
+}
+
+// 记录一次连接数参数的调整，创建结束时统一写日志
+func (t *ThriftPool) adjustSize(format string, v ...interface{}) {
+	t.sizeAdjustments = append(t.sizeAdjustments, fmt.Sprintf(format, v...))
+}
+
+// 创建时对连接数参数的调整，没有调整时返回nil
+func (t *ThriftPool) checkSizes() error {
+	if len(t.sizeAdjustments) == 0 || t.LenientSizes {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrBadSize, strings.Join(t.sizeAdjustments, "; "))
+}
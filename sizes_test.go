@@ -0,0 +1,42 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckedSizes(t *testing.T) {
+	pool, err := NewThriftPoolChecked("127.0.0.1:9", 100, 60000, 5, 4)
+	if pool != nil || !errors.Is(err, ErrBadSize) {
+		t.Errorf("max size below twice the init size should fail with ErrBadSize, got %v\n", err)
+	}
+
+	pool, err = NewThriftPoolChecked("127.0.0.1:9", 100, 60000, 5, 0, WithInitSize(8))
+	if pool != nil || !errors.Is(err, ErrBadSize) {
+		t.Errorf("init size above max size should fail with ErrBadSize, got %v\n", err)
+	}
+
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool, err = NewThriftPoolChecked(endpoint, 100, 60000, 5, 0, WithLenientSizes(true), WithInitSize(8))
+	if err != nil {
+		t.Errorf("lenient sizes should not fail:%s\n", err)
+	} else {
+		if pool.GetInitSize() != 5 || pool.GetMaxSize() != 5 {
+			t.Errorf("init size should be clamped to 5, got %d/%d\n", pool.GetInitSize(), pool.GetMaxSize())
+		}
+		pool.Close()
+	}
+
+	logger := &testLogger{}
+	pool = NewThriftPool("127.0.0.1:9", 100, 60000, 5, 4, WithLogger(logger))
+	if pool.GetMaxSize() != 8 {
+		t.Errorf("NewThriftPool should raise max size to 8, got %d\n", pool.GetMaxSize())
+	}
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "max size 5 raised to 8") {
+		t.Errorf("NewThriftPool should log the adjustment, got %v\n", logger.lines)
+	}
+	pool.Close()
+}
@@ -0,0 +1,44 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync/atomic"
+)
+
+// 已打开的连接数，包括使用中和空闲的连接，不会超过 MaxSize
+func (t *ThriftPool) GetOpen() int32 {
+	return atomic.LoadInt32(&t.open)
+}
+
+// 预占一个连接名额，已打开的连接数达到 MaxSize（限流时为限流值）时返回 false
+// 连接名额相当于一个容量为 MaxSize 的信号量，所有创建连接的途径都必须先预占名额
+func (t *ThriftPool) tryReserveSlot() bool {
+	for {
+		open := atomic.LoadInt32(&t.open)
+		if open >= t.GetEffectiveMaxSize() {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&t.open, open, open+1) {
+			return true
+		}
+	}
+}
+
+// 释放一个连接名额
+func (t *ThriftPool) releaseSlot() {
+	atomic.AddInt32(&t.open, -1)
+}
+
+// 关闭连接并释放其占用的连接名额，同一连接多次调用只释放一次
+func (t *ThriftPool) discard(conn *ThriftConn) {
+	_ = conn.Close()
+	if atomic.CompareAndSwapInt32(&conn.slot, 1, 0) {
+		if conn.overflow {
+			t.releaseOverflow()
+		} else {
+			t.releaseSlot()
+		}
+		t.releaseBuffer(conn)
+		t.wakeWaiter()
+	}
+}
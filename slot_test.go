@@ -0,0 +1,67 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// 多个途径并发创建连接时，已打开的连接数不应超过 MaxSize
+func TestOpenConnCeiling(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 4, 1)
+	defer pool.Close()
+
+	var exceeded int32
+	var wg sync.WaitGroup
+	stopChan := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-stopChan:
+				return
+			default:
+			}
+			if open := pool.GetOpen(); open > pool.GetMaxSize() {
+				atomic.StoreInt32(&exceeded, open)
+			}
+		}
+	}()
+
+	for i := 0; i < 16; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				conn, err := pool.Get(context.Background())
+				if err != nil {
+					continue
+				}
+				if j%5 == 0 {
+					// 部分连接作废，促使连接池重新拨号
+					_ = conn.Close()
+				}
+				_ = pool.Put(conn)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = pool.Probe()
+			}
+		}()
+	}
+	wg.Wait()
+	close(stopChan)
+
+	if open := atomic.LoadInt32(&exceeded); open != 0 {
+		t.Errorf("open conns reached %d, max is %d\n", open, pool.GetMaxSize())
+	}
+	if pool.GetUsed() != 0 || pool.GetOpen() != pool.GetIdle() {
+		t.Errorf("used:%d, open:%d, idle:%d, want open == idle after all Put\n",
+			pool.GetUsed(), pool.GetOpen(), pool.GetIdle())
+	}
+}
@@ -0,0 +1,99 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 解析SRV记录，测试时替换
+var lookupSRV = net.LookupSRV
+
+// SRV记录的重新解析间隔，单位毫秒，小于1时为30秒
+func WithSRVRefresh(interval int32) Option {
+	return func(t *ThriftPool) {
+		if interval < 1 {
+			t.SRVRefreshInterval = time.Duration(30000) * time.Millisecond
+		} else {
+			t.SRVRefreshInterval = time.Duration(interval) * time.Millisecond
+		}
+	}
+}
+
+// 按SRV记录创建连接池，name 为完整的SRV名称，如 _echo._tcp.example.com，其它参数含义同 NewThriftPool。
+// 解析出的目标按 SetTargets 以权重轮流拨号，只使用优先级最高（Priority 最小）的一组记录，
+// 组内按记录的 Weight 分配。连接池每隔 SRVRefreshInterval 重新解析一次，
+// 解析失败或结果为空时保留之前的目标。首次解析失败时返回错误
+func NewThriftPoolSRV(name string, dialTimeout, idleTimeout, maxSize, initSize int32, opts ...Option) (*ThriftPool, error) {
+	targets, err := resolveSRV(name)
+	if err != nil {
+		return nil, err
+	}
+	opts = append([]Option{WithSRVRefresh(0)}, opts...)
+	t := NewThriftPool(name, dialTimeout, idleTimeout, maxSize, initSize, opts...)
+	t.SetTargets(targets)
+	t.startSRVRefresh(name)
+	return t, nil
+}
+
+// 启动定期重新解析SRV记录的后台协程
+func (t *ThriftPool) startSRVRefresh(name string) {
+	t.srvName = name
+	t.goWorker(func() {
+		t.refreshSRV(name)
+	})
+}
+
+// 解析SRV记录，返回优先级最高的一组目标
+func resolveSRV(name string) ([]Target, error) {
+	_, records, err := lookupSRV("", "", name)
+	if err != nil {
+		return nil, fmt.Errorf("thriftpool lookup srv %s: %w", name, err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New(fmt.Sprintf("thriftpool lookup srv %s: no records", name))
+	}
+	priority := records[0].Priority
+	for _, record := range records {
+		if record.Priority < priority {
+			priority = record.Priority
+		}
+	}
+	targets := make([]Target, 0, len(records))
+	for _, record := range records {
+		if record.Priority != priority {
+			continue
+		}
+		host := strings.TrimSuffix(record.Target, ".")
+		targets = append(targets, Target{
+			Endpoint: net.JoinHostPort(host, strconv.Itoa(int(record.Port))),
+			Weight:   int32(record.Weight),
+		})
+	}
+	return targets, nil
+}
+
+// 定期重新解析SRV记录
+func (t *ThriftPool) refreshSRV(name string) {
+	ticker := time.NewTicker(t.SRVRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		targets, err := resolveSRV(name)
+		if err != nil {
+			t.logf("refresh srv failed:%s", err.Error())
+			continue
+		}
+		t.SetTargets(targets)
+	}
+}
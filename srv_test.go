@@ -0,0 +1,140 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func srvRecord(t *testing.T, endpoint string, priority, weight uint16) *net.SRV {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		t.Fatalf("split %s error:%s\n", endpoint, err.Error())
+	}
+	portNum, _ := strconv.Atoi(port)
+	return &net.SRV{Target: host + ".", Port: uint16(portNum), Priority: priority, Weight: weight}
+}
+
+func TestNewThriftPoolSRV(t *testing.T) {
+	endpoint1, stop1 := startTestServer(t, "127.0.0.1:0")
+	defer stop1()
+	endpoint2, stop2 := startTestServer(t, "127.0.0.1:0")
+	defer stop2()
+	backup, stop3 := startTestServer(t, "127.0.0.1:0")
+	defer stop3()
+
+	var mutex sync.Mutex
+	records := []*net.SRV{
+		srvRecord(t, endpoint1, 10, 3),
+		srvRecord(t, endpoint2, 10, 1),
+		srvRecord(t, backup, 20, 100),
+	}
+	defer func() { lookupSRV = net.LookupSRV }()
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return name, records, nil
+	}
+
+	pool, err := NewThriftPoolSRV("_echo._tcp.example.com", 1000, 60000, 20, 1,
+		WithSRVRefresh(20), WithLogger(&testLogger{}))
+	if err != nil {
+		t.Fatalf("NewThriftPoolSRV error:%s\n", err.Error())
+	}
+	defer pool.Close()
+
+	counts := make(map[string]int)
+	conns := make([]*ThriftConn, 0, 8)
+	for i := 0; i < 8; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		counts[conn.GetEndpoint()]++
+		conns = append(conns, conn)
+	}
+	if counts[endpoint1] != 6 || counts[endpoint2] != 2 || counts[backup] != 0 {
+		t.Errorf("dials should follow the weights of the best priority, got %v\n", counts)
+	}
+	for _, conn := range conns {
+		_ = pool.Put(conn)
+	}
+
+	// 重新解析后 endpoint2 被移除，其连接在被取出时关闭
+	mutex.Lock()
+	records = records[:1]
+	mutex.Unlock()
+	deadline := time.Now().Add(time.Second)
+	for len(pool.GetTargets()) != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if targets := pool.GetTargets(); len(targets) != 1 || targets[0] != endpoint1 {
+		t.Fatalf("targets should be refreshed, got %v\n", targets)
+	}
+	for i := 0; i < 8; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		if conn.GetEndpoint() != endpoint1 {
+			t.Errorf("conn to a removed target should not be served, got %s\n", conn.GetEndpoint())
+		}
+		defer pool.Put(conn)
+	}
+}
+
+func TestCloneSRV(t *testing.T) {
+	endpoint1, stop1 := startTestServer(t, "127.0.0.1:0")
+	defer stop1()
+	endpoint2, stop2 := startTestServer(t, "127.0.0.1:0")
+	defer stop2()
+
+	var mutex sync.Mutex
+	records := []*net.SRV{
+		srvRecord(t, endpoint1, 10, 1),
+		srvRecord(t, endpoint2, 10, 1),
+	}
+	defer func() { lookupSRV = net.LookupSRV }()
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return name, records, nil
+	}
+
+	pool, err := NewThriftPoolSRV("_echo._tcp.example.com", 1000, 60000, 20, 0,
+		WithSRVRefresh(20), WithLogger(&testLogger{}))
+	if err != nil {
+		t.Fatalf("NewThriftPoolSRV error:%s\n", err.Error())
+	}
+	defer pool.Close()
+	clone := pool.Clone()
+	defer clone.Close()
+
+	if targets := clone.GetTargets(); len(targets) != 2 {
+		t.Fatalf("clone should copy the targets, got %v\n", targets)
+	}
+	conn, err := clone.Get(context.Background())
+	if err != nil {
+		t.Fatalf("clone.Get error:%s\n", err.Error())
+	}
+	if conn.GetEndpoint() != endpoint1 && conn.GetEndpoint() != endpoint2 {
+		t.Errorf("clone should dial a target, got %s\n", conn.GetEndpoint())
+	}
+	_ = clone.Put(conn)
+
+	// 克隆出的连接池同样定期重新解析
+	mutex.Lock()
+	records = records[:1]
+	mutex.Unlock()
+	deadline := time.Now().Add(time.Second)
+	for len(clone.GetTargets()) != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if targets := clone.GetTargets(); len(targets) != 1 || targets[0] != endpoint1 {
+		t.Errorf("clone targets should be refreshed, got %v\n", targets)
+	}
+}
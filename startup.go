@@ -0,0 +1,164 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// 启动时 InitSize 个连接未能全部拨号成功时的处理策略
+type StartupPolicy int32
+
+const (
+	StartupFailFast			StartupPolicy = iota	// 关闭连接池并返回错误（默认）
+	StartupBestEffort								// 使用已拨号成功的连接，同时返回 *StartupError 作为警告
+	StartupRetryBackground							// 使用已拨号成功的连接，不足的部分在后台持续重试
+)
+
+func (p StartupPolicy) String() string {
+	switch p {
+	case StartupFailFast:
+		return "FailFast"
+	case StartupBestEffort:
+		return "BestEffort"
+	case StartupRetryBackground:
+		return "RetryBackground"
+	default:
+		return "Unknown"
+	}
+}
+
+// 后台重试预热的间隔
+var warmUpInterval = time.Second
+
+// 启动时未能拨号足够的连接
+type StartupError struct {
+	Dialed	int32	// 拨号成功的连接数
+	Wanted	int32	// 期望的连接数，即 InitSize
+	Err		error	// 最后一次拨号失败的原因
+}
+
+func (e *StartupError) Error() string {
+	return fmt.Sprintf("thriftpool startup dialed %d/%d: %s", e.Dialed, e.Wanted, e.Err)
+}
+
+func (e *StartupError) Unwrap() error {
+	return e.Err
+}
+
+// 设置 NewThriftPoolChecked 的启动策略
+func WithStartupPolicy(policy StartupPolicy) Option {
+	return func(t *ThriftPool) {
+		t.StartupPolicy = policy
+	}
+}
+
+// 创建连接池并预先拨号 InitSize 个空闲连接，拨号不足时按 StartupPolicy 处理：
+// FailFast 关闭连接池并返回 *StartupError；BestEffort 返回可用的连接池和 *StartupError；
+// RetryBackground 返回可用的连接池和nil，不足的部分由后台协程重试（退避和暂停见 WithWarmUpRetry），直到补足或连接池关闭。
+// endpoint 先经 NormalizeEndpoint 校验和规范化，格式错误时不创建连接池，返回包装了 ErrBadEndpoint 的错误；
+// 连接数参数需要调整时（如 maxSize 小于 initSize 的两倍）同样返回错误，参见 WithLenientSizes
+func NewThriftPoolChecked(endpoint string, dialTimeout, idleTimeout, maxSize, initSize int32, opts ...Option) (*ThriftPool, error) {
+	endpoint, err := NormalizeEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	t := NewThriftPool(endpoint, dialTimeout, idleTimeout, maxSize, initSize, opts...)
+	if err := t.checkSizes(); err != nil {
+		t.Close()
+		return nil, t.nameErr(err)
+	}
+	dialed, err := t.fillIdle(t.ctx)
+	if err == nil {
+		return t, nil
+	}
+	startupErr := &StartupError{Dialed: dialed, Wanted: t.InitSize, Err: err}
+	switch t.StartupPolicy {
+	case StartupBestEffort:
+		return t, t.nameErr(startupErr)
+	case StartupRetryBackground:
+		t.logf("%s, retrying in background", startupErr.Error())
+		t.setWarmUpState(WarmUpActive)
+		t.goWorker(t.retryWarmUp)
+		return t, nil
+	default:
+		t.Close()
+		return nil, t.nameErr(startupErr)
+	}
+}
+
+// 预先拨号补足 InitSize 个空闲连接，供 NewThriftPool 创建的连接池在接入流量前预热，避免前 InitSize 个 Get 各自拨号。
+// 已打开的连接数已达 InitSize 时直接返回nil；拨号不足时返回 *StartupError，已拨号成功的连接留在池中，可再次调用补足。
+// ctx 结束时停止拨号。NewThriftPoolChecked 创建时已经预热，无需再调用
+func (t *ThriftPool) WarmUp(ctx context.Context) error {
+	ctx, cancel := t.withPoolContext(ctx)
+	defer cancel()
+	dialed, err := t.fillIdle(ctx)
+	if err != nil {
+		return t.nameErr(&StartupError{Dialed: dialed, Wanted: t.InitSize, Err: err})
+	}
+	return nil
+}
+
+// 拨号补足 InitSize 个连接并放入空闲队列，返回本次拨号成功的连接数和最后一次失败的原因
+func (t *ThriftPool) fillIdle(ctx context.Context) (int32, error) {
+	var dialed int32
+	var lastErr error
+	for t.GetOpen() < t.InitSize {
+		if t.ctx.Err() != nil {
+			return dialed, ErrPoolClosed
+		}
+		if err := ctx.Err(); err != nil {
+			return dialed, err
+		}
+		if t.IsPaused() {
+			return dialed, ErrPaused
+		}
+		if !t.allowDial() {
+			return dialed, ErrBreakerOpen
+		}
+		if err := t.waitDialToken(ctx); err != nil {
+			return dialed, err
+		}
+		conn, err := t.dial(ctx)
+		if err != nil {
+			lastErr = err
+			break
+		}
+		if !t.tryAddIdle() {
+			t.discard(conn)
+			break
+		}
+		t.restoreIdle(conn)
+		dialed++
+	}
+	if t.GetOpen() < t.InitSize && lastErr != nil {
+		return dialed, lastErr
+	}
+	return dialed, nil
+}
+
+// WaitReady 检查就绪的间隔
+var readyCheckInterval = 10 * time.Millisecond
+
+// 阻塞直到连接池就绪，即空闲连接数与已用连接数之和达到 InitSize，
+// 可配合 StartupRetryBackground 在预热完成前阻止流量进入。
+// ctx 结束时返回包装了 ctx.Err() 的错误并附带当前的连接数，连接池关闭时返回 ErrPoolClosed
+func (t *ThriftPool) WaitReady(ctx context.Context) error {
+	ticker := time.NewTicker(readyCheckInterval)
+	defer ticker.Stop()
+	for {
+		if t.GetIdle()+t.GetUsed() >= t.InitSize {
+			return nil
+		}
+		select {
+		case <-t.ctx.Done():
+			return t.nameErr(ErrPoolClosed)
+		case <-ctx.Done():
+			return t.nameErr(fmt.Errorf("%w: pool not ready, idle:%d, used:%d, init:%d",
+				ctx.Err(), t.GetIdle(), t.GetUsed(), t.InitSize))
+		case <-ticker.C:
+		}
+	}
+}
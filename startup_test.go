@@ -0,0 +1,154 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 前 n 次拨号成功，之后失败，直到 recover 被置为1
+func flakyWrapper(n int32, recovered *int32) Option {
+	var dials int32
+	return WithTransportWrapper(func(socket *thrift.TSocket) (thrift.TTransport, error) {
+		if atomic.AddInt32(&dials, 1) > n && atomic.LoadInt32(recovered) == 0 {
+			return nil, errors.New("handshake failed")
+		}
+		return socket, nil
+	})
+}
+
+// 通过遍历空闲队列等待 n 个连接真正入队，GetIdle 在入队前就已计数
+func waitQueued(pool *ThriftPool, n int32) bool {
+	queued := func() int32 {
+		h := pool.GetAgeHistogram()
+		return h.Under1s + h.Under10s + h.Under1m + h.Under10m + h.Older
+	}
+	deadline := time.Now().Add(time.Second)
+	for queued() < n && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return queued() == n
+}
+
+func TestStartupPolicy(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	var recovered int32
+	pool, err := NewThriftPoolChecked(endpoint, 100, 60000, 10, 3, flakyWrapper(1, &recovered))
+	var startupErr *StartupError
+	if pool != nil || !errors.As(err, &startupErr) || startupErr.Dialed != 1 || startupErr.Wanted != 3 {
+		t.Errorf("FailFast should return a StartupError, got %v\n", err)
+	}
+
+	pool, err = NewThriftPoolChecked(endpoint, 100, 60000, 10, 3, flakyWrapper(2, &recovered),
+		WithStartupPolicy(StartupBestEffort))
+	if pool == nil || !errors.As(err, &startupErr) || startupErr.Dialed != 2 {
+		t.Errorf("BestEffort should return the pool and a StartupError, got %v\n", err)
+	} else {
+		if pool.GetIdle() != 2 {
+			t.Errorf("BestEffort pool should keep 2 idle connections, got %d\n", pool.GetIdle())
+		}
+		pool.Close()
+	}
+
+	interval := warmUpInterval
+	warmUpInterval = 10 * time.Millisecond
+	defer func() { warmUpInterval = interval }()
+	pool, err = NewThriftPoolChecked(endpoint, 100, 60000, 10, 3, flakyWrapper(1, &recovered),
+		WithStartupPolicy(StartupRetryBackground))
+	if pool == nil || err != nil {
+		t.Fatalf("RetryBackground should return the pool without error, got %v\n", err)
+	}
+	defer pool.Close()
+	if pool.GetIdle() != 1 {
+		t.Errorf("RetryBackground pool should start with 1 idle connection, got %d\n", pool.GetIdle())
+	}
+	atomic.StoreInt32(&recovered, 1)
+	if !waitQueued(pool, 3) {
+		t.Errorf("background warm up should fill InitSize, idle:%d\n", pool.GetIdle())
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	var recovered int32
+	interval := warmUpInterval
+	warmUpInterval = 10 * time.Millisecond
+	defer func() { warmUpInterval = interval }()
+	pool, err := NewThriftPoolChecked(endpoint, 100, 60000, 10, 3, flakyWrapper(1, &recovered),
+		WithStartupPolicy(StartupRetryBackground))
+	if err != nil {
+		t.Fatalf("NewThriftPoolChecked error:%s\n", err.Error())
+	}
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := pool.WaitReady(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitReady should time out before warm up, got %v\n", err)
+	}
+
+	atomic.StoreInt32(&recovered, 1)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := pool.WaitReady(ctx); err != nil {
+		t.Errorf("WaitReady error:%s\n", err)
+	}
+	waitQueued(pool, 3)
+}
+
+func TestWarmUp(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 3)
+	defer pool.Close()
+
+	if pool.GetIdle() != 0 {
+		t.Fatalf("NewThriftPool should not dial, idle:%d\n", pool.GetIdle())
+	}
+	if err := pool.WarmUp(context.Background()); err != nil {
+		t.Fatalf("pool.WarmUp error:%s\n", err.Error())
+	}
+	if pool.GetIdle() != pool.GetInitSize() {
+		t.Errorf("idle should equal InitSize after warm up, idle:%d\n", pool.GetIdle())
+	}
+	// 预热过的连接直接借出，不再拨号
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if pool.GetDialedConns() != 3 {
+		t.Errorf("Get should reuse a warmed conn, dialed:%d\n", pool.GetDialedConns())
+	}
+	_ = pool.Put(conn)
+	// 已达 InitSize 时再次预热不拨号
+	if err := pool.WarmUp(context.Background()); err != nil || pool.GetDialedConns() != 3 {
+		t.Errorf("warm up of a full pool should not dial, dialed:%d, err:%v\n", pool.GetDialedConns(), err)
+	}
+
+	// 拨号失败时返回 *StartupError
+	refused := NewThriftPool(refusedEndpoint(t), 100, 60000, 10, 2)
+	defer refused.Close()
+	var startupErr *StartupError
+	if err := refused.WarmUp(context.Background()); !errors.As(err, &startupErr) {
+		t.Fatalf("failed warm up should return *StartupError, got %v\n", err)
+	}
+	if startupErr.Dialed != 0 || startupErr.Wanted != 2 {
+		t.Errorf("startup error should report 0/2, got %d/%d\n", startupErr.Dialed, startupErr.Wanted)
+	}
+
+	// ctx 已结束时不拨号
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := refused.WarmUp(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("warm up with a cancelled ctx should fail with context.Canceled, got %v\n", err)
+	}
+}
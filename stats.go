@@ -0,0 +1,106 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"fmt"
+	"time"
+)
+
+// 连接池的状态快照
+type PoolStats struct {
+	Name			string	// 连接池名称
+	Endpoint		string	// 服务端的端点
+	Used			int32	// 已用连接数
+	Idle			int32	// 空闲连接数
+	Open			int32	// 已打开的连接数
+	MaxSize			int32	// 最大连接数
+	Throttled		bool	// 是否处于 ThrottleTo 的限流中
+	EffectiveMaxSize	int32	// 当前生效的最大连接数，限流时可能小于 MaxSize
+	InitSize		int32	// 初始连接数
+	MaxIdle			int32	// 最大空闲连接数，为0时不单独限制
+	DialFailures	int32	// 连续拨号失败次数
+	BreakerOpen		bool	// 熔断是否打开
+	DroppedEvents	int64	// 丢弃的事件数
+	BufferedBytes	int64	// 缓冲占用的字节数
+	Dialing			int32	// 正在拨号的数量
+	DialLatencyP50	time.Duration	// 拨号成功（含 TransportWrapper 握手）耗时的中位数，按直方图桶的上界估算
+	DialLatencyP95	time.Duration	// 拨号成功耗时的95分位数
+	DialLatencyP99	time.Duration	// 拨号成功耗时的99分位数
+	HoldTimeP50		time.Duration	// 连接从借出到 Put 归还的持有时长的中位数，包括调用耗时和业务处理时间
+	HoldTimeP95		time.Duration	// 持有时长的95分位数
+	HoldTimeP99		time.Duration	// 持有时长的99分位数
+	EvictedIdle		int64	// 因闲置超时（包括 Shrink）关闭的连接数
+	EvictedOverMax	int64	// 因空闲连接数已达上限或超出限流值关闭的连接数
+	EvictedBroken	int64	// 因连接不可用、心跳或校验失败关闭的连接数
+	EvictedStale	int64	// 因 Rebind 或目标变化关闭的旧连接数
+	EvictedLeaked	int64	// 因借出超时（疑似泄漏）被回收的连接数
+	EvictedLifetime	int64	// 因超过最长存活时间关闭的连接数
+	EvictedBytes	int64	// 因累计读写超过字节预算关闭的连接数
+	Overflow		int32	// 借出中的临时连接数，不计入 Used 和 Open
+	MaxOverflow		int32	// 临时连接的最大数，为0时不允许溢出
+	EndpointUsed	map[string]int32	// 各端点借出中的连接数，不包括借出数为0的端点
+	Goroutines		int32	// 运行中的后台协程数
+	ThrottledDials	int64	// 因拨号速率限制而等待过的拨号数
+	Probes			int32	// 借出中的探测连接数，不计入 Used
+	WarmUp			WarmUpState	// 后台预热的状态
+	MinIdle			int32	// 保持的最少空闲连接数，为0时不保持
+	Replacements	int32	// 最近一轮保持 MinIdle 时补连的连接数
+	DialedConns		int64	// 累计拨号创建的连接数
+	AdoptedConns	int64	// 累计 Adopt 接管的连接数
+	IOBytes			int64	// 所有连接累计读写的字节数，开启字节计数时统计
+}
+
+// 连接池的状态快照，各项数据分别读取，彼此之间不保证严格一致
+func (t *ThriftPool) Stats() PoolStats {
+	return PoolStats{
+		Name:           t.Name,
+		Endpoint:       t.GetEndpoint(),
+		Used:           t.GetUsed(),
+		Idle:           t.GetIdle(),
+		Open:           t.GetOpen(),
+		MaxSize:        t.GetMaxSize(),
+		Throttled:      t.IsThrottled(),
+		EffectiveMaxSize: t.GetEffectiveMaxSize(),
+		InitSize:       t.GetInitSize(),
+		MaxIdle:        t.GetMaxIdle(),
+		DialFailures:   t.GetDialFailures(),
+		BreakerOpen:    t.IsBreakerOpen(),
+		DroppedEvents:  t.GetDroppedEvents(),
+		BufferedBytes:  t.GetBufferedBytes(),
+		Dialing:        t.GetDialing(),
+		DialLatencyP50: t.dialLatency.percentile(0.50),
+		DialLatencyP95: t.dialLatency.percentile(0.95),
+		DialLatencyP99: t.dialLatency.percentile(0.99),
+		HoldTimeP50:    t.holdLatency.percentile(0.50),
+		HoldTimeP95:    t.holdLatency.percentile(0.95),
+		HoldTimeP99:    t.holdLatency.percentile(0.99),
+		EvictedIdle:    t.getEvicted(evictIdle),
+		EvictedOverMax: t.getEvicted(evictOverMax),
+		EvictedBroken:  t.getEvicted(evictBroken),
+		EvictedStale:   t.getEvicted(evictStale),
+		EvictedLeaked:  t.getEvicted(evictReclaimed),
+		EvictedLifetime: t.getEvicted(evictLifetime),
+		EvictedBytes:   t.getEvicted(evictBytes),
+		Overflow:       t.GetOverflow(),
+		MaxOverflow:    t.GetMaxOverflow(),
+		EndpointUsed:   t.getEndpointUsed(),
+		Goroutines:     t.GoroutineCount(),
+		ThrottledDials: t.GetThrottledDials(),
+		Probes:         t.GetProbes(),
+		WarmUp:         t.GetWarmUpState(),
+		MinIdle:        t.GetMinIdle(),
+		Replacements:   t.GetReplacements(),
+		DialedConns:    t.GetDialedConns(),
+		AdoptedConns:   t.GetAdoptedConns(),
+		IOBytes:        t.GetIOBytes(),
+	}
+}
+
+func (s PoolStats) String() string {
+	return fmt.Sprintf("thriftpool(%s) %s, used:%d, idle:%d, open:%d, init:%d, max:%d",
+		s.Name, s.Endpoint, s.Used, s.Idle, s.Open, s.InitSize, s.MaxSize)
+}
+
+func (t *ThriftPool) String() string {
+	return t.Stats().String()
+}
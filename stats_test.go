@@ -0,0 +1,75 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestName(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	logger := &testLogger{}
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1, WithName("echo"), WithLogger(logger))
+	defer pool.Close()
+
+	_, err := pool.GetIdleOnly(context.Background())
+	if !errors.Is(err, ErrNoIdle) || !strings.Contains(err.Error(), "echo") {
+		t.Errorf("error %v should wrap ErrNoIdle and carry the pool name\n", err)
+	}
+	pool.logf("hello %d", 1)
+	if len(logger.lines) != 1 || logger.lines[0] != "[echo] hello 1" {
+		t.Errorf("unexpected log lines:%v\n", logger.lines)
+	}
+
+	stats := pool.Stats()
+	if stats.Name != "echo" || stats.Endpoint != endpoint || stats.MaxSize != 10 {
+		t.Errorf("unexpected stats:%+v\n", stats)
+	}
+	if !strings.Contains(pool.String(), "thriftpool(echo)") {
+		t.Errorf("String should include the pool name: %s\n", pool.String())
+	}
+}
+
+func TestEvictionCounters(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	clock := newFakeClock()
+	pool := NewThriftPool(endpoint, 1000, 10000, 10, 1, WithMaxIdle(2), withClock(clock.Now))
+	defer pool.Close()
+
+	conns := make([]*ThriftConn, 0, 4)
+	for i := 0; i < 4; i++ {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns = append(conns, conn)
+	}
+	// 1个不可用，2个放回，第4个超出 MaxIdle
+	_ = conns[0].CloseTransport()
+	for _, conn := range conns {
+		_ = pool.Put(conn)
+	}
+	// 闲置超时后回收到 InitSize
+	clock.Advance(11 * time.Second)
+	pool.releaseIdle()
+
+	stats := pool.Stats()
+	if stats.EvictedBroken != 1 || stats.EvictedOverMax != 1 || stats.EvictedIdle != 1 {
+		t.Errorf("unexpected eviction counters, broken:%d, overMax:%d, idle:%d\n",
+			stats.EvictedBroken, stats.EvictedOverMax, stats.EvictedIdle)
+	}
+}
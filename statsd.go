@@ -0,0 +1,64 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"time"
+)
+
+// StatsD客户端的最小接口，由使用方适配具体的StatsD库
+type StatsdClient interface {
+	Gauge(name string, value int64)
+	Count(name string, value int64)
+}
+
+// 把连接池的指标写入StatsD
+// 每隔 interval 毫秒上报 prefix.idle、prefix.used、prefix.open、prefix.max 四个Gauge，
+// 并在拨号成功、拨号失败、连接被关闭时分别累加 prefix.dialed、prefix.dial_failed、prefix.evicted 三个Count。
+// interval 小于1时默认10s，prefix 为空时默认 thriftpool
+func WithStatsd(client StatsdClient, interval int32, prefix string) Option {
+	return func(t *ThriftPool) {
+		t.statsd = client
+		if interval < 1 {
+			t.statsdInterval = time.Duration(10000) * time.Millisecond
+		} else {
+			t.statsdInterval = time.Duration(interval) * time.Millisecond
+		}
+		if prefix == "" {
+			t.statsdPrefix = "thriftpool"
+		} else {
+			t.statsdPrefix = prefix
+		}
+	}
+}
+
+// 定期上报Gauge，连接池关闭时退出
+func (t *ThriftPool) reportStatsd() {
+	ticker := time.NewTicker(t.statsdInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		t.statsd.Gauge(t.statsdPrefix+".idle", int64(t.GetIdle()))
+		t.statsd.Gauge(t.statsdPrefix+".used", int64(t.GetUsed()))
+		t.statsd.Gauge(t.statsdPrefix+".open", int64(t.GetOpen()))
+		t.statsd.Gauge(t.statsdPrefix+".max", int64(t.GetMaxSize()))
+	}
+}
+
+// 按事件累加Count
+func (t *ThriftPool) countStatsd(eventType EventType) {
+	switch eventType {
+	case EventDialed:
+		t.statsd.Count(t.statsdPrefix+".dialed", 1)
+	case EventDialFailed:
+		t.statsd.Count(t.statsdPrefix+".dial_failed", 1)
+	case EventAdopted:
+		t.statsd.Count(t.statsdPrefix+".adopted", 1)
+	case EventEvicted:
+		t.statsd.Count(t.statsdPrefix+".evicted", 1)
+	}
+}
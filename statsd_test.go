@@ -0,0 +1,65 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testStatsd struct {
+	mutex	sync.Mutex
+	gauges	map[string]int64
+	counts	map[string]int64
+}
+
+func (s *testStatsd) Gauge(name string, value int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.gauges[name] = value
+}
+
+func (s *testStatsd) Count(name string, value int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.counts[name] += value
+}
+
+func (s *testStatsd) get(metrics map[string]int64, name string) (int64, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	value, ok := metrics[name]
+	return value, ok
+}
+
+func TestStatsd(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	statsd := &testStatsd{gauges: make(map[string]int64), counts: make(map[string]int64)}
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1, WithStatsd(statsd, 10, "echo"))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if dialed, _ := statsd.get(statsd.counts, "echo.dialed"); dialed != 1 {
+		t.Errorf("echo.dialed is %d, want 1\n", dialed)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if used, ok := statsd.get(statsd.gauges, "echo.used"); ok && used == 1 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if used, _ := statsd.get(statsd.gauges, "echo.used"); used != 1 {
+		t.Errorf("echo.used is %d, want 1\n", used)
+	}
+	if max, _ := statsd.get(statsd.gauges, "echo.max"); max != 10 {
+		t.Errorf("echo.max is %d, want 10\n", max)
+	}
+	_ = pool.Put(conn)
+}
@@ -0,0 +1,109 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync/atomic"
+)
+
+// Get 从多个空闲连接中挑选连接的策略
+type IdleStrategy int32
+
+const (
+	IdleFIFO				IdleStrategy = iota	// 取最早放回的空闲连接，各连接轮流使用（默认）
+	IdleLIFO									// 取最近放回的空闲连接，其余连接更容易闲置超时被回收
+	IdleLeastLoadedEndpoint						// 取借出中连接最少的端点的空闲连接，用于多端点连接池均衡服务端负载
+)
+
+func (s IdleStrategy) String() string {
+	switch s {
+	case IdleFIFO:
+		return "FIFO"
+	case IdleLIFO:
+		return "LIFO"
+	case IdleLeastLoadedEndpoint:
+		return "LeastLoadedEndpoint"
+	default:
+		return "Unknown"
+	}
+}
+
+// 空闲连接的挑选策略，参见 IdleStrategy
+// 挑选在持有空闲队列的锁时直接进行，不会取出其它连接；LeastLoadedEndpoint 要比较每个空闲连接，开销和空闲连接数成正比
+func WithIdleStrategy(strategy IdleStrategy) Option {
+	return func(t *ThriftPool) {
+		t.IdleStrategy = strategy
+	}
+}
+
+// 按 IdleStrategy 取出一个空闲连接，没有空闲连接时返回nil
+// 后台协程（回收、心跳等）取连接时 inOrder 为 true，总是取队首最久未使用的连接；
+// endpoint 不为空时取最早放回的连到该端点的连接
+func (t *ThriftPool) popIdle(inOrder bool, endpoint string) *ThriftConn {
+	if endpoint != "" {
+		return t.idleConns.pick(func(conns []*ThriftConn) int {
+			for i, conn := range conns {
+				if conn.GetEndpoint() == endpoint {
+					return i
+				}
+			}
+			return -1
+		})
+	}
+	if inOrder || t.IdleStrategy == IdleFIFO {
+		return t.idleConns.popFront()
+	}
+	return t.idleConns.pick(t.chooseIdle)
+}
+
+// 在持有空闲队列的锁时按 IdleStrategy 挑选连接，返回下标
+func (t *ThriftPool) chooseIdle(conns []*ThriftConn) int {
+	switch t.IdleStrategy {
+	case IdleLIFO:
+		return len(conns) - 1
+	case IdleLeastLoadedEndpoint:
+		selected, least := 0, t.GetEndpointUsed(conns[0].GetEndpoint())
+		for i := 1; i < len(conns); i++ {
+			if used := t.GetEndpointUsed(conns[i].GetEndpoint()); used < least {
+				selected, least = i, used
+			}
+		}
+		return selected
+	}
+	return 0
+}
+
+// 端点借出中的连接数
+func (t *ThriftPool) GetEndpointUsed(endpoint string) int32 {
+	if counter, ok := t.endpointUsed.Load(endpoint); ok {
+		return atomic.LoadInt32(counter.(*int32))
+	}
+	return 0
+}
+
+// 各端点借出中的连接数，不包括借出数为0的端点
+func (t *ThriftPool) getEndpointUsed() map[string]int32 {
+	endpointUsed := make(map[string]int32)
+	t.endpointUsed.Range(func(key, value interface{}) bool {
+		if used := atomic.LoadInt32(value.(*int32)); used != 0 {
+			endpointUsed[key.(string)] = used
+		}
+		return true
+	})
+	return endpointUsed
+}
+
+// 连接借出时计入其端点的借出数
+func (t *ThriftPool) addEndpointUsed(conn *ThriftConn) {
+	counter, ok := t.endpointUsed.Load(conn.GetEndpoint())
+	if !ok {
+		counter, _ = t.endpointUsed.LoadOrStore(conn.GetEndpoint(), new(int32))
+	}
+	atomic.AddInt32(counter.(*int32), 1)
+}
+
+// 连接归还或被回收时扣减其端点的借出数
+func (t *ThriftPool) subEndpointUsed(conn *ThriftConn) {
+	if counter, ok := t.endpointUsed.Load(conn.GetEndpoint()); ok {
+		atomic.AddInt32(counter.(*int32), -1)
+	}
+}
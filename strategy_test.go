@@ -0,0 +1,132 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestIdleStrategy(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	for _, strategy := range []IdleStrategy{IdleFIFO, IdleLIFO} {
+		pool := NewThriftPool(endpoint, 2000, 60000, 5, 0, WithIdleStrategy(strategy))
+		conns := make([]*ThriftConn, 3)
+		for i := range conns {
+			conn, err := pool.Get(context.Background())
+			if err != nil {
+				t.Fatalf("get error:%s\n", err.Error())
+			}
+			conns[i] = conn
+		}
+		for _, conn := range conns {
+			_ = pool.Put(conn)
+		}
+
+		want := conns[0]
+		if strategy == IdleLIFO {
+			want = conns[2]
+		}
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("get error:%s\n", err.Error())
+		}
+		if conn != want {
+			t.Errorf("%s picked the wrong idle conn\n", strategy)
+		}
+		if pool.GetIdle() != 2 {
+			t.Errorf("%s should keep the other idle conns, idle:%d\n", strategy, pool.GetIdle())
+		}
+		_ = pool.Put(conn)
+		pool.Close()
+	}
+}
+
+func TestLeastLoadedEndpoint(t *testing.T) {
+	endpoint1, stop1 := startTestServer(t, "127.0.0.1:0")
+	defer stop1()
+	endpoint2, stop2 := startTestServer(t, "127.0.0.1:0")
+	defer stop2()
+
+	pool := NewThriftPool(endpoint1, 2000, 60000, 5, 0, WithIdleStrategy(IdleLeastLoadedEndpoint))
+	defer pool.Close()
+	pool.SetTargets([]Target{{Endpoint: endpoint1, Weight: 1}, {Endpoint: endpoint2, Weight: 1}})
+
+	conns := make([]*ThriftConn, 4)
+	for i := range conns {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("get error:%s\n", err.Error())
+		}
+		conns[i] = conn
+	}
+	for _, conn := range conns {
+		_ = pool.Put(conn)
+	}
+
+	first, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get error:%s\n", err.Error())
+	}
+	second, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("get error:%s\n", err.Error())
+	}
+	if first.GetEndpoint() == second.GetEndpoint() {
+		t.Errorf("second conn should come from the less loaded endpoint, both from %s\n", first.GetEndpoint())
+	}
+	stats := pool.Stats()
+	if stats.EndpointUsed[endpoint1] != 1 || stats.EndpointUsed[endpoint2] != 1 {
+		t.Errorf("unexpected endpoint used:%v\n", stats.EndpointUsed)
+	}
+
+	_ = pool.Put(first)
+	_ = pool.Put(second)
+	if used := pool.GetEndpointUsed(endpoint1) + pool.GetEndpointUsed(endpoint2); used != 0 {
+		t.Errorf("endpoint used should drop to 0 after put, got %d\n", used)
+	}
+}
+
+func TestIdleStrategyConcurrentGet(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	for _, strategy := range []IdleStrategy{IdleLIFO, IdleLeastLoadedEndpoint} {
+		// 连接都已打开，挑选空闲连接时其它 Get 必须仍能取到空闲连接，不能因拨号名额用尽而报满
+		pool := NewThriftPool(endpoint, 2000, 60000, 3, 0, WithIdleStrategy(strategy))
+		conns := make([]*ThriftConn, 3)
+		for i := range conns {
+			conn, err := pool.Get(context.Background())
+			if err != nil {
+				t.Fatalf("get error:%s\n", err.Error())
+			}
+			conns[i] = conn
+		}
+		for _, conn := range conns {
+			_ = pool.Put(conn)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					conn, err := pool.Get(context.Background())
+					if err != nil {
+						t.Errorf("%s get error:%s\n", strategy, err.Error())
+						return
+					}
+					_ = pool.Put(conn)
+				}
+			}()
+		}
+		wg.Wait()
+		if pool.GetOpen() != 3 || pool.GetIdle() != 3 {
+			t.Errorf("%s open:%d, idle:%d, want 3, 3\n", strategy, pool.GetOpen(), pool.GetIdle())
+		}
+		pool.Close()
+	}
+}
@@ -0,0 +1,80 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// 多端点连接池的一个目标
+type Target struct {
+	Endpoint	string	// 服务端的端点
+	Weight		int32	// 权重，小于1时按1处理
+}
+
+// 一组按权重轮询的目标，创建后只读
+type targetSet struct {
+	endpoints	[]string
+	cumulative	[]int32			// 权重前缀和，用于按权重选取
+	members		map[string]bool
+}
+
+func newTargetSet(targets []Target) *targetSet {
+	set := &targetSet{
+		endpoints:  make([]string, 0, len(targets)),
+		cumulative: make([]int32, 0, len(targets)),
+		members:    make(map[string]bool, len(targets)),
+	}
+	var total int32
+	for _, target := range targets {
+		if set.members[target.Endpoint] {
+			continue
+		}
+		weight := target.Weight
+		if weight < 1 {
+			weight = 1
+		}
+		total += weight
+		set.endpoints = append(set.endpoints, target.Endpoint)
+		set.cumulative = append(set.cumulative, total)
+		set.members[target.Endpoint] = true
+	}
+	return set
+}
+
+// 按权重轮询选取一个端点，cursor 为连接池的轮询计数
+func (s *targetSet) pick(cursor *uint32) string {
+	total := s.cumulative[len(s.cumulative)-1]
+	n := int32((atomic.AddUint32(cursor, 1) - 1) % uint32(total))
+	i := sort.Search(len(s.cumulative), func(i int) bool {
+		return s.cumulative[i] > n
+	})
+	return s.endpoints[i]
+}
+
+// 设置多个目标，之后新拨号的连接按权重轮流连到各目标，targets 为空时忽略。
+// 不再属于目标的空闲连接在被 Get 取出或空闲回收时关闭，使用中的连接在 Put 时关闭；
+// 仍属于目标的连接不受影响。GetEndpoint 仍返回连接池的端点（如SRV名称），
+// 各连接实际连接的目标见 ThriftConn.GetEndpoint
+func (t *ThriftPool) SetTargets(targets []Target) {
+	if len(targets) == 0 {
+		return
+	}
+	t.rebindMutex.Lock()
+	defer t.rebindMutex.Unlock()
+	current := t.loadBinding()
+	t.binding.Store(binding{
+		endpoint:   current.endpoint,
+		generation: current.generation,
+		targets:    newTargetSet(targets),
+	})
+}
+
+// 当前的目标端点列表，未设置多个目标时为nil
+func (t *ThriftPool) GetTargets() []string {
+	targets := t.loadBinding().targets
+	if targets == nil {
+		return nil
+	}
+	return append([]string(nil), targets.endpoints...)
+}
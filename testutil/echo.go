@@ -0,0 +1,77 @@
+// Package testutil provides helpers for end-to-end tests of thriftpool
+package testutil
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/tianxingpan/thriftpool/example/echo"
+)
+
+// example 中 Echo 服务的实现，总是返回 "success"
+type EchoServer struct {
+}
+
+func (e *EchoServer) Echo(req *echo.EchoReq) (*echo.EchoRes, error) {
+	return &echo.EchoRes{Msg: "success"}, nil
+}
+
+// 在本机随机端口启动 Echo 服务，与 example/thrift_server.go 一样使用 framed transport 和 binary protocol，
+// 返回监听的端点和关闭函数；关闭函数停止监听并断开所有已接受的连接。
+// 用于不依赖外部进程的端到端测试，客户端连接池需开启 WithFramedTransport
+func StartEchoServer(t testing.TB) (string, func()) {
+	return StartEchoServerWith(t, nil)
+}
+
+// 同 StartEchoServer，factory 不为nil时先用它包装每个连接的socket，再套上 framed transport，
+// 如 thrift.NewTZlibTransportFactory 用于测试 zlib 压缩
+func StartEchoServerWith(t testing.TB, factory thrift.TTransportFactory) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error:%s\n", err.Error())
+	}
+	processor := echo.NewEchoProcessor(&EchoServer{})
+
+	var mutex sync.Mutex
+	var conns []net.Conn
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mutex.Lock()
+			conns = append(conns, conn)
+			mutex.Unlock()
+			go serveEcho(processor, factory, conn)
+		}
+	}()
+	return listener.Addr().String(), func() {
+		_ = listener.Close()
+		mutex.Lock()
+		defer mutex.Unlock()
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+		conns = nil
+	}
+}
+
+// 处理一个连接上的请求，直到连接关闭或出错
+func serveEcho(processor thrift.TProcessor, factory thrift.TTransportFactory, conn net.Conn) {
+	defer conn.Close()
+	var transport thrift.TTransport = thrift.NewTSocketFromConnTimeout(conn, 0)
+	if factory != nil {
+		transport = factory.GetTransport(transport)
+	}
+	transport = thrift.NewTFramedTransport(transport)
+	prot := thrift.NewTBinaryProtocolTransport(transport)
+	for {
+		ok, err := processor.Process(prot, prot)
+		if err != nil || !ok {
+			return
+		}
+	}
+}
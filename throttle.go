@@ -0,0 +1,48 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync/atomic"
+)
+
+// 临时把最大连接数降到 n（小于1时为1），用于故障期间限制连接池对共享后端的压力，Unthrottle 恢复。
+// 限流期间已打开的连接数达到 n 后不再拨号，超出的 Get 同连接池耗尽；已打开的连接不会被强制关闭，
+// 而是在归还时关闭，直到已打开的连接数降到 n 以内。MaxSize 本身不变，n 不小于 MaxSize 时不起作用
+func (t *ThriftPool) ThrottleTo(n int32) {
+	if n < 1 {
+		n = 1
+	}
+	atomic.StoreInt32(&t.throttle, n)
+	t.logf("throttled to %d connections, max:%d", n, t.MaxSize)
+}
+
+// 解除 ThrottleTo 的限流，恢复到 MaxSize，并唤醒等待的 GetPriority
+func (t *ThriftPool) Unthrottle() {
+	n := atomic.SwapInt32(&t.throttle, 0)
+	if n == 0 {
+		return
+	}
+	t.logf("unthrottled, max:%d", t.MaxSize)
+	for i := n; i < t.MaxSize && t.GetWaiting() > 0; i++ {
+		t.wakeWaiter()
+	}
+}
+
+// 是否处于 ThrottleTo 的限流中
+func (t *ThriftPool) IsThrottled() bool {
+	return atomic.LoadInt32(&t.throttle) > 0
+}
+
+// 当前生效的最大连接数，限流时为 MaxSize 和限流值中较小的一个
+func (t *ThriftPool) GetEffectiveMaxSize() int32 {
+	if n := atomic.LoadInt32(&t.throttle); n > 0 && n < t.MaxSize {
+		return n
+	}
+	return t.MaxSize
+}
+
+// 限流时已打开的连接数是否超出，归还的连接应关闭
+func (t *ThriftPool) isOverThrottle() bool {
+	n := atomic.LoadInt32(&t.throttle)
+	return n > 0 && t.GetOpen() > n
+}
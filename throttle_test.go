@@ -0,0 +1,70 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 4, 0)
+	defer pool.Close()
+
+	conns := make([]*ThriftConn, 3)
+	for i := range conns {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("pool.Get error:%s\n", err.Error())
+		}
+		conns[i] = conn
+	}
+	pool.ThrottleTo(1)
+	stats := pool.Stats()
+	if !stats.Throttled || stats.EffectiveMaxSize != 1 || stats.MaxSize != 4 {
+		t.Errorf("unexpected throttle stats, throttled:%v, effective:%d, max:%d\n",
+			stats.Throttled, stats.EffectiveMaxSize, stats.MaxSize)
+	}
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("get beyond the throttle should fail with ErrPoolExhausted, got %v\n", err)
+	}
+
+	// 已打开的连接在归还时关闭，直到降到限流值以内
+	for _, conn := range conns {
+		_ = pool.Put(conn)
+	}
+	if pool.GetOpen() != 1 || pool.GetIdle() != 1 || pool.Stats().EvictedOverMax != 2 {
+		t.Errorf("open conns should drain to the throttle, open:%d, idle:%d\n", pool.GetOpen(), pool.GetIdle())
+	}
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	// 限流期间等待的 GetPriority 在解除限流后拨号
+	done := make(chan error, 1)
+	go func() {
+		waiting, err := pool.GetPriority(context.Background(), PriorityHigh)
+		if err == nil {
+			_ = pool.Put(waiting)
+		}
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	pool.Unthrottle()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("waiter should dial after Unthrottle:%s\n", err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("waiter not woken by Unthrottle\n")
+	}
+	if pool.IsThrottled() || pool.GetEffectiveMaxSize() != 4 {
+		t.Errorf("Unthrottle should restore MaxSize, effective:%d\n", pool.GetEffectiveMaxSize())
+	}
+	_ = pool.Put(conn)
+}
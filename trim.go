@@ -0,0 +1,19 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+// 空闲连接回收策略，后台协程每轮回收时调用，返回本轮要关闭的空闲连接数
+type TrimPolicy func(stats PoolStats) int32
+
+// 自定义后台协程每轮（每秒）回收空闲连接的数量，如内存紧张时多回收、吞吐高时少回收
+// 设置后每轮按 policy 的返回值关闭空闲连接（默认最久未使用的，见 WithEvictionOrder），不再按 IdleTimeout 和 InitSize 判断，
+// 返回值小于1时本轮不回收，超过空闲连接数时全部回收；policy 为nil时使用默认的闲置超时回收
+func WithTrimPolicy(policy TrimPolicy) Option {
+	return func(t *ThriftPool) {
+		t.TrimPolicy = policy
+	}
+}
+
+// 按 TrimPolicy 回收一轮空闲连接，返回关闭的连接数
+func (t *ThriftPool) trimIdle() int32 {
+	return t.evictIdleConns(t.TrimPolicy(t.Stats()))
+}
@@ -0,0 +1,54 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTrimPolicy(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	var target, seenIdle int32
+	policy := func(stats PoolStats) int32 {
+		atomic.StoreInt32(&seenIdle, stats.Idle)
+		return atomic.LoadInt32(&target)
+	}
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithTrimPolicy(policy))
+	defer pool.Close()
+
+	conns := make([]*ThriftConn, 4)
+	for i := range conns {
+		conn, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatalf("get error:%s\n", err.Error())
+		}
+		conns[i] = conn
+	}
+	for _, conn := range conns {
+		_ = pool.Put(conn)
+	}
+
+	pool.releaseIdle()
+	if pool.GetIdle() != 4 {
+		t.Errorf("policy returning 0 should keep idle conns, idle:%d\n", pool.GetIdle())
+	}
+	atomic.StoreInt32(&target, 3)
+	pool.releaseIdle()
+	if atomic.LoadInt32(&seenIdle) != 4 {
+		t.Errorf("policy should see the idle count, got %d\n", atomic.LoadInt32(&seenIdle))
+	}
+	if pool.GetIdle() != 1 || pool.GetOpen() != 1 || !conns[0].IsClose() || conns[3].IsClose() {
+		t.Errorf("oldest idle conns should be trimmed, idle:%d, open:%d\n", pool.GetIdle(), pool.GetOpen())
+	}
+	if pool.Stats().EvictedIdle != 3 {
+		t.Errorf("trimmed conns should be counted as idle evictions, got %d\n", pool.Stats().EvictedIdle)
+	}
+	atomic.StoreInt32(&target, 10)
+	pool.releaseIdle()
+	if pool.GetIdle() != 0 {
+		t.Errorf("trim target beyond idle count should close all idle conns, idle:%d\n", pool.GetIdle())
+	}
+}
@@ -0,0 +1,42 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// 连接池闲置 ttl（单位：毫秒）后自动关闭，小于1表示不自动关闭（默认）
+// 没有借出的连接且 ttl 内没有 Get 或 Put（见 IsActive）时，回收协程关闭连接池，之后 Get 返回 ErrPoolClosed。
+// onClose 不为nil时在自动关闭后调用，可供管理器移除对该连接池的引用，需要时再重新创建。
+// 用于访问很少的长尾端点释放连接和后台协程
+func WithIdlePoolTTL(ttl int32, onClose func(pool *ThriftPool)) Option {
+	return func(t *ThriftPool) {
+		if ttl < 1 {
+			t.IdlePoolTTL = 0
+		} else {
+			t.IdlePoolTTL = time.Duration(ttl) * time.Millisecond
+		}
+		t.OnIdleClose = onClose
+	}
+}
+
+// 连接池闲置超过 IdlePoolTTL 时在另一个协程中关闭连接池并返回 true。
+// 由回收协程调用，Close 会等待回收协程退出，因此不能在回收协程中直接关闭
+func (t *ThriftPool) expireIdlePool() bool {
+	if t.IdlePoolTTL <= 0 || t.IsActive(t.IdlePoolTTL) {
+		return false
+	}
+	if !atomic.CompareAndSwapInt32(&t.idleClosing, 0, 1) {
+		// 已在关闭中
+		return true
+	}
+	go func() {
+		t.logf("pool idle for %s, closing", t.IdlePoolTTL)
+		t.Close()
+		if t.OnIdleClose != nil {
+			t.OnIdleClose(t)
+		}
+	}()
+	return true
+}
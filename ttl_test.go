@@ -0,0 +1,79 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdlePoolTTL(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	clock := newFakeClock()
+	closed := make(chan *ThriftPool, 1)
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 1, withClock(clock.Now),
+		WithIdlePoolTTL(5000, func(pool *ThriftPool) { closed <- pool }))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	// 有借出的连接时不关闭
+	clock.Advance(10 * time.Second)
+	if pool.expireIdlePool() {
+		t.Errorf("pool with borrowed conns should not expire\n")
+	}
+	_ = pool.Put(conn)
+	clock.Advance(2 * time.Second)
+	if pool.expireIdlePool() {
+		t.Errorf("recently used pool should not expire\n")
+	}
+
+	clock.Advance(6 * time.Second)
+	if !pool.expireIdlePool() {
+		t.Fatalf("idle pool should expire\n")
+	}
+	select {
+	case p := <-closed:
+		if p != pool {
+			t.Errorf("OnIdleClose should receive the closed pool\n")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("OnIdleClose was not called\n")
+	}
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("expired pool should return ErrPoolClosed, got %v\n", err)
+	}
+	if pool.GetOpen() != 0 {
+		t.Errorf("expired pool should close its conns, open:%d\n", pool.GetOpen())
+	}
+}
+
+func TestIdlePoolTTLWithMinIdle(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	clock := newFakeClock()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0, withClock(clock.Now),
+		WithMinIdle(1, 10, 0), WithIdlePoolTTL(5000, nil))
+	defer pool.Close()
+
+	// 等 MinIdle 补连，之后探测协程反复取放空闲连接
+	deadline := time.Now().Add(time.Second)
+	for pool.GetIdle() < 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if pool.GetIdle() < 1 {
+		t.Fatalf("MinIdle should keep an idle conn\n")
+	}
+	clock.Advance(10 * time.Second)
+	time.Sleep(50 * time.Millisecond)
+	if pool.IsActive(5 * time.Second) {
+		t.Errorf("background probing should not keep the pool active\n")
+	}
+	if !pool.expireIdlePool() {
+		t.Errorf("pool kept warm only by MinIdle should expire\n")
+	}
+}
@@ -0,0 +1,74 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"time"
+)
+
+// 连接校验函数，通常调用服务端的空操作方法，返回错误表示连接已失效，与 HeartbeatFunc 的约定相同
+type ValidateFunc func(ctx context.Context, conn *ThriftConn) error
+
+// 设置连接校验函数，每次校验的超时为 timeout 毫秒（小于1时为1秒）。
+// 仅设置校验函数不会校验，须用 WithValidateOnBorrow 和 WithValidateOnReturn 选择校验的时机，两者可同时开启；
+// 未设置校验函数时校验使用 WithHeartbeat 的心跳函数，参见 Ping
+func WithValidator(fn ValidateFunc, timeout int32) Option {
+	return func(t *ThriftPool) {
+		t.Validator = fn
+		if timeout < 1 {
+			t.ValidateTimeout = time.Duration(1000) * time.Millisecond
+		} else {
+			t.ValidateTimeout = time.Duration(timeout) * time.Millisecond
+		}
+	}
+}
+
+// 借出空闲连接前校验，校验失败的连接被关闭，改取下一个空闲连接或拨号新连接。
+// 借出的连接最可靠，但每次借出空闲连接都多一次往返，校验的耗时计入 Get 的延迟；
+// 新拨号的连接和 GetIdleOnly 取出的连接不校验
+func WithValidateOnBorrow(on bool) Option {
+	return func(t *ThriftPool) {
+		t.ValidateOnBorrow = on
+	}
+}
+
+// 放回连接时校验，校验失败的连接被关闭而不放回池。
+// 校验的耗时落在 Put 的调用方而不是下一次 Get，借出更快；
+// 但连接在池中闲置期间失效（如被对端关闭）时无法发现，可配合 WithHeartbeat 使用
+func WithValidateOnReturn(on bool) Option {
+	return func(t *ThriftPool) {
+		t.ValidateOnReturn = on
+	}
+}
+
+// 校验连接，校验函数见 healthCheck，都未设置时总是通过
+func (t *ThriftPool) validate(ctx context.Context, conn *ThriftConn) error {
+	check, timeout := t.healthCheck()
+	if check == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := check(ctx, conn)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		t.logf("validate %s failed:%s", conn.GetEndpoint(), err.Error())
+	}
+	return err
+}
+
+// 连接池统一的健康检查函数及其超时，借出和放回时的校验、Ping 都使用它，优先级为：
+// 1) WithValidator 设置的校验函数，超时为 ValidateTimeout；
+// 2) 否则为 WithHeartbeat 设置的心跳函数，超时为 HeartbeatTimeout，与后台心跳对“健康”的判断一致；
+// 3) 都未设置时返回nil
+func (t *ThriftPool) healthCheck() (ValidateFunc, time.Duration) {
+	if t.Validator != nil {
+		return t.Validator, t.ValidateTimeout
+	}
+	if t.HeartbeatFunc != nil {
+		return ValidateFunc(t.HeartbeatFunc), t.HeartbeatTimeout
+	}
+	return nil, 0
+}
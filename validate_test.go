@@ -0,0 +1,116 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// 记录校验次数，broken 中的连接校验失败
+type testValidator struct {
+	mutex	sync.Mutex
+	calls	int
+	broken	map[*ThriftConn]bool
+}
+
+func (v *testValidator) validate(ctx context.Context, conn *ThriftConn) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.calls++
+	if v.broken[conn] {
+		return errors.New("validate failed")
+	}
+	return nil
+}
+
+func TestValidateOnBorrow(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	v := &testValidator{broken: make(map[*ThriftConn]bool)}
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 0,
+		WithValidator(v.validate, 100), WithValidateOnBorrow(true))
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if v.calls != 0 {
+		t.Errorf("new conn should not be validated, calls:%d\n", v.calls)
+	}
+	v.broken[conn] = true
+	_ = pool.Put(conn)
+	if pool.GetIdle() != 1 {
+		t.Errorf("conn should be pooled without return validation, idle:%d\n", pool.GetIdle())
+	}
+
+	// 空闲连接校验失败被关闭，改为拨号新连接
+	conn2, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if conn2 == conn || !conn.IsClose() {
+		t.Errorf("conn failing validation should be closed and replaced\n")
+	}
+	if v.calls != 1 || pool.Stats().EvictedBroken != 1 {
+		t.Errorf("unexpected validation, calls:%d, evicted:%d\n", v.calls, pool.Stats().EvictedBroken)
+	}
+	if pool.GetUsed() != 1 || pool.GetOpen() != 1 {
+		t.Errorf("unexpected counters, used:%d, open:%d\n", pool.GetUsed(), pool.GetOpen())
+	}
+	_ = pool.Put(conn2)
+
+	conn3, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if conn3 != conn2 || v.calls != 2 {
+		t.Errorf("healthy idle conn should be validated and reused, calls:%d\n", v.calls)
+	}
+	_ = pool.Put(conn3)
+}
+
+func TestValidateOnReturn(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	v := &testValidator{broken: make(map[*ThriftConn]bool)}
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 0,
+		WithValidator(v.validate, 100), WithValidateOnReturn(true))
+	defer pool.Close()
+
+	conn1, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	conn2, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	v.broken[conn2] = true
+	_ = pool.Put(conn1)
+	_ = pool.Put(conn2)
+	if v.calls != 2 {
+		t.Errorf("both conns should be validated on return, calls:%d\n", v.calls)
+	}
+	if !conn2.IsClose() || pool.GetIdle() != 1 || pool.GetUsed() != 0 || pool.GetOpen() != 1 {
+		t.Errorf("conn failing validation should not be pooled, idle:%d, used:%d, open:%d\n",
+			pool.GetIdle(), pool.GetUsed(), pool.GetOpen())
+	}
+	if pool.Stats().EvictedBroken != 1 {
+		t.Errorf("unexpected evicted:%d\n", pool.Stats().EvictedBroken)
+	}
+
+	// 借出时不再校验
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if conn != conn1 || v.calls != 2 {
+		t.Errorf("borrow should not validate, calls:%d\n", v.calls)
+	}
+	_ = pool.Put(conn)
+}
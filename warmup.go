@@ -0,0 +1,129 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// 后台预热（StartupRetryBackground）的状态
+type WarmUpState int32
+
+const (
+	WarmUpIdle			WarmUpState = iota	// 没有后台预热，或已补足 InitSize
+	WarmUpActive							// 正在按 warmUpInterval 补足连接
+	WarmUpBackingOff						// 拨号失败，正在退避等待
+	WarmUpPaused							// 连续失败达到上限，暂停到业务的 Get 拨号成功
+)
+
+func (s WarmUpState) String() string {
+	switch s {
+	case WarmUpIdle:
+		return "Idle"
+	case WarmUpActive:
+		return "Active"
+	case WarmUpBackingOff:
+		return "BackingOff"
+	case WarmUpPaused:
+		return "Paused"
+	default:
+		return "Unknown"
+	}
+}
+
+// 设置后台预热的失败处理：每轮拨号失败后等待的间隔从 warmUpInterval 起翻倍，不超过 maxBackoff 毫秒（小于1时为30秒）；
+// 连续失败 maxFailures 轮（小于1时不暂停，默认）后暂停预热，不再拨号，
+// 直到业务的 Get 拨号成功证明服务端已恢复，再从 warmUpInterval 重新开始。
+// 熔断打开期间不拨号，也不计为失败，熔断冷却后继续
+func WithWarmUpRetry(maxFailures, maxBackoff int32) Option {
+	return func(t *ThriftPool) {
+		if maxFailures < 1 {
+			t.WarmUpMaxFailures = 0
+		} else {
+			t.WarmUpMaxFailures = maxFailures
+		}
+		if maxBackoff < 1 {
+			t.WarmUpMaxBackoff = time.Duration(30000) * time.Millisecond
+		} else {
+			t.WarmUpMaxBackoff = time.Duration(maxBackoff) * time.Millisecond
+		}
+	}
+}
+
+// 后台预热的状态
+func (t *ThriftPool) GetWarmUpState() WarmUpState {
+	return WarmUpState(atomic.LoadInt32(&t.warmUpState))
+}
+
+func (t *ThriftPool) setWarmUpState(state WarmUpState) {
+	atomic.StoreInt32(&t.warmUpState, int32(state))
+}
+
+// 拨号成功时唤醒暂停的后台预热
+func (t *ThriftPool) resumeWarmUp() {
+	if t.GetWarmUpState() != WarmUpPaused {
+		return
+	}
+	select {
+	case t.warmUpResume <- struct{}{}:
+	default:
+	}
+}
+
+// 后台预热，直到已打开的连接数达到 InitSize 或连接池关闭
+func (t *ThriftPool) retryWarmUp() {
+	defer t.setWarmUpState(WarmUpIdle)
+	interval := warmUpInterval
+	var failures int32
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		_, err := t.fillIdle(t.ctx)
+		switch {
+		case err == nil:
+			return
+		case errors.Is(err, ErrBreakerOpen) || errors.Is(err, ErrPaused):
+			// 熔断打开或连接池暂停时不计为失败
+			t.setWarmUpState(WarmUpActive)
+			interval = warmUpInterval
+		case t.WarmUpMaxFailures > 0 && failures+1 >= t.WarmUpMaxFailures:
+			t.logf("warm up failed %d times:%s, paused until a dial succeeds", failures+1, err.Error())
+			if !t.waitWarmUpResume() {
+				return
+			}
+			failures = 0
+			interval = warmUpInterval
+		default:
+			failures++
+			t.setWarmUpState(WarmUpBackingOff)
+			interval *= 2
+			if interval > t.WarmUpMaxBackoff {
+				interval = t.WarmUpMaxBackoff
+			}
+		}
+		timer.Reset(interval)
+	}
+}
+
+// 暂停后台预热，直到有拨号成功，连接池关闭时返回 false
+func (t *ThriftPool) waitWarmUpResume() bool {
+	// 清除暂停前（预热自身拨号成功时）留下的通知
+	select {
+	case <-t.warmUpResume:
+	default:
+	}
+	t.setWarmUpState(WarmUpPaused)
+	select {
+	case <-t.ctx.Done():
+		return false
+	case <-t.warmUpResume:
+		t.setWarmUpState(WarmUpActive)
+		return true
+	}
+}
@@ -0,0 +1,111 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 等待后台预热进入 state
+func waitWarmUpState(pool *ThriftPool, state WarmUpState) bool {
+	deadline := time.Now().Add(time.Second)
+	for pool.GetWarmUpState() != state && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return pool.GetWarmUpState() == state
+}
+
+func TestWarmUpPause(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	interval := warmUpInterval
+	warmUpInterval = 10 * time.Millisecond
+	defer func() { warmUpInterval = interval }()
+
+	// 第一个连接拨号成功，之后失败直到 recovered
+	var dials, recovered int32
+	wrapper := WithTransportWrapper(func(socket *thrift.TSocket) (thrift.TTransport, error) {
+		if atomic.AddInt32(&dials, 1) > 1 && atomic.LoadInt32(&recovered) == 0 {
+			return nil, errors.New("handshake failed")
+		}
+		return socket, nil
+	})
+	pool, err := NewThriftPoolChecked(endpoint, 100, 60000, 10, 3, wrapper,
+		WithStartupPolicy(StartupRetryBackground), WithWarmUpRetry(3, 1000))
+	if err != nil {
+		t.Fatalf("NewThriftPoolChecked error:%s\n", err.Error())
+	}
+	defer pool.Close()
+	if !waitWarmUpState(pool, WarmUpPaused) {
+		t.Fatalf("warm up should pause after 3 failures, state:%s\n", pool.GetWarmUpState())
+	}
+	if pool.Stats().WarmUp != WarmUpPaused {
+		t.Errorf("stats should report the paused warm up\n")
+	}
+
+	// 暂停期间不再拨号，即使服务端已恢复
+	paused := atomic.LoadInt32(&dials)
+	atomic.StoreInt32(&recovered, 1)
+	time.Sleep(100 * time.Millisecond)
+	if atomic.LoadInt32(&dials) != paused {
+		t.Errorf("paused warm up should not dial, dials:%d -> %d\n", paused, atomic.LoadInt32(&dials))
+	}
+
+	// 业务 Get 拨号成功后恢复预热并补足 InitSize
+	conn1, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	conn2, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if !waitWarmUpState(pool, WarmUpIdle) {
+		t.Errorf("warm up should resume and finish, state:%s\n", pool.GetWarmUpState())
+	}
+	if pool.GetOpen() < 3 {
+		t.Errorf("warm up should fill InitSize, open:%d\n", pool.GetOpen())
+	}
+	_ = pool.Put(conn1)
+	_ = pool.Put(conn2)
+}
+
+func TestWarmUpBackoff(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+
+	interval := warmUpInterval
+	warmUpInterval = 10 * time.Millisecond
+	defer func() { warmUpInterval = interval }()
+
+	var dials int32
+	wrapper := WithTransportWrapper(func(socket *thrift.TSocket) (thrift.TTransport, error) {
+		if atomic.AddInt32(&dials, 1) > 1 {
+			return nil, errors.New("handshake failed")
+		}
+		return socket, nil
+	})
+	pool, err := NewThriftPoolChecked(endpoint, 100, 60000, 10, 3, wrapper,
+		WithStartupPolicy(StartupRetryBackground), WithWarmUpRetry(0, 40))
+	if err != nil {
+		t.Fatalf("NewThriftPoolChecked error:%s\n", err.Error())
+	}
+	defer pool.Close()
+	if !waitWarmUpState(pool, WarmUpBackingOff) {
+		t.Fatalf("failed warm up should back off, state:%s\n", pool.GetWarmUpState())
+	}
+	// 10, 20, 40, 40... 毫秒，300ms 内约 8 次，不退避时为 30 次
+	time.Sleep(300 * time.Millisecond)
+	if n := atomic.LoadInt32(&dials); n > 12 {
+		t.Errorf("warm up should back off between failed dials, dials:%d\n", n)
+	}
+	if pool.GetWarmUpState() != WarmUpBackingOff {
+		t.Errorf("warm up without a failure limit should keep backing off, state:%s\n", pool.GetWarmUpState())
+	}
+}
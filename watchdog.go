@@ -0,0 +1,65 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// 连接借出超过 MaxBorrowTime 已被强制回收，由 Put 返回
+var ErrConnReclaimed = errors.New("thriftpool connection reclaimed after max borrow time")
+
+// 设置连接的最长借出时长，单位毫秒，小于1表示不限制（默认）
+// 连接借出超过该时长仍未 Put 归还时，连接会被强制关闭并扣减已用连接数，随后调用 onTimeout（可为nil），
+// 之后再 Put 该连接将返回 ErrConnReclaimed。
+// 警告：回收发生在另一个协程中，如果调用方此时仍在使用该连接，读写会因连接被关闭而失败，
+// 因此应把该时长设置得远大于正常调用耗时，仅用于兜底回收泄漏的连接
+func (t *ThriftPool) SetMaxBorrowTime(maxBorrowTime int32, onTimeout func(conn *ThriftConn)) {
+	if maxBorrowTime < 1 {
+		t.MaxBorrowTime = 0
+	} else {
+		t.MaxBorrowTime = time.Duration(maxBorrowTime) * time.Millisecond
+	}
+	t.OnBorrowTimeout = onTimeout
+}
+
+// 连接借出时启动看门狗
+func (t *ThriftPool) startWatchdog(conn *ThriftConn) {
+	if t.MaxBorrowTime <= 0 {
+		return
+	}
+	atomic.StoreInt32(&conn.borrowed, 1)
+	conn.watchdog = time.AfterFunc(t.MaxBorrowTime, func() {
+		t.reclaim(conn)
+	})
+}
+
+// 连接归还时停止看门狗，返回 false 表示连接已被看门狗回收
+func (t *ThriftPool) stopWatchdog(conn *ThriftConn) bool {
+	if conn.watchdog == nil {
+		return true
+	}
+	conn.watchdog.Stop()
+	conn.watchdog = nil
+	return atomic.CompareAndSwapInt32(&conn.borrowed, 1, 0)
+}
+
+// 回收借出超时的连接
+func (t *ThriftPool) reclaim(conn *ThriftConn) {
+	if !atomic.CompareAndSwapInt32(&conn.borrowed, 1, 0) {
+		// 已被 Put 归还
+		return
+	}
+	t.subEndpointUsed(conn)
+	t.discard(conn)
+	t.countEvicted(evictReclaimed)
+	if !conn.overflow {
+		t.subUsed()
+	}
+	t.logf("conn borrowed by %q not returned after %s, reclaimed", conn.tag, t.MaxBorrowTime)
+	t.emitTagged(EventEvicted, conn.tag, nil)
+	if t.OnBorrowTimeout != nil {
+		t.OnBorrowTimeout(conn)
+	}
+}
@@ -0,0 +1,54 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMaxBorrowTime(t *testing.T) {
+	endpoint, stop := startTestServer(t, "127.0.0.1:0")
+	defer stop()
+	pool := NewThriftPool(endpoint, 100, 60000, 10, 1)
+	defer pool.Close()
+
+	reclaimed := make(chan *ThriftConn, 1)
+	pool.SetMaxBorrowTime(20, func(conn *ThriftConn) {
+		reclaimed <- conn
+	})
+
+	// 及时归还的连接不会被回收
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if err = pool.Put(conn); err != nil {
+		t.Fatalf("pool.Put error:%s\n", err.Error())
+	}
+
+	conn, err = pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	select {
+	case reclaimedConn := <-reclaimed:
+		if reclaimedConn != conn {
+			t.Errorf("reclaimed an unexpected conn\n")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("conn was not reclaimed after max borrow time\n")
+	}
+	if !conn.IsClose() {
+		t.Errorf("reclaimed conn should be closed\n")
+	}
+	if pool.GetUsed() != 0 {
+		t.Errorf("used is %d, want 0 after reclaim\n", pool.GetUsed())
+	}
+	if err = pool.Put(conn); err != ErrConnReclaimed {
+		t.Errorf("pool.Put error is %v, want ErrConnReclaimed\n", err)
+	}
+	if pool.GetUsed() != 0 || pool.GetIdle() != 0 {
+		t.Errorf("used:%d, idle:%d, want 0 after late Put\n", pool.GetUsed(), pool.GetIdle())
+	}
+}
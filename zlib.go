@@ -0,0 +1,38 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"compress/zlib"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+)
+
+// 为每个连接套上 thrift 的 TZlibTransport，压缩读写的数据，用于带宽受限的广域网链路。
+// level 为 zlib 的压缩级别（0-9），超出范围时为 zlib.DefaultCompression。
+// zlib 位于socket（及字节计数）之外、缓冲和 framed 之内，服务端须按同样的顺序套上 zlib，
+// 如 framed 时为 TFramedTransport(TZlibTransport(socket))。
+// 压缩流在连接的整个生命周期内连续，每次调用须 Flush 才会把压缩数据发出；
+// 与其它连接一样，Put 时仍有写入后未 Flush 的数据的连接会被关闭（见 GuardedTransport），避免残留的半截压缩数据带到下一次借出
+func WithZlibTransport(level int32) Option {
+	return func(t *ThriftPool) {
+		t.ZlibTransport = true
+		if level < zlib.NoCompression || level > zlib.BestCompression {
+			t.ZlibLevel = zlib.DefaultCompression
+		} else {
+			t.ZlibLevel = level
+		}
+	}
+}
+
+// 为连接套上 zlib transport，位于缓冲之内
+func (t *ThriftPool) wrapZlib(conn *ThriftConn) error {
+	if !t.ZlibTransport {
+		return nil
+	}
+	transport, err := thrift.NewTZlibTransport(conn.transport, int(t.ZlibLevel))
+	if err != nil {
+		return err
+	}
+	conn.transport = transport
+	return nil
+}
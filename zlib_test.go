@@ -0,0 +1,89 @@
+// Package thriftpool provides a pool of thrift clients
+package thriftpool
+
+import (
+	"compress/zlib"
+	"context"
+	"strings"
+	"testing"
+
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"github.com/tianxingpan/thriftpool/example/echo"
+	"github.com/tianxingpan/thriftpool/testutil"
+)
+
+// 一次 Echo 大请求写入socket的字节数
+func echoBytesWritten(t testing.TB, pool *ThriftPool, msg string) int64 {
+	conn, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	defer pool.Put(conn)
+	before := conn.GetBytesWritten()
+	res, err := echo.NewEchoClientFactory(conn.ClientFactory()).Echo(&echo.EchoReq{Msg: msg})
+	if err != nil {
+		t.Fatalf("Echo error:%s\n", err.Error())
+	}
+	if res.GetMsg() != "success" {
+		t.Fatalf("Echo returned %q\n", res.GetMsg())
+	}
+	return conn.GetBytesWritten() - before
+}
+
+func TestZlibTransport(t *testing.T) {
+	msg := strings.Repeat("thriftpool zlib payload ", 4096)
+
+	endpoint, stop := testutil.StartEchoServer(t)
+	defer stop()
+	plain := NewThriftPool(endpoint, 1000, 60000, 10, 0, WithFramedTransport(0), WithByteCounting())
+	defer plain.Close()
+	plainBytes := echoBytesWritten(t, plain, msg)
+
+	zlibEndpoint, zlibStop := testutil.StartEchoServerWith(t, thrift.NewTZlibTransportFactory(zlib.DefaultCompression))
+	defer zlibStop()
+	compressed := NewThriftPool(zlibEndpoint, 1000, 60000, 10, 0,
+		WithFramedTransport(0), WithZlibTransport(zlib.BestSpeed), WithByteCounting())
+	defer compressed.Close()
+	// 压缩流跨调用连续，复用的连接同样可用
+	zlibBytes := echoBytesWritten(t, compressed, msg)
+	zlibBytes += echoBytesWritten(t, compressed, msg)
+	if compressed.GetOpen() != 1 {
+		t.Errorf("zlib conn should be reused, open:%d\n", compressed.GetOpen())
+	}
+	t.Logf("payload %d bytes, plain %d bytes, zlib %d bytes per call\n", len(msg), plainBytes, zlibBytes/2)
+	if zlibBytes/2 > plainBytes/10 {
+		t.Errorf("zlib should shrink a repetitive payload, plain:%d zlib:%d\n", plainBytes, zlibBytes/2)
+	}
+
+	// 写入后未 Flush 的连接在 Put 时关闭
+	conn, err := compressed.Get(context.Background())
+	if err != nil {
+		t.Fatalf("pool.Get error:%s\n", err.Error())
+	}
+	if _, err := conn.GuardedTransport().Write([]byte("partial")); err != nil {
+		t.Fatalf("write error:%s\n", err.Error())
+	}
+	_ = compressed.Put(conn)
+	if compressed.GetOpen() != 0 || compressed.Stats().EvictedBroken != 1 {
+		t.Errorf("conn with unflushed zlib data should be discarded, open:%d\n", compressed.GetOpen())
+	}
+}
+
+// 大请求经 zlib 压缩后每次调用写入socket的字节数
+func BenchmarkZlibEcho(b *testing.B) {
+	msg := strings.Repeat("thriftpool zlib payload ", 4096)
+	endpoint, stop := testutil.StartEchoServerWith(b, thrift.NewTZlibTransportFactory(zlib.DefaultCompression))
+	defer stop()
+	pool := NewThriftPool(endpoint, 1000, 60000, 10, 0,
+		WithFramedTransport(0), WithZlibTransport(zlib.BestSpeed), WithByteCounting())
+	defer pool.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var written int64
+	for i := 0; i < b.N; i++ {
+		written += echoBytesWritten(b, pool, msg)
+	}
+	b.ReportMetric(float64(written)/float64(b.N), "wire-bytes/op")
+	b.ReportMetric(float64(len(msg)), "payload-bytes/op")
+}